@@ -1,23 +1,60 @@
 package disgord
 
 import (
+	"context"
 	"errors"
+	"io"
+	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/andersfylling/disgord/constant"
+	"github.com/andersfylling/disgord/httd"
 )
 
-// Channel types
+// ChannelType the numeric type of a channel
 // https://discordapp.com/developers/docs/resources/channel#channel-object-channel-types
+type ChannelType uint
+
+// Channel types
 const (
-	ChannelTypeGuildText uint = iota
+	ChannelTypeGuildText ChannelType = iota
 	ChannelTypeDM
 	ChannelTypeGuildVoice
 	ChannelTypeGroupDM
 	ChannelTypeGuildCategory
+	ChannelTypeGuildNews
+	ChannelTypeGuildStore
 )
 
+// Channel types for threads and stage channels. Note the gap after ChannelTypeGuildStore.
+const (
+	ChannelTypeGuildNewsThread ChannelType = iota + 10
+	ChannelTypeGuildPublicThread
+	ChannelTypeGuildPrivateThread
+	ChannelTypeGuildStageVoice
+)
+
+// IsThread checks if the channel type is one of the thread types
+func (t ChannelType) IsThread() bool {
+	return t == ChannelTypeGuildNewsThread || t == ChannelTypeGuildPublicThread || t == ChannelTypeGuildPrivateThread
+}
+
+// IsVoice checks if users can connect to the channel using voice
+func (t ChannelType) IsVoice() bool {
+	return t == ChannelTypeGuildVoice || t == ChannelTypeGuildStageVoice
+}
+
+// IsDM checks if the channel is a direct message, or a group direct message
+func (t ChannelType) IsDM() bool {
+	return t == ChannelTypeDM || t == ChannelTypeGroupDM
+}
+
+// IsGuild checks if the channel type belongs to a guild
+func (t ChannelType) IsGuild() bool {
+	return !t.IsDM()
+}
+
 // Attachment https://discordapp.com/developers/docs/resources/channel#attachment-object
 type Attachment struct {
 	ID       Snowflake `json:"id"`
@@ -44,6 +81,85 @@ func (a *Attachment) DeepCopy() (copy interface{}) {
 	return
 }
 
+// AttachmentDownload is the content of a downloaded attachment. Body must be closed by the caller.
+type AttachmentDownload struct {
+	Body          io.ReadCloser
+	ContentType   string
+	ContentLength int64 // -1 when unknown
+}
+
+// Download fetches the attachment file using the given http client. If maxSize is above 0, attachments
+// larger than maxSize bytes are rejected, and reading past maxSize bytes from the Body returns an error.
+// The request is cancelled once ctx is done.
+func (a *Attachment) Download(ctx context.Context, client *http.Client, maxSize int64) (download *AttachmentDownload, err error) {
+	if a.URL == "" {
+		err = newErrorEmptyValue("attachment has no url")
+		return
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var req *http.Request
+	req, err = http.NewRequest(http.MethodGet, a.URL, nil)
+	if err != nil {
+		return
+	}
+
+	var resp *http.Response
+	resp, err = client.Do(req.WithContext(ctx))
+	if err != nil {
+		return
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		err = errors.New("unable to download attachment: " + resp.Status)
+		return
+	}
+	if maxSize > 0 && resp.ContentLength > maxSize {
+		resp.Body.Close()
+		err = errors.New("attachment size exceeds the limit of " + strconv.FormatInt(maxSize, 10) + " bytes")
+		return
+	}
+
+	download = &AttachmentDownload{
+		Body:          resp.Body,
+		ContentType:   resp.Header.Get(httd.ContentType),
+		ContentLength: resp.ContentLength,
+	}
+	if maxSize > 0 {
+		download.Body = &limitedReadCloser{
+			ReadCloser: resp.Body,
+			remaining:  maxSize,
+		}
+	}
+	return
+}
+
+// limitedReadCloser returns an error once more than the allowed number of bytes have been read
+type limitedReadCloser struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (l *limitedReadCloser) Read(p []byte) (n int, err error) {
+	if l.remaining < 0 {
+		return 0, errors.New("attachment size exceeds the limit")
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+
+	n, err = l.ReadCloser.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		n += int(l.remaining)
+		err = errors.New("attachment size exceeds the limit")
+	}
+	return
+}
+
 // PermissionOverwrite https://discordapp.com/developers/docs/resources/channel#overwrite-object
 type PermissionOverwrite struct {
 	ID    Snowflake `json:"id"`    // role or user id
@@ -52,6 +168,24 @@ type PermissionOverwrite struct {
 	Deny  int       `json:"deny"`  // permission bit set
 }
 
+// ThreadMetadata holds the thread specific fields of a thread channel
+// https://discordapp.com/developers/docs/resources/channel#thread-metadata-object
+type ThreadMetadata struct {
+	Archived            bool      `json:"archived"`
+	AutoArchiveDuration uint      `json:"auto_archive_duration"` // minutes
+	ArchiveTimestamp    Timestamp `json:"archive_timestamp"`
+	Locked              bool      `json:"locked,omitempty"`
+}
+
+// ThreadMember is a user that has joined a thread
+// https://discordapp.com/developers/docs/resources/channel#thread-member-object
+type ThreadMember struct {
+	ID            Snowflake `json:"id,omitempty"`      // ?| the thread ID
+	UserID        Snowflake `json:"user_id,omitempty"` // ?|
+	JoinTimestamp Timestamp `json:"join_timestamp"`
+	Flags         int       `json:"flags"`
+}
+
 // NewChannel ...
 func NewChannel() *Channel {
 	return &Channel{}
@@ -77,16 +211,16 @@ type ChannelFetcher interface {
 // // }
 type PartialChannel struct {
 	Lockable `json:"-"`
-	ID       Snowflake `json:"id"`
-	Name     string    `json:"name"`
-	Type     uint      `json:"type"`
+	ID       Snowflake   `json:"id"`
+	Name     string      `json:"name"`
+	Type     ChannelType `json:"type"`
 }
 
 // Channel ...
 type Channel struct {
 	Lockable             `json:"-"`
 	ID                   Snowflake             `json:"id"`
-	Type                 uint                  `json:"type"`
+	Type                 ChannelType           `json:"type"`
 	GuildID              Snowflake             `json:"guild_id,omitempty"`              // ?|
 	Position             uint                  `json:"position,omitempty"`              // ?|
 	PermissionOverwrites []PermissionOverwrite `json:"permission_overwrites,omitempty"` // ?|
@@ -103,6 +237,9 @@ type Channel struct {
 	ApplicationID        Snowflake             `json:"application_id,omitempty"`        // ?|
 	ParentID             Snowflake             `json:"parent_id,omitempty"`             // ?|?
 	LastPinTimestamp     Timestamp             `json:"last_pin_timestamp,omitempty"`    // ?|
+	MessageCount         uint                  `json:"message_count,omitempty"`         // ?| threads only
+	MemberCount          uint                  `json:"member_count,omitempty"`          // ?| threads only
+	ThreadMetadata       *ThreadMetadata       `json:"thread_metadata,omitempty"`       // ?| threads only
 
 	// set to true when the object is not incomplete. Used in situations
 	// like cacheLink to avoid overwriting correct information.
@@ -296,6 +433,12 @@ func (c *Channel) CopyOverTo(other interface{}) (err error) {
 	channel.ParentID = c.ParentID
 	channel.LastPinTimestamp = c.LastPinTimestamp
 	channel.LastMessageID = c.LastMessageID
+	channel.MessageCount = c.MessageCount
+	channel.MemberCount = c.MemberCount
+	if c.ThreadMetadata != nil {
+		metadata := *c.ThreadMetadata
+		channel.ThreadMetadata = &metadata
+	}
 
 	// add recipients if it's a DM
 	for _, recipient := range c.Recipients {
@@ -1,7 +1,11 @@
 package disgord
 
 import (
+	"context"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/andersfylling/disgord/httd"
@@ -266,3 +270,101 @@ func TestModifyChannelParams(t *testing.T) {
 		}
 	})
 }
+
+func TestChannelType(t *testing.T) {
+	t.Run("predicates", func(t *testing.T) {
+		if !ChannelTypeGuildPublicThread.IsThread() || ChannelTypeGuildText.IsThread() {
+			t.Error("IsThread failed")
+		}
+		if !ChannelTypeGuildStageVoice.IsVoice() || !ChannelTypeGuildVoice.IsVoice() || ChannelTypeDM.IsVoice() {
+			t.Error("IsVoice failed")
+		}
+		if !ChannelTypeGroupDM.IsDM() || ChannelTypeGuildCategory.IsDM() {
+			t.Error("IsDM failed")
+		}
+		if ChannelTypeGuildPrivateThread != 12 {
+			t.Errorf("incorrect channel type value. Got %d, wants 12", ChannelTypeGuildPrivateThread)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		channel := Channel{}
+		err := httd.Unmarshal([]byte(`{"id":"1","type":11}`), &channel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if channel.Type != ChannelTypeGuildPublicThread {
+			t.Errorf("incorrect channel type. Got %d, wants %d", channel.Type, ChannelTypeGuildPublicThread)
+		}
+
+		data, err := httd.Marshal(&PartialChannel{Type: ChannelTypeGuildNews})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), `"type":5`) {
+			t.Errorf("channel type was not marshalled as a number: %s", string(data))
+		}
+	})
+}
+
+func TestAttachment_Download(t *testing.T) {
+	content := "this is a attachment"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	t.Run("download", func(t *testing.T) {
+		attachment := &Attachment{URL: server.URL + "/file.txt"}
+		download, err := attachment.Download(context.Background(), server.Client(), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer download.Body.Close()
+
+		data, err := ioutil.ReadAll(download.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("incorrect content. Got %s, wants %s", string(data), content)
+		}
+		if download.ContentType != "text/plain" || download.ContentLength != int64(len(content)) {
+			t.Errorf("incorrect metadata. Got %s, %d", download.ContentType, download.ContentLength)
+		}
+	})
+
+	t.Run("size limit", func(t *testing.T) {
+		attachment := &Attachment{URL: server.URL + "/file.txt"}
+		if _, err := attachment.Download(context.Background(), server.Client(), 5); err == nil {
+			t.Error("expected attachment to exceed the size limit")
+		}
+
+		body := &limitedReadCloser{ReadCloser: ioutil.NopCloser(strings.NewReader(content)), remaining: 5}
+		data, err := ioutil.ReadAll(body)
+		if err == nil || len(data) != 5 {
+			t.Errorf("expected limited reader to stop after 5 bytes. Got %d bytes, %v", len(data), err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		attachment := &Attachment{URL: server.URL + "/missing"}
+		if _, err := attachment.Download(context.Background(), server.Client(), 0); err == nil {
+			t.Error("expected error on status 404")
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		attachment := &Attachment{URL: server.URL + "/file.txt"}
+		if _, err := attachment.Download(ctx, server.Client(), 0); err == nil {
+			t.Error("expected error on cancelled context")
+		}
+	})
+}
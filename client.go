@@ -25,6 +25,10 @@ type Config struct {
 	Token      string
 	HTTPClient *http.Client
 
+	// TokenType is the kind of token used for the REST requests. Set httd.TokenTypeBearer to act on behalf
+	// of a user with an OAuth2 access token. Defaults to httd.TokenTypeBot.
+	TokenType httd.TokenType
+
 	CancelRequestWhenRateLimited bool
 
 	CacheConfig *CacheConfig
@@ -33,6 +37,10 @@ type Config struct {
 	TotalShards  uint
 	WebsocketURL string
 
+	// RESTBaseURL overrides the Discord REST API URL, eg. to run against a mock server. It is given without
+	// the API version, and defaults to https://discordapp.com/api
+	RESTBaseURL string
+
 	//ImmutableCache bool
 	DisableCache bool
 
@@ -46,12 +54,51 @@ type Config struct {
 	// your project name, name of bot, or application
 	ProjectName string
 
+	// OS is the operating system reported to Discord when identifying, eg. to report "linux" no matter where
+	// the bot runs. Defaults to runtime.GOOS.
+	OS string
+
+	// OfflineOnDisconnect shows the bot as offline right away when disconnecting or shutting down, instead
+	// of once Discord notices the session is gone. Defaults to false.
+	OfflineOnDisconnect bool
+
+	// DedupePresences drops presence updates that are identical to the last one of the same user in the
+	// same guild. Defaults to false, which delivers every presence update.
+	DedupePresences bool
+
+	// GuardSessionStarts fetches the session start limit of the bot token on Connect, and holds back
+	// identify commands until the limit resets once only a few session starts remain. This keeps reconnect
+	// storms, eg. after a Discord outage, from getting the token banned from identifying. Defaults to false.
+	GuardSessionStarts bool
+
+	// BeforeIdentify is called with the identify payload before it is sent, to adjust it. The token
+	// and shard array must not be changed. See websocket.Config.BeforeIdentify.
+	BeforeIdentify func(payload *websocket.IdentifyPayload)
+
+	// DryRun records the REST requests and socket commands instead of sending them, for testing the bot
+	// logic without reaching Discord. They are still validated, encoded and rate limited. REST requests get
+	// an empty success, and the client never connects to the gateway. See Client#RecordedRequests and
+	// Client#RecordedCommands. Defaults to false.
+	DryRun bool
+
 	// ActivateEventChannels signifies that the developer will use channels to handle incoming events. May it be
 	// in addition to handlers or not. This forces the use of a scheduler to empty the buffered channels when they
 	// reach their capacity. Since it requires extra resources, others who have no interest in utilizing channels
 	// should not experience any performance penalty (even though it might be unnoticeable).
 	ActivateEventChannels bool
 
+	// EventWorkers runs the event handlers on a pool of this many goroutines, instead of a new goroutine for
+	// every event. The handlers of events in the same channel, or of guild events in the same guild, run in
+	// order, one at a time, while other events are handled concurrently; with a single worker every handler
+	// runs in order. When the
+	// workers fall behind, the events are held back and the socket layer applies its slow consumer policy.
+	// Defaults to 0, which starts a goroutine per event without any ordering.
+	EventWorkers uint
+
+	// GuildLoadTimeout is how long to wait for the guilds of the READY event to be created, before
+	// Client#GuildsLoaded gives up on the remaining ones. Defaults to 30 seconds.
+	GuildLoadTimeout time.Duration
+
 	//Logger logger.Logrus
 }
 
@@ -63,6 +110,10 @@ type Client struct {
 	config       *Config
 	token        string
 
+	// ctx is cancelled on disconnect, and replaced on connect, see Client#Context
+	ctx    context.Context
+	cancel context.CancelFunc
+
 	connected     sync.Mutex
 	ws            *websocket.Client
 	socketEvtChan <-chan *websocket.Event
@@ -71,6 +122,7 @@ type Client struct {
 
 	// register listeners for events
 	evtDispatch *Dispatch
+	workers     *eventWorkers // nil unless Config.EventWorkers is set
 
 	// cancelRequestWhenRateLimited by default the client waits until either the HTTPClient.timeout or
 	// the rate limit ends before closing a request channel. If activated, in stead, requests will
@@ -84,6 +136,8 @@ type Client struct {
 
 	// cacheLink
 	cache *Cache
+
+	guildLoad guildLoader
 }
 
 // HeartbeatLatency checks the duration of waiting before receiving a response from Discord when a
@@ -93,6 +147,44 @@ func (c *Client) HeartbeatLatency() (duration time.Duration, err error) {
 	return c.ws.HeartbeatLatency()
 }
 
+// Ping sends a heartbeat and measures the time until Discord replies. Useful for a ping command, as
+// HeartbeatLatency is only updated once every heartbeat interval.
+func (c *Client) Ping(ctx context.Context) (duration time.Duration, err error) {
+	return c.ws.Ping(ctx)
+}
+
+// ConnectedSince returns when the socket connection was established, or the zero time while disconnected.
+// Useful to report the uptime of the bot.
+func (c *Client) ConnectedSince() time.Time {
+	return c.ws.ConnectedSince()
+}
+
+// LastDisconnect returns when the socket connection was last lost or closed, or the zero time
+func (c *Client) LastDisconnect() time.Time {
+	return c.ws.LastDisconnect()
+}
+
+// ConnectLatency returns how long dialing Discord and upgrading to a socket connection took on the last
+// connect. Compare it with HeartbeatLatency to tell a slow network from a slow gateway.
+func (c *Client) ConnectLatency() time.Duration {
+	return c.ws.ConnectLatency()
+}
+
+// HelloLatency returns how long Discord took to greet the current socket connection, or 0 if it has not yet
+func (c *Client) HelloLatency() time.Duration {
+	return c.ws.HelloLatency()
+}
+
+// ReadyData returns the last READY payload of the socket connection, or nil if it has never been ready
+func (c *Client) ReadyData() *websocket.Ready {
+	return c.ws.ReadyData()
+}
+
+// GuildCount returns the number of guilds the bot is in, including unavailable guilds
+func (c *Client) GuildCount() int {
+	return c.ws.GuildCount()
+}
+
 // ShardID ...
 func (c *Client) ShardID() uint {
 	return c.config.ShardID
@@ -153,10 +245,21 @@ func (c *Client) Connect() (err error) {
 		session.Cache().Update(UserCache, update.User)
 	})
 
+	// the requests made while connecting use the client context
+	c.renewContext()
+
+	if c.config.GuardSessionStarts {
+		c.guardSessionStarts()
+	}
+
+	// subscribe before connecting, such that the READY event is not missed
+	stopGuildLoading := c.followGuildLoading()
+
 	c.logInfo("Connecting to discord Gateway")
 	//c.evtDispatch.start()
 	err = c.ws.Connect()
 	if err != nil {
+		stopGuildLoading()
 		c.logErr(err.Error())
 		return
 	}
@@ -168,11 +271,29 @@ func (c *Client) Connect() (err error) {
 	return nil
 }
 
+// guardSessionStarts gives the session start limit of the bot token to the websocket client, see
+// Config.GuardSessionStarts. The client connects unguarded when the limit can not be fetched.
+func (c *Client) guardSessionStarts() {
+	info, err := GetGatewayBot(c.req)
+	if err != nil {
+		c.logErr("unable to fetch the session start limit, identifying without a guard: " + err.Error())
+		return
+	}
+
+	limit := info.SessionStartLimit
+	c.ws.SetSessionStartLimit(websocket.SessionStartLimit{
+		Total:      limit.Total,
+		Remaining:  limit.Remaining,
+		ResetAfter: limit.ResetAfter,
+	})
+}
+
 // Disconnect closes the discord websocket connection
 func (c *Client) Disconnect() (err error) {
 	fmt.Println() // to keep ^C on it's own line
 	c.logInfo("Closing Discord gateway connection")
 	close(c.evtDispatch.shutdown)
+	c.cancelContext()
 	err = c.ws.Disconnect()
 	if err != nil {
 		c.logErr(err.Error())
@@ -194,12 +315,64 @@ func (c *Client) DisconnectOnInterrupt() (err error) {
 	return c.Disconnect()
 }
 
+// RecordedRequests returns the REST requests recorded in dry run mode, see Config.DryRun
+func (c *Client) RecordedRequests() []httd.RecordedRequest {
+	return c.req.RecordedRequests()
+}
+
+// RecordedCommands returns the socket commands recorded in dry run mode, see Config.DryRun
+func (c *Client) RecordedCommands() []websocket.RecordedCommand {
+	return c.ws.RecordedCommands()
+}
+
 // Req return the request object. Used in REST requests to handle rate limits,
 // wrong http responses, etc.
 func (c *Client) Req() httd.Requester {
 	return c.req
 }
 
+// ReqWithContext gives a requester that sends the REST requests with the given context, such that they
+// are cancelled once the context is done. Use the Ctx of an event, or Client#Context, to tie the requests
+// to the lifetime of the client, eg. disgord.GetChannel(session.ReqWithContext(evt.Ctx), channelID)
+func (c *Client) ReqWithContext(ctx context.Context) httd.Requester {
+	return c.req.WithContext(ctx)
+}
+
+// ReqWithPriority gives a requester that sends the REST requests before the other requests waiting for the
+// same rate limit, eg. to get an alert out under load. See httd.Request#Priority.
+func (c *Client) ReqWithPriority() httd.Requester {
+	return c.req.WithPriority()
+}
+
+// Context is cancelled when the client disconnects, and a new one is used once the client connects again.
+// Events are given the context of the connection they were received on, so REST requests made in response
+// to an event can be cancelled on shutdown, see Client#ReqWithContext. REST requests made through the
+// client itself use the current context as well.
+func (c *Client) Context() context.Context {
+	c.RLock()
+	defer c.RUnlock()
+	return c.ctx
+}
+
+// renewContext replaces the client context when it was cancelled by a disconnect
+func (c *Client) renewContext() {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.ctx == nil || c.ctx.Err() != nil {
+		c.ctx, c.cancel = context.WithCancel(context.Background())
+	}
+}
+
+func (c *Client) cancelContext() {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.cancel != nil {
+		c.cancel()
+	}
+}
+
 // Cache returns the cacheLink manager for the session
 func (c *Client) Cache() Cacher {
 	return c.cache
@@ -233,12 +406,207 @@ func (c *Client) Once(event string, handlers ...interface{}) {
 
 // Emit sends a socket command directly to Discord.
 func (c *Client) Emit(command SocketCommand, data interface{}) error {
+	if err := validateCommand(command, data); err != nil {
+		return err
+	}
+	return c.ws.Emit(command, data)
+}
+
+// EmitWait is the same as Emit, but waits for the command rate limit instead of failing when it is
+// reached. Returns the context error if the context is done first.
+func (c *Client) EmitWait(ctx context.Context, command SocketCommand, data interface{}) error {
+	if err := validateCommand(command, data); err != nil {
+		return err
+	}
+	return c.ws.EmitWait(ctx, command, data)
+}
+
+func validateCommand(command SocketCommand, data interface{}) error {
 	switch command {
 	case CommandUpdateStatus, CommandUpdateVoiceState, CommandRequestGuildMembers:
 	default:
 		return errors.New("command is not supported")
 	}
-	return c.ws.Emit(command, data)
+	if members, ok := data.(*RequestGuildMembersCommand); ok {
+		return members.Validate()
+	}
+	return nil
+}
+
+// the gateway sends at most 1000 members per Guild Members Chunk event
+const maxMembersPerChunk = 1000
+
+// RequestGuildMembers requests the members of a guild over the socket connection, and hands each Guild
+// Members Chunk event to the handler as it arrives. Set an empty query and a limit of 0 to fetch every
+// member of the guild; at most 100 chunks are buffered, so memory use does not grow with the guild size as
+// long as the handler does not keep them. Fetching every member requires the GUILD_MEMBERS privileged
+// intent to be enabled for the bot application.
+//
+// RequestGuildMembers blocks until the last chunk has been handled, the handler returns an error, or the
+// context is done. Once the buffer is full the socket layer waits for the handler, and a chunk is only
+// dropped, failing the request, if the handler stalls for longer than the event send timeout of the
+// socket layer (1 second by default). The nonce of the command is set to identify the chunks of this
+// request. When specific user IDs are requested, the ones that are not members of the guild are returned
+// as notFound. See StartRequestGuildMembers to not block.
+func (c *Client) RequestGuildMembers(ctx context.Context, command *RequestGuildMembersCommand, handler func(chunk *GuildMembersChunk) error) (notFound []Snowflake, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	req := c.StartRequestGuildMembers(ctx, command)
+	for chunk := range req.Chunks() {
+		if err = handler(chunk); err != nil {
+			cancel()
+			<-req.Done()
+			return req.NotFound(), err
+		}
+	}
+	return req.NotFound(), req.Err()
+}
+
+// StartRequestGuildMembers sends a RequestGuildMembers command without blocking, and returns a handle
+// to follow the request. Unlike Emit, the handle reports whether the command actually reached Discord,
+// such that a rate limited request is not mistaken for one that never completes. See RequestGuildMembers
+// for details about the command. The nonce of the command is used when set, otherwise one is generated. A
+// nonce longer than MaxGuildMembersNonceLength fails the request without sending it.
+//
+// The chunks must be read from GuildMembersRequest#Chunks until it is closed, or the context is done.
+func (c *Client) StartRequestGuildMembers(ctx context.Context, command *RequestGuildMembersCommand) *GuildMembersRequest {
+	// the generated nonce is 13 characters at most, well within the limit
+	payload := *command
+	if payload.Nonce == "" {
+		payload.Nonce = strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	events, unsubscribe := c.subscribeGuildMembersChunks(&payload)
+	req := &GuildMembersRequest{
+		Nonce:  payload.Nonce,
+		sent:   make(chan struct{}),
+		chunks: make(chan *GuildMembersChunk),
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer unsubscribe()
+		defer close(req.done)
+		defer close(req.chunks)
+
+		if req.sentErr = payload.Validate(); req.sentErr == nil {
+			req.sentErr = c.ws.EmitSync(CommandRequestGuildMembers, &payload)
+		}
+		close(req.sent)
+		if req.sentErr != nil {
+			req.err = req.sentErr
+			return
+		}
+		req.err = req.receive(ctx, events)
+	}()
+	return req
+}
+
+// subscribeGuildMembersChunks gives the request its own subscription, so the chunks do not compete with
+// other events for room in the buffer. Only the identifying fields are decoded to filter the chunks.
+func (c *Client) subscribeGuildMembersChunks(command *RequestGuildMembersCommand) (<-chan *websocket.Event, func()) {
+	return c.ws.SubscribeTo([]string{event.GuildMembersChunk}, func(evt *websocket.Event) bool {
+		chunk := &struct {
+			GuildID Snowflake `json:"guild_id"`
+			Nonce   string    `json:"nonce"`
+		}{}
+		if err := unmarshal(evt.Data, chunk); err != nil {
+			return false
+		}
+		return chunk.Nonce == command.Nonce && chunk.GuildID == command.GuildID
+	})
+}
+
+// GuildMembersRequest follows a RequestGuildMembers command sent by Client#StartRequestGuildMembers
+type GuildMembersRequest struct {
+	// Nonce identifies the Guild Members Chunk events of this request
+	Nonce string
+
+	sent     chan struct{}
+	sentErr  error
+	chunks   chan *GuildMembersChunk
+	done     chan struct{}
+	err      error
+	notFound []Snowflake
+}
+
+// Sent blocks until the command has been written to the socket, and returns the error if it was not,
+// eg. because of the rate limit.
+func (r *GuildMembersRequest) Sent() error {
+	<-r.sent
+	return r.sentErr
+}
+
+// Chunks returns the Guild Members Chunk events of this request. The channel is closed once the last
+// chunk has been received, or the request failed.
+func (r *GuildMembersRequest) Chunks() <-chan *GuildMembersChunk {
+	return r.chunks
+}
+
+// Done is closed when the request has completed, or failed
+func (r *GuildMembersRequest) Done() <-chan struct{} {
+	return r.done
+}
+
+// Err returns the reason the request failed, or nil. Only valid once Done is closed.
+func (r *GuildMembersRequest) Err() error {
+	select {
+	case <-r.done:
+		return r.err
+	default:
+		return nil
+	}
+}
+
+// NotFound returns the requested user IDs that are not members of the guild. Only complete once Done is
+// closed.
+func (r *GuildMembersRequest) NotFound() []Snowflake {
+	select {
+	case <-r.done:
+		return r.notFound
+	default:
+		return nil
+	}
+}
+
+func (r *GuildMembersRequest) receive(ctx context.Context, events <-chan *websocket.Event) error {
+	var next uint
+	for {
+		var evt *websocket.Event
+		select {
+		case evt = <-events:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if evt == nil {
+			return errors.New("guild members chunk subscription was closed")
+		}
+
+		// the subscription only gives the chunks of this request, see subscribeGuildMembersChunks
+		chunk := &GuildMembersChunk{}
+		if err := unmarshal(evt.Data, chunk); err != nil {
+			return errors.New("unable to read guild members chunk: " + err.Error())
+		}
+		if chunk.ChunkCount > 0 && chunk.ChunkIndex != next {
+			// the chunks were not read for longer than the socket layer waits, see websocket.Client#SubscribeTo
+			return errors.New("guild members chunk " + strconv.Itoa(int(next)) + " was dropped")
+		}
+		next++
+		r.notFound = append(r.notFound, chunk.NotFound...)
+		chunk.Ctx = ctx
+		select {
+		case r.chunks <- chunk:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		// older gateway versions does not send the chunk count, in which case only the last chunk is not full
+		if chunk.ChunkCount > 0 && chunk.ChunkIndex+1 >= chunk.ChunkCount {
+			return nil
+		} else if chunk.ChunkCount == 0 && len(chunk.Members) < maxMembersPerChunk {
+			return nil
+		}
+	}
 }
 
 // EventChan get a event channel using the event name
@@ -918,9 +1286,14 @@ func waitForEvent(eventEmitter <-chan *websocket.Event) (event *websocket.Event,
 
 /* status updates */
 
-// UpdateStatus updates the client's game status
+// UpdateStatus updates the client's game status. The activity is validated first, see Activity#Validate.
 // note: for simple games, check out UpdateStatusString
 func (c *Client) UpdateStatus(s *UpdateStatusCommand) error {
+	if s.Game != nil {
+		if err := s.Game.Validate(); err != nil {
+			return err
+		}
+	}
 	return c.Emit(CommandUpdateStatus, s)
 }
 
@@ -971,6 +1344,16 @@ func (c *Client) eventHandler() {
 			box = &ChannelDelete{}
 		case EventChannelPinsUpdate:
 			box = &ChannelPinsUpdate{}
+		case EventThreadCreate:
+			box = &ThreadCreate{}
+		case EventThreadUpdate:
+			box = &ThreadUpdate{}
+		case EventThreadDelete:
+			box = &ThreadDelete{}
+		case EventThreadListSync:
+			box = &ThreadListSync{}
+		case EventThreadMemberUpdate:
+			box = &ThreadMemberUpdate{}
 		case EventGuildCreate:
 			box = &GuildCreate{}
 		case EventGuildUpdate:
@@ -1031,7 +1414,7 @@ func (c *Client) eventHandler() {
 		}
 
 		// populate box
-		ctx := context.Background()
+		ctx := c.Context()
 		box.registerContext(ctx)
 
 		// first unmarshal to get identifiers
@@ -1055,6 +1438,13 @@ func (c *Client) eventHandler() {
 		// trigger listeners
 		prepareBox(evt.Name, box)
 		c.evtDispatch.triggerChan(ctx, evt.Name, c, box)
-		go c.evtDispatch.triggerCallbacks(ctx, evt.Name, c, box)
+		if c.workers != nil {
+			name := evt.Name
+			c.workers.dispatch(eventWorkerKey(box), func() {
+				c.evtDispatch.triggerCallbacks(ctx, name, c, box)
+			})
+		} else {
+			go c.evtDispatch.triggerCallbacks(ctx, evt.Name, c, box)
+		}
 	}
 }
@@ -0,0 +1,206 @@
+package disgord
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andersfylling/disgord/event"
+	"github.com/andersfylling/disgord/websocket"
+)
+
+func TestClient_StartRequestGuildMembers(t *testing.T) {
+	mocker := &mockerWSReceiveOnly{reading: make(chan []byte)}
+	ws, _ := websocket.NewTestClient(nil, mocker)
+	c := &Client{ws: ws}
+
+	// the command can not be sent before the socket is connected
+	req := c.StartRequestGuildMembers(context.Background(), &RequestGuildMembersCommand{GuildID: 1})
+	if err := req.Sent(); err == nil {
+		t.Fatal("expected the command to not be sent")
+	}
+	select {
+	case <-req.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the request to complete when the command was not sent")
+	}
+	if _, open := <-req.Chunks(); open {
+		t.Error("expected the chunks channel to be closed")
+	}
+	if req.Err() == nil {
+		t.Error("expected the request to fail")
+	}
+}
+
+func TestGuildMembersRequest_Receive(t *testing.T) {
+	mocker := &mockerWSReceiveOnly{reading: make(chan []byte)}
+	ws, _ := websocket.NewTestClient(nil, mocker)
+	ws.RegisterEvent(event.MessageCreate)
+	c := &Client{ws: ws}
+	go func() {
+		for range ws.EventChan() {
+		}
+	}()
+
+	// more chunks than the subscription buffer, interleaved with unrelated events
+	const chunks = 150
+	command := &RequestGuildMembersCommand{GuildID: 1, Nonce: "request"}
+	events, unsubscribe := c.subscribeGuildMembersChunks(command)
+	defer unsubscribe()
+	go func() {
+		var seq int
+		packet := func(name, data string) []byte {
+			seq++
+			return []byte(`{"op":0,"s":` + strconv.Itoa(seq) + `,"t":"` + name + `","d":` + data + `}`)
+		}
+		for i := 0; i < chunks; i++ {
+			count := `,"chunk_index":` + strconv.Itoa(i) + `,"chunk_count":` + strconv.Itoa(chunks)
+			mocker.reading <- packet(event.MessageCreate, `{"id":"1","channel_id":"2"}`)
+			mocker.reading <- packet(event.GuildMembersChunk, `{"guild_id":"1","nonce":"other"`+count+`}`)
+			mocker.reading <- packet(event.GuildMembersChunk, `{"guild_id":"1","nonce":"request"`+count+`}`)
+		}
+	}()
+
+	req := &GuildMembersRequest{
+		Nonce:  command.Nonce,
+		chunks: make(chan *GuildMembersChunk),
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- req.receive(context.Background(), events)
+		close(req.chunks)
+	}()
+
+	// let the subscription buffer fill up before reading
+	time.Sleep(20 * time.Millisecond)
+	var received uint
+	for chunk := range req.chunks {
+		if chunk.Nonce != command.Nonce || chunk.ChunkIndex != received {
+			t.Fatalf("expected chunk %d of the request, got chunk %d with nonce %s", received, chunk.ChunkIndex, chunk.Nonce)
+		}
+		received++
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if received != chunks {
+		t.Errorf("expected %d chunks, got %d", chunks, received)
+	}
+}
+
+func TestClient_RequestGuildMembersNonce(t *testing.T) {
+	mocker := &mockerWSReceiveOnly{reading: make(chan []byte)}
+	ws, _ := websocket.NewTestClient(nil, mocker)
+	c := &Client{ws: ws}
+
+	long := strings.Repeat("a", MaxGuildMembersNonceLength+1)
+	if err := c.Emit(CommandRequestGuildMembers, &RequestGuildMembersCommand{GuildID: 1, Nonce: long}); err == nil {
+		t.Error("expected Emit to reject a nonce that is too long")
+	}
+
+	req := c.StartRequestGuildMembers(context.Background(), &RequestGuildMembersCommand{GuildID: 1, Nonce: long})
+	if err := req.Sent(); err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Errorf("expected the request to fail on the nonce, got %v", err)
+	}
+
+	req = c.StartRequestGuildMembers(context.Background(), &RequestGuildMembersCommand{GuildID: 1, Nonce: "custom"})
+	if req.Nonce != "custom" {
+		t.Errorf("expected the given nonce to be used, got %s", req.Nonce)
+	}
+	<-req.Done()
+
+	req = c.StartRequestGuildMembers(context.Background(), &RequestGuildMembersCommand{GuildID: 1})
+	if req.Nonce == "" || len(req.Nonce) > MaxGuildMembersNonceLength {
+		t.Errorf("expected a generated nonce within the limit, got %q", req.Nonce)
+	}
+	<-req.Done()
+}
+
+func TestClient_DryRun(t *testing.T) {
+	session, err := NewSession(&Config{
+		Token:  "token",
+		DryRun: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := session.(*Client)
+	if err = c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.DeleteMessage(1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.UpdateStatusString("tests"); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := c.RecordedRequests()
+	if len(requests) != 1 || requests[0].Method != "DELETE" || !strings.HasSuffix(requests[0].URL, "/channels/1/messages/2") {
+		t.Errorf("incorrect recorded requests %+v", requests)
+	}
+	commands := c.RecordedCommands()
+	if len(commands) != 1 || commands[0].Command != CommandUpdateStatus || !strings.Contains(string(commands[0].Data), `"name":"tests"`) {
+		t.Errorf("incorrect recorded commands %+v", commands)
+	}
+}
+
+func TestClient_Context(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client{ctx: ctx, cancel: cancel}
+
+	// a context that is still alive is kept
+	c.renewContext()
+	if c.Context() != ctx {
+		t.Error("expected the context to be kept until the client disconnects")
+	}
+
+	c.cancelContext()
+	if c.Context().Err() == nil {
+		t.Fatal("expected the context to be cancelled on disconnect")
+	}
+	c.renewContext()
+	if c.Context().Err() != nil {
+		t.Error("expected a new context once the client connects again")
+	}
+}
+
+func TestClient_DisconnectCancelsRequests(t *testing.T) {
+	received := make(chan interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- true
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	session, err := NewSession(&Config{
+		Token:       "token",
+		RESTBaseURL: server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := session.(*Client)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.GetChannel(1)
+		done <- err
+	}()
+	<-received
+
+	_ = c.Disconnect()
+	select {
+	case err = <-done:
+		if err == nil {
+			t.Error("expected the pending request to fail")
+		}
+	case <-time.After(time.Second):
+		t.Error("expected the pending request to be cancelled on disconnect")
+	}
+}
@@ -0,0 +1,89 @@
+// Command disgord-probe connects to the Discord gateway, listens for every event type and prints how many
+// events of each type were received once it is stopped (ctrl+c). Use it to smoke test a bot token.
+//
+//	DISGORD_TOKEN=... go run ./cmd/disgord-probe -version 6
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/andersfylling/disgord/constant"
+	"github.com/andersfylling/disgord/event"
+	"github.com/andersfylling/disgord/websocket"
+)
+
+func main() {
+	token := flag.String("token", os.Getenv("DISGORD_TOKEN"), "bot token, defaults to $DISGORD_TOKEN")
+	version := flag.Int("version", constant.DiscordVersion, "gateway version")
+	encoding := flag.String("encoding", constant.JSONEncoding, "gateway encoding, only json is supported")
+	flag.Parse()
+
+	if *token == "" {
+		fmt.Fprintln(os.Stderr, "missing bot token, use -token or $DISGORD_TOKEN")
+		os.Exit(2)
+	}
+	if *encoding != constant.JSONEncoding {
+		fmt.Fprintln(os.Stderr, "unsupported encoding: "+*encoding)
+		os.Exit(2)
+	}
+
+	client, err := websocket.NewClient(&websocket.Config{
+		Token:      *token,
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+		Version:    *version,
+		Encoding:   *encoding,
+		Browser:    "disgord-probe",
+		Device:     "disgord-probe",
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	for _, evt := range event.All() {
+		client.RegisterEvent(evt)
+	}
+	if err = client.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println("connected, press ctrl+c to stop")
+
+	termSignal := make(chan os.Signal, 1)
+	signal.Notify(termSignal, syscall.SIGINT, syscall.SIGTERM)
+
+	started := time.Now()
+	counts := map[string]int{}
+	for running := true; running; {
+		select {
+		case evt := <-client.EventChan():
+			counts[evt.Name]++
+		case <-termSignal:
+			running = false
+		}
+	}
+	_ = client.Shutdown()
+
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Printf("\nreceived events during %s:\n", time.Since(started).Round(time.Second))
+	for _, name := range names {
+		fmt.Printf("  %-30s %d\n", name, counts[name])
+	}
+	if latency, err := client.HeartbeatLatency(); err == nil {
+		fmt.Printf("heartbeat latency: %s\n", latency)
+	}
+	if trace := client.Trace(); len(trace) > 0 {
+		fmt.Printf("gateway trace: %v\n", trace)
+	}
+}
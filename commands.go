@@ -1,6 +1,11 @@
 package disgord
 
-import "github.com/andersfylling/disgord/websocket/cmd"
+import (
+	"errors"
+	"strconv"
+
+	"github.com/andersfylling/disgord/websocket/cmd"
+)
 
 // SocketCommand represents the type used to emit commands to Discord
 // over the socket connection
@@ -26,6 +31,30 @@ type RequestGuildMembersCommand struct {
 
 	// Limit maximum number of members to send or 0 to request all members matched
 	Limit uint `json:"limit"`
+
+	// UserIDs requests specific members instead of using a query. IDs that are not members of the guild
+	// are returned in GuildMembersChunk.NotFound. Long lists must be split over several commands, as a
+	// command larger than websocket.MaxPayloadSize is rejected.
+	UserIDs []Snowflake `json:"user_ids,omitempty"`
+
+	// Presences requests the presences of the members as well
+	Presences bool `json:"presences,omitempty"`
+
+	// Nonce is sent back in the Guild Members Chunk events, to identify the chunks of this request. At most
+	// MaxGuildMembersNonceLength bytes, as Discord ignores requests with a longer nonce.
+	Nonce string `json:"nonce,omitempty"`
+}
+
+// MaxGuildMembersNonceLength is the longest nonce Discord accepts in a RequestGuildMembersCommand
+const MaxGuildMembersNonceLength = 32
+
+// Validate verifies the command against the limits of Discord, which silently ignores a request with a
+// nonce that is too long.
+func (c *RequestGuildMembersCommand) Validate() error {
+	if len(c.Nonce) > MaxGuildMembersNonceLength {
+		return errors.New("nonce is longer than " + strconv.Itoa(MaxGuildMembersNonceLength) + " bytes")
+	}
+	return nil
 }
 
 // CommandUpdateVoiceState Sent when a client wants to join, move, or
@@ -139,6 +139,7 @@ package disgord
 import (
 	"errors"
 	"strings"
+	"time"
 
 	"github.com/andersfylling/disgord/constant"
 	"github.com/andersfylling/snowflake/v3"
@@ -171,6 +172,25 @@ func ParseSnowflakeString(v string) Snowflake {
 	return Snowflake(snowflake.ParseSnowflakeString(v))
 }
 
+// discordEpoch is the first millisecond of 2015, which Discord snowflakes are relative to
+const discordEpoch = 1420070400000
+
+// SnowflakeTime gives the time a Discord ID was created at
+func SnowflakeTime(id Snowflake) time.Time {
+	ms := int64(uint64(id)>>22) + discordEpoch
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+// SnowflakeAge gives the time passed since a Discord ID was created, such as the age of a message
+func SnowflakeAge(id Snowflake) time.Duration {
+	return time.Since(SnowflakeTime(id))
+}
+
+// SnowflakeBefore checks if a Discord ID was created before the given time
+func SnowflakeBefore(id Snowflake, t time.Time) bool {
+	return SnowflakeTime(id).Before(t)
+}
+
 func newErrorMissingSnowflake(message string) *ErrorMissingSnowflake {
 	return &ErrorMissingSnowflake{
 		info: message,
@@ -1,6 +1,7 @@
 package disgord
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 	"sync"
@@ -61,6 +62,14 @@ func injectRandomEvents(t *testing.T, callback func(name string, evt interface{}
 		PartialEmoji: &Emoji{},
 	}
 	events[EventMessageReactionRemoveAll] = &MessageReactionRemoveAll{}
+	events[EventThreadCreate] = &ThreadCreate{
+		Thread: NewChannel(),
+	}
+	events[EventThreadDelete] = &ThreadDelete{}
+	events[EventThreadListSync] = &ThreadListSync{}
+	events[EventThreadMemberUpdate] = &ThreadMemberUpdate{
+		Member: &ThreadMember{},
+	}
 	events[EventTypingStart] = &TypingStart{}
 	events[EventVoiceStateUpdate] = &VoiceStateUpdate{
 		VoiceState: &VoiceState{},
@@ -164,6 +173,7 @@ func Benchmark1000DiscordEventToHandler_cacheDisabled(b *testing.B) {
 	wsClient, wsShutdownChan := websocket.NewTestClient(nil, &mocker)
 
 	d := Client{
+		ctx:          context.Background(),
 		shutdownChan: make(chan interface{}),
 		config: &Config{
 			DisableCache: true,
@@ -204,3 +214,35 @@ func Benchmark1000DiscordEventToHandler_cacheDisabled(b *testing.B) {
 	close(d.shutdownChan)
 	close(wsShutdownChan)
 }
+
+func TestSnowflakeTime(t *testing.T) {
+	// 2016-04-30 11:18:25.796 UTC, from the Discord documentation
+	id := NewSnowflake(175928847299117063)
+	wants := time.Date(2016, 4, 30, 11, 18, 25, 796*int(time.Millisecond), time.UTC)
+	if created := SnowflakeTime(id); !created.Equal(wants) {
+		t.Errorf("incorrect snowflake time. Got %s, wants %s", created, wants)
+	}
+	if !SnowflakeBefore(id, wants.Add(time.Millisecond)) || SnowflakeBefore(id, wants) {
+		t.Error("incorrect snowflake comparison")
+	}
+	if since := time.Since(wants); SnowflakeAge(id) < since {
+		t.Error("incorrect snowflake age")
+	}
+}
+
+func TestPartitionBulkDeletable(t *testing.T) {
+	snowflakeAt := func(at time.Time) Snowflake {
+		ms := uint64(at.UnixNano()/int64(time.Millisecond)) - discordEpoch
+		return NewSnowflake(ms << 22)
+	}
+	recent := snowflakeAt(time.Now().Add(-time.Hour))
+	old := snowflakeAt(time.Now().Add(-15 * 24 * time.Hour))
+
+	deletable, tooOld := PartitionBulkDeletable([]Snowflake{recent, old, recent})
+	if len(deletable) != 2 || deletable[0] != recent {
+		t.Errorf("expected the recent messages to be deletable, got %v", deletable)
+	}
+	if len(tooOld) != 1 || tooOld[0] != old {
+		t.Errorf("expected the old message to be too old, got %v", tooOld)
+	}
+}
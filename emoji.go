@@ -3,6 +3,7 @@ package disgord
 import (
 	"errors"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/andersfylling/disgord/constant"
@@ -46,6 +47,74 @@ func (e *Emoji) Mention() string {
 	return "<" + prefix + e.Name + ":" + e.ID.String() + ">"
 }
 
+// ParseEmoji parses a emoji token as found in message content. Supported formats are custom emojis, such as
+// `<:name:id>`, `<a:name:id>` (animated), `name:id` or `a:name:id`, and unicode emojis.
+func ParseEmoji(token string) (emoji *Emoji, err error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		err = newErrorEmptyValue("emoji token is empty")
+		return
+	}
+
+	if strings.HasPrefix(token, "<") && strings.HasSuffix(token, ">") {
+		token = token[1 : len(token)-1]
+	}
+
+	parts := strings.Split(token, ":")
+	switch len(parts) {
+	case 1:
+		// unicode
+		emoji = &Emoji{Name: token}
+	case 2:
+		emoji = &Emoji{Name: parts[0]}
+		emoji.ID, err = GetSnowflake(parts[1])
+	case 3:
+		if parts[0] != "a" && parts[0] != "" {
+			err = errors.New("unknown emoji prefix: " + parts[0])
+			return
+		}
+		emoji = &Emoji{Name: parts[1], Animated: parts[0] == "a"}
+		emoji.ID, err = GetSnowflake(parts[2])
+	default:
+		err = errors.New("malformed emoji token: " + token)
+	}
+	if err != nil {
+		emoji = nil
+		return
+	}
+
+	if emoji.Name == "" {
+		err = newErrorEmptyValue("emoji name is empty")
+		emoji = nil
+	}
+	return
+}
+
+// Reaction returns the emoji encoded for use in the reaction endpoints: `name:id` for custom emojis
+// and the unicode emoji otherwise. The result is escaped to be placed in a url path.
+func (e *Emoji) Reaction() string {
+	if e.ID.Empty() {
+		return url.PathEscape(e.Name)
+	}
+	return url.PathEscape(e.Name + ":" + e.ID.String())
+}
+
+// emojiReactionCode converts a *Emoji or a emoji token string, see ParseEmoji, into a reaction endpoint code
+func emojiReactionCode(emoji interface{}) (code string, err error) {
+	switch t := emoji.(type) {
+	case *Emoji:
+		code = t.Reaction()
+	case string:
+		var parsed *Emoji
+		if parsed, err = ParseEmoji(t); err == nil {
+			code = parsed.Reaction()
+		}
+	default:
+		err = errors.New("emoji type can only be a unicode string or a *Emoji struct")
+	}
+	return
+}
+
 func (e *Emoji) LinkToGuild(guildID snowflake.ID) {
 	e.guildID = guildID
 }
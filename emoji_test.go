@@ -256,3 +256,43 @@ func TestValidEmojiName(t *testing.T) {
 		}
 	})
 }
+
+func TestParseEmoji(t *testing.T) {
+	testCases := []struct {
+		token    string
+		name     string
+		id       Snowflake
+		animated bool
+		reaction string
+	}{
+		{"<:disgord:486891339078696971>", "disgord", 486891339078696971, false, "disgord:486891339078696971"},
+		{"<a:dance:486891339078696971>", "dance", 486891339078696971, true, "dance:486891339078696971"},
+		{"disgord:486891339078696971", "disgord", 486891339078696971, false, "disgord:486891339078696971"},
+		{"a:dance:486891339078696971", "dance", 486891339078696971, true, "dance:486891339078696971"},
+		{"👍", "👍", 0, false, "%F0%9F%91%8D"},
+	}
+
+	for _, tc := range testCases {
+		emoji, err := ParseEmoji(tc.token)
+		if err != nil {
+			t.Errorf("unable to parse %s: %s", tc.token, err)
+			continue
+		}
+		if emoji.Name != tc.name || emoji.ID != tc.id || emoji.Animated != tc.animated {
+			t.Errorf("incorrect emoji for %s. Got %+v", tc.token, emoji)
+		}
+		if emoji.Reaction() != tc.reaction {
+			t.Errorf("incorrect reaction code for %s. Got %s, wants %s", tc.token, emoji.Reaction(), tc.reaction)
+		}
+	}
+
+	for _, token := range []string{"", "<:name:notanid>", "x:name:486891339078696971", ":486891339078696971", "a:b:c:d"} {
+		if _, err := ParseEmoji(token); err == nil {
+			t.Errorf("expected error when parsing %s", token)
+		}
+	}
+
+	if _, err := emojiReactionCode(42); err == nil {
+		t.Error("expected error on unsupported emoji type")
+	}
+}
@@ -6,3 +6,8 @@ import "strconv"
 func Gateway(v int) string {
 	return discordAPI + version + strconv.Itoa(v) + gateway
 }
+
+// GatewayBot ...
+func GatewayBot(v int) string {
+	return Gateway(v) + "/bot"
+}
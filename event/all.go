@@ -0,0 +1,46 @@
+package event
+
+// All returns every event type Discord can dispatch to a bot
+func All() []string {
+	return []string{
+		PresencesReplace,
+		Ready,
+		Resumed,
+		ChannelCreate,
+		ChannelUpdate,
+		ChannelDelete,
+		ChannelPinsUpdate,
+		ThreadCreate,
+		ThreadUpdate,
+		ThreadDelete,
+		ThreadListSync,
+		ThreadMemberUpdate,
+		TypingStart,
+		MessageCreate,
+		MessageUpdate,
+		MessageDelete,
+		MessageDeleteBulk,
+		MessageReactionAdd,
+		MessageReactionRemove,
+		MessageReactionRemoveAll,
+		GuildEmojisUpdate,
+		GuildCreate,
+		GuildUpdate,
+		GuildDelete,
+		GuildBanAdd,
+		GuildBanRemove,
+		GuildIntegrationsUpdate,
+		GuildMemberAdd,
+		GuildMemberRemove,
+		GuildMemberUpdate,
+		GuildMembersChunk,
+		GuildRoleCreate,
+		GuildRoleUpdate,
+		GuildRoleDelete,
+		PresenceUpdate,
+		UserUpdate,
+		VoiceStateUpdate,
+		VoiceServerUpdate,
+		WebhooksUpdate,
+	}
+}
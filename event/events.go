@@ -39,6 +39,39 @@ const ChannelDelete = "CHANNEL_DELETE"
 // TODO fix.
 const ChannelPinsUpdate = "CHANNEL_PINS_UPDATE"
 
+// ThreadCreate Sent when a thread is created, or when the current user is added to a thread. The inner payload is a
+// channel object, with an extra newly_created key.
+//  Fields:
+//  - Thread       *Channel
+//  - NewlyCreated bool
+const ThreadCreate = "THREAD_CREATE"
+
+// ThreadUpdate Sent when a thread is updated. The inner payload is a channel object.
+const ThreadUpdate = "THREAD_UPDATE"
+
+// ThreadDelete Sent when a thread relevant to the current user is deleted.
+//  Fields:
+//  - ID       Snowflake
+//  - GuildID  Snowflake
+//  - ParentID Snowflake
+//  - Type     ChannelType
+const ThreadDelete = "THREAD_DELETE"
+
+// ThreadListSync Sent when the current user gains access to a channel, with the active threads of the channels.
+//  Fields:
+//  - GuildID    Snowflake
+//  - ChannelIDs []Snowflake
+//  - Threads    []*Channel
+//  - Members    []*ThreadMember
+const ThreadListSync = "THREAD_LIST_SYNC"
+
+// ThreadMemberUpdate Sent when the thread member object for the current user is updated. The inner payload is a
+// thread member object, with an extra guild_id key.
+//  Fields:
+//  - GuildID Snowflake
+//  - Member  *ThreadMember
+const ThreadMemberUpdate = "THREAD_MEMBER_UPDATE"
+
 // TypingStart Sent when a user starts typing in a channel.
 //  Fields:
 //  - ChannelID     Snowflake
@@ -145,7 +178,7 @@ const GuildMemberUpdate = "GUILD_MEMBER_UPDATE"
 //  Fields:
 //  - GuildID Snowflake
 //  - Members []*Member
-const GuildMembersChunk = "GUILD_MEMBER_CHUNK"
+const GuildMembersChunk = "GUILD_MEMBERS_CHUNK"
 
 // GuildRoleCreate Sent when a guild role is created.
 //  Fields:
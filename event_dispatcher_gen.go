@@ -55,6 +55,11 @@ func NewDispatch(ws *websocket.Client, activateEventChannels bool, evtChanSize i
 		dispatcher.presencesReplaceChan = make(chan *PresencesReplace, evtChanSize)
 		dispatcher.readyChan = make(chan *Ready, evtChanSize)
 		dispatcher.resumedChan = make(chan *Resumed, evtChanSize)
+		dispatcher.threadCreateChan = make(chan *ThreadCreate, evtChanSize)
+		dispatcher.threadDeleteChan = make(chan *ThreadDelete, evtChanSize)
+		dispatcher.threadListSyncChan = make(chan *ThreadListSync, evtChanSize)
+		dispatcher.threadMemberUpdateChan = make(chan *ThreadMemberUpdate, evtChanSize)
+		dispatcher.threadUpdateChan = make(chan *ThreadUpdate, evtChanSize)
 		dispatcher.typingStartChan = make(chan *TypingStart, evtChanSize)
 		dispatcher.userUpdateChan = make(chan *UserUpdate, evtChanSize)
 		dispatcher.voiceServerUpdateChan = make(chan *VoiceServerUpdate, evtChanSize)
@@ -97,6 +102,11 @@ type Dispatch struct {
 	presencesReplaceChan         chan *PresencesReplace
 	readyChan                    chan *Ready
 	resumedChan                  chan *Resumed
+	threadCreateChan             chan *ThreadCreate
+	threadDeleteChan             chan *ThreadDelete
+	threadListSyncChan           chan *ThreadListSync
+	threadMemberUpdateChan       chan *ThreadMemberUpdate
+	threadUpdateChan             chan *ThreadUpdate
 	typingStartChan              chan *TypingStart
 	userUpdateChan               chan *UserUpdate
 	voiceServerUpdateChan        chan *VoiceServerUpdate
@@ -180,6 +190,16 @@ func (d *Dispatch) EventChan(event string) (channel interface{}, err error) {
 		channel = d.Ready()
 	case EventResumed:
 		channel = d.Resumed()
+	case EventThreadCreate:
+		channel = d.ThreadCreate()
+	case EventThreadDelete:
+		channel = d.ThreadDelete()
+	case EventThreadListSync:
+		channel = d.ThreadListSync()
+	case EventThreadMemberUpdate:
+		channel = d.ThreadMemberUpdate()
+	case EventThreadUpdate:
+		channel = d.ThreadUpdate()
 	case EventTypingStart:
 		channel = d.TypingStart()
 	case EventUserUpdate:
@@ -262,6 +282,16 @@ func (d *Dispatch) triggerChan(ctx context.Context, evtName string, session Sess
 		d.readyChan <- box.(*Ready)
 	case EventResumed:
 		d.resumedChan <- box.(*Resumed)
+	case EventThreadCreate:
+		d.threadCreateChan <- box.(*ThreadCreate)
+	case EventThreadDelete:
+		d.threadDeleteChan <- box.(*ThreadDelete)
+	case EventThreadListSync:
+		d.threadListSyncChan <- box.(*ThreadListSync)
+	case EventThreadMemberUpdate:
+		d.threadMemberUpdateChan <- box.(*ThreadMemberUpdate)
+	case EventThreadUpdate:
+		d.threadUpdateChan <- box.(*ThreadUpdate)
 	case EventTypingStart:
 		d.typingStartChan <- box.(*TypingStart)
 	case EventUserUpdate:
@@ -373,6 +403,21 @@ func (d *Dispatch) emptyChannel(evtName string) {
 	case EventResumed:
 		for _ = range d.resumedChan {
 		}
+	case EventThreadCreate:
+		for _ = range d.threadCreateChan {
+		}
+	case EventThreadDelete:
+		for _ = range d.threadDeleteChan {
+		}
+	case EventThreadListSync:
+		for _ = range d.threadListSyncChan {
+		}
+	case EventThreadMemberUpdate:
+		for _ = range d.threadMemberUpdateChan {
+		}
+	case EventThreadUpdate:
+		for _ = range d.threadUpdateChan {
+		}
 	case EventTypingStart:
 		for _ = range d.typingStartChan {
 		}
@@ -510,6 +555,26 @@ func (d *Dispatch) triggerCallbacks(ctx context.Context, evtName string, session
 		for _, listener := range d.listeners[EventResumed] {
 			(listener.(ResumedCallback))(session, box.(*Resumed))
 		}
+	case EventThreadCreate:
+		for _, listener := range d.listeners[EventThreadCreate] {
+			(listener.(ThreadCreateCallback))(session, box.(*ThreadCreate))
+		}
+	case EventThreadDelete:
+		for _, listener := range d.listeners[EventThreadDelete] {
+			(listener.(ThreadDeleteCallback))(session, box.(*ThreadDelete))
+		}
+	case EventThreadListSync:
+		for _, listener := range d.listeners[EventThreadListSync] {
+			(listener.(ThreadListSyncCallback))(session, box.(*ThreadListSync))
+		}
+	case EventThreadMemberUpdate:
+		for _, listener := range d.listeners[EventThreadMemberUpdate] {
+			(listener.(ThreadMemberUpdateCallback))(session, box.(*ThreadMemberUpdate))
+		}
+	case EventThreadUpdate:
+		for _, listener := range d.listeners[EventThreadUpdate] {
+			(listener.(ThreadUpdateCallback))(session, box.(*ThreadUpdate))
+		}
 	case EventTypingStart:
 		for _, listener := range d.listeners[EventTypingStart] {
 			(listener.(TypingStartCallback))(session, box.(*TypingStart))
@@ -700,6 +765,31 @@ func (d *Dispatch) Resumed() <-chan *Resumed {
 	return d.resumedChan
 }
 
+// ThreadCreate gives access to threadCreateChan for ThreadCreate events
+func (d *Dispatch) ThreadCreate() <-chan *ThreadCreate {
+	return d.threadCreateChan
+}
+
+// ThreadDelete gives access to threadDeleteChan for ThreadDelete events
+func (d *Dispatch) ThreadDelete() <-chan *ThreadDelete {
+	return d.threadDeleteChan
+}
+
+// ThreadListSync gives access to threadListSyncChan for ThreadListSync events
+func (d *Dispatch) ThreadListSync() <-chan *ThreadListSync {
+	return d.threadListSyncChan
+}
+
+// ThreadMemberUpdate gives access to threadMemberUpdateChan for ThreadMemberUpdate events
+func (d *Dispatch) ThreadMemberUpdate() <-chan *ThreadMemberUpdate {
+	return d.threadMemberUpdateChan
+}
+
+// ThreadUpdate gives access to threadUpdateChan for ThreadUpdate events
+func (d *Dispatch) ThreadUpdate() <-chan *ThreadUpdate {
+	return d.threadUpdateChan
+}
+
 // TypingStart gives access to typingStartChan for TypingStart events
 func (d *Dispatch) TypingStart() <-chan *TypingStart {
 	return d.typingStartChan
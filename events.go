@@ -237,6 +237,105 @@ type ChannelPinsUpdate struct {
 
 // ---------------------------
 
+// ThreadCreate thread was created, or the current user was added to a thread
+type ThreadCreate struct {
+	Thread *Channel `json:"thread"`
+
+	// NewlyCreated is true when the thread was just created, and false when the current user was added to
+	// an existing thread
+	NewlyCreated bool            `json:"newly_created"`
+	Ctx          context.Context `json:"-"`
+}
+
+// UnmarshalJSON ...
+func (obj *ThreadCreate) UnmarshalJSON(data []byte) error {
+	obj.Thread = &Channel{}
+	if err := unmarshal(data, obj.Thread); err != nil {
+		return err
+	}
+
+	// the flag is given next to the channel fields
+	flag := struct {
+		NewlyCreated bool `json:"newly_created"`
+	}{}
+	if err := unmarshal(data, &flag); err != nil {
+		return err
+	}
+	obj.NewlyCreated = flag.NewlyCreated
+	return nil
+}
+
+// ---------------------------
+
+// ThreadUpdate thread was updated
+type ThreadUpdate struct {
+	Thread *Channel        `json:"thread"`
+	Ctx    context.Context `json:"-"`
+}
+
+// UnmarshalJSON ...
+func (obj *ThreadUpdate) UnmarshalJSON(data []byte) error {
+	obj.Thread = &Channel{}
+	return unmarshal(data, obj.Thread)
+}
+
+// ---------------------------
+
+// ThreadDelete thread was deleted
+type ThreadDelete struct {
+	ID       Snowflake       `json:"id"`
+	GuildID  Snowflake       `json:"guild_id"`
+	ParentID Snowflake       `json:"parent_id"`
+	Type     ChannelType     `json:"type"`
+	Ctx      context.Context `json:"-"`
+}
+
+// ---------------------------
+
+// ThreadListSync the current user gained access to channels, and is given their active threads
+type ThreadListSync struct {
+	GuildID Snowflake `json:"guild_id"`
+
+	// ChannelIDs are the parent channels whose threads are synced. When empty, the threads of the whole
+	// guild are synced.
+	ChannelIDs []Snowflake `json:"channel_ids,omitempty"`
+
+	Threads []*Channel `json:"threads"`
+
+	// Members are the thread member objects of the current user, for the threads it has joined
+	Members []*ThreadMember `json:"members"`
+	Ctx     context.Context `json:"-"`
+}
+
+// ---------------------------
+
+// ThreadMemberUpdate the thread member of the current user was updated
+type ThreadMemberUpdate struct {
+	GuildID Snowflake       `json:"guild_id"`
+	Member  *ThreadMember   `json:"member"`
+	Ctx     context.Context `json:"-"`
+}
+
+// UnmarshalJSON ...
+func (obj *ThreadMemberUpdate) UnmarshalJSON(data []byte) error {
+	obj.Member = &ThreadMember{}
+	if err := unmarshal(data, obj.Member); err != nil {
+		return err
+	}
+
+	// the guild ID is given next to the thread member fields
+	guild := struct {
+		GuildID Snowflake `json:"guild_id"`
+	}{}
+	if err := unmarshal(data, &guild); err != nil {
+		return err
+	}
+	obj.GuildID = guild.GuildID
+	return nil
+}
+
+// ---------------------------
+
 // TypingStart user started typing in a channel
 type TypingStart struct {
 	ChannelID     Snowflake       `json:"channel_id"`
@@ -443,9 +542,19 @@ type GuildMemberUpdate struct {
 
 // GuildMembersChunk response to Request Guild Members
 type GuildMembersChunk struct {
-	GuildID Snowflake       `json:"guild_id"`
-	Members []*Member       `json:"members"`
-	Ctx     context.Context `json:"-"`
+	GuildID    Snowflake       `json:"guild_id"`
+	Members    []*Member       `json:"members"`
+	ChunkIndex uint            `json:"chunk_index"`
+	ChunkCount uint            `json:"chunk_count"`
+	Nonce      string          `json:"nonce,omitempty"`
+
+	// NotFound holds the requested user IDs that are not members of the guild
+	NotFound []Snowflake `json:"not_found,omitempty"`
+
+	// Presences of the members, if requested
+	Presences []*PresenceUpdate `json:"presences,omitempty"`
+
+	Ctx context.Context `json:"-"`
 }
 
 // ---------------------------
@@ -506,6 +615,12 @@ type VoiceStateUpdate struct {
 	Ctx        context.Context `json:"-"`
 }
 
+// UnmarshalJSON see interface json.Unmarshaler. Discord sends the voice state object itself as the payload.
+func (h *VoiceStateUpdate) UnmarshalJSON(data []byte) error {
+	h.VoiceState = &VoiceState{}
+	return unmarshal(data, h.VoiceState)
+}
+
 // ---------------------------
 
 // VoiceServerUpdate guild's voice server was updated. Sent when a guild's voice server is updated. This is sent when initially
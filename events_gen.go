@@ -407,6 +407,79 @@ type ResumedCallback = func(session Session, h *Resumed)
 
 // ---------------------------
 
+// EventThreadCreate Sent when a thread is created, or when the current user is added to a thread. The inner payload is a
+// channel object, with an extra newly_created key.
+//  Fields:
+//  - Thread       *Channel
+//  - NewlyCreated bool
+//
+const EventThreadCreate = event.ThreadCreate
+
+func (h *ThreadCreate) registerContext(ctx context.Context) { h.Ctx = ctx }
+
+// ThreadCreateCallback is triggered in ThreadCreate events
+type ThreadCreateCallback = func(session Session, h *ThreadCreate)
+
+// ---------------------------
+
+// EventThreadDelete Sent when a thread relevant to the current user is deleted.
+//  Fields:
+//  - ID       Snowflake
+//  - GuildID  Snowflake
+//  - ParentID Snowflake
+//  - Type     ChannelType
+//
+const EventThreadDelete = event.ThreadDelete
+
+func (h *ThreadDelete) registerContext(ctx context.Context) { h.Ctx = ctx }
+
+// ThreadDeleteCallback is triggered in ThreadDelete events
+type ThreadDeleteCallback = func(session Session, h *ThreadDelete)
+
+// ---------------------------
+
+// EventThreadListSync Sent when the current user gains access to a channel, with the active threads of the channels.
+//  Fields:
+//  - GuildID    Snowflake
+//  - ChannelIDs []Snowflake
+//  - Threads    []*Channel
+//  - Members    []*ThreadMember
+//
+const EventThreadListSync = event.ThreadListSync
+
+func (h *ThreadListSync) registerContext(ctx context.Context) { h.Ctx = ctx }
+
+// ThreadListSyncCallback is triggered in ThreadListSync events
+type ThreadListSyncCallback = func(session Session, h *ThreadListSync)
+
+// ---------------------------
+
+// EventThreadMemberUpdate Sent when the thread member object for the current user is updated. The inner payload is a
+// thread member object, with an extra guild_id key.
+//  Fields:
+//  - GuildID Snowflake
+//  - Member  *ThreadMember
+//
+const EventThreadMemberUpdate = event.ThreadMemberUpdate
+
+func (h *ThreadMemberUpdate) registerContext(ctx context.Context) { h.Ctx = ctx }
+
+// ThreadMemberUpdateCallback is triggered in ThreadMemberUpdate events
+type ThreadMemberUpdateCallback = func(session Session, h *ThreadMemberUpdate)
+
+// ---------------------------
+
+// EventThreadUpdate Sent when a thread is updated. The inner payload is a channel object.
+//
+const EventThreadUpdate = event.ThreadUpdate
+
+func (h *ThreadUpdate) registerContext(ctx context.Context) { h.Ctx = ctx }
+
+// ThreadUpdateCallback is triggered in ThreadUpdate events
+type ThreadUpdateCallback = func(session Session, h *ThreadUpdate)
+
+// ---------------------------
+
 // EventTypingStart Sent when a user starts typing in a channel.
 //  Fields:
 //  - ChannelID     Snowflake
@@ -1,9 +1,12 @@
 package disgord
 
 import (
+	"context"
 	"io/ioutil"
 	"testing"
+	"time"
 
+	"github.com/andersfylling/disgord/websocket"
 	"github.com/andersfylling/snowflake/v3"
 )
 
@@ -273,3 +276,126 @@ func TestGuildDelete_UnmarshalJSON(t *testing.T) {
 		t.Error("different ID")
 	}
 }
+
+func TestGuildMembersChunk_Unmarshal(t *testing.T) {
+	data := []byte(`{"guild_id":"1","members":[],"chunk_index":1,"chunk_count":3,"nonce":"abc","not_found":["2","3"]}`)
+
+	chunk := GuildMembersChunk{}
+	if err := unmarshal(data, &chunk); err != nil {
+		t.Fatal(err)
+	}
+	if chunk.ChunkIndex != 1 || chunk.ChunkCount != 3 || chunk.Nonce != "abc" {
+		t.Errorf("incorrect chunk information: %+v", chunk)
+	}
+	if len(chunk.NotFound) != 2 || chunk.NotFound[0] != NewSnowflake(2) || chunk.NotFound[1] != NewSnowflake(3) {
+		t.Errorf("incorrect not found IDs: %v", chunk.NotFound)
+	}
+}
+
+func TestThreadCreate_UnmarshalJSON(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/channel/thread_create.json")
+	check(err, t)
+
+	evt := &ThreadCreate{}
+	if err = unmarshal(data, evt); err != nil {
+		t.Fatal(err)
+	}
+	if !evt.NewlyCreated {
+		t.Error("expected the thread to be newly created")
+	}
+	if evt.Thread.ID != NewSnowflake(800000000000000001) || !evt.Thread.Type.IsThread() {
+		t.Errorf("incorrect thread: %+v", evt.Thread)
+	}
+	if evt.Thread.ThreadMetadata == nil || evt.Thread.ThreadMetadata.AutoArchiveDuration != 1440 {
+		t.Errorf("incorrect thread metadata: %+v", evt.Thread.ThreadMetadata)
+	}
+
+	// the current user was added to an existing thread
+	evt = &ThreadCreate{}
+	if err = unmarshal([]byte(`{"id":"1","type":11}`), evt); err != nil {
+		t.Fatal(err)
+	}
+	if evt.NewlyCreated {
+		t.Error("expected the thread to not be newly created")
+	}
+}
+
+func TestThreadMemberUpdate_UnmarshalJSON(t *testing.T) {
+	data := []byte(`{"id":"1","user_id":"2","join_timestamp":"2021-05-01T12:00:00.000000+00:00","flags":1,"guild_id":"3"}`)
+
+	evt := &ThreadMemberUpdate{}
+	if err := unmarshal(data, evt); err != nil {
+		t.Fatal(err)
+	}
+	if evt.GuildID != NewSnowflake(3) {
+		t.Errorf("incorrect guild ID. Got %s, wants 3", evt.GuildID)
+	}
+	if evt.Member.ID != NewSnowflake(1) || evt.Member.UserID != NewSnowflake(2) || evt.Member.Flags != 1 {
+		t.Errorf("incorrect thread member: %+v", evt.Member)
+	}
+}
+
+func TestThreadListSync_Unmarshal(t *testing.T) {
+	data := []byte(`{"guild_id":"1","channel_ids":["2"],"threads":[{"id":"3","type":11,"parent_id":"2"}],"members":[{"id":"3","user_id":"4","join_timestamp":"2021-05-01T12:00:00.000000+00:00","flags":0}]}`)
+
+	evt := &ThreadListSync{}
+	if err := unmarshal(data, evt); err != nil {
+		t.Fatal(err)
+	}
+	if len(evt.ChannelIDs) != 1 || len(evt.Threads) != 1 || evt.Threads[0].ParentID != NewSnowflake(2) {
+		t.Errorf("incorrect thread list: %+v", evt)
+	}
+	if len(evt.Members) != 1 || evt.Members[0].UserID != NewSnowflake(4) {
+		t.Errorf("incorrect thread members: %+v", evt.Members)
+	}
+}
+
+func TestThreadEvents_Routing(t *testing.T) {
+	mocker := mockerWSReceiveOnly{
+		reading: make(chan []byte),
+	}
+	wsClient, wsShutdownChan := websocket.NewTestClient(nil, &mocker)
+	defer close(wsShutdownChan)
+
+	d := Client{
+		ctx:          context.Background(),
+		shutdownChan: make(chan interface{}),
+		config: &Config{
+			DisableCache: true,
+		},
+		ws:            wsClient,
+		socketEvtChan: wsClient.EventChan(),
+		evtDispatch:   NewDispatch(wsClient, false, 20),
+	}
+	defer close(d.shutdownChan)
+	go d.eventHandler()
+
+	created := make(chan *ThreadCreate, 1)
+	deleted := make(chan *ThreadDelete, 1)
+	d.On(EventThreadCreate, func(s Session, evt *ThreadCreate) {
+		created <- evt
+	})
+	d.On(EventThreadDelete, func(s Session, evt *ThreadDelete) {
+		deleted <- evt
+	})
+
+	mocker.reading <- []byte(`{"t":"THREAD_CREATE","s":1,"op":0,"d":{"id":"1","type":11,"newly_created":true}}`)
+	mocker.reading <- []byte(`{"t":"THREAD_DELETE","s":2,"op":0,"d":{"id":"1","guild_id":"2","parent_id":"3","type":11}}`)
+
+	select {
+	case evt := <-created:
+		if !evt.NewlyCreated || evt.Thread.ID != NewSnowflake(1) {
+			t.Errorf("incorrect thread create: %+v", evt.Thread)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("THREAD_CREATE was not routed to the handler")
+	}
+	select {
+	case evt := <-deleted:
+		if evt.ParentID != NewSnowflake(3) || evt.Type != ChannelTypeGuildPublicThread {
+			t.Errorf("incorrect thread delete: %+v", evt)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("THREAD_DELETE was not routed to the handler")
+	}
+}
@@ -0,0 +1,165 @@
+package disgord
+
+import (
+	"sync"
+	"time"
+
+	"github.com/andersfylling/disgord/event"
+	"github.com/andersfylling/disgord/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultGuildLoadTimeout is used when Config.GuildLoadTimeout is not set
+const defaultGuildLoadTimeout = 30 * time.Second
+
+// guildLoader keeps track of the guilds listed in the READY event, until Discord has sent a GUILD_CREATE
+// for each of them.
+type guildLoader struct {
+	sync.Mutex
+	pending map[Snowflake]struct{}
+	loaded  chan struct{}
+	done    bool
+	timer   *time.Timer
+}
+
+func (g *guildLoader) channel() chan struct{} {
+	if g.loaded == nil {
+		g.loaded = make(chan struct{})
+	}
+	return g.loaded
+}
+
+// ready starts tracking the guilds of a new session. The guilds that have not been created when the
+// timeout is reached, are given up on.
+func (g *guildLoader) ready(guilds []*GuildUnavailable, timeout time.Duration) {
+	g.Lock()
+	defer g.Unlock()
+
+	if g.timer != nil {
+		g.timer.Stop()
+		g.timer = nil
+	}
+	if g.done {
+		// a new session must be loaded again
+		g.loaded = nil
+		g.done = false
+	}
+
+	g.pending = make(map[Snowflake]struct{}, len(guilds))
+	for _, guild := range guilds {
+		g.pending[guild.ID] = struct{}{}
+	}
+	if len(g.pending) == 0 {
+		g.finish()
+		return
+	}
+
+	loaded := g.channel()
+	g.timer = time.AfterFunc(timeout, func() {
+		g.Lock()
+		defer g.Unlock()
+		if g.loaded != loaded || g.done {
+			return // outdated
+		}
+		logrus.Warnf("%d guilds were not loaded within %s", len(g.pending), timeout)
+		g.finish()
+	})
+}
+
+func (g *guildLoader) created(id Snowflake) {
+	g.Lock()
+	defer g.Unlock()
+
+	if _, exists := g.pending[id]; !exists || g.done {
+		return
+	}
+	delete(g.pending, id)
+	if len(g.pending) == 0 {
+		g.finish()
+	}
+}
+
+// finish signals that the guilds are loaded. Must be called while holding the lock.
+func (g *guildLoader) finish() {
+	if g.timer != nil {
+		g.timer.Stop()
+		g.timer = nil
+	}
+	g.done = true
+	close(g.channel())
+}
+
+// GuildsLoaded gives a channel which is closed once every guild listed in the READY event has been received
+// through a GUILD_CREATE event, so cache dependent work can be deferred until the guilds are in place.
+// Guilds that are unavailable because of an outage might never be created, so the channel is also closed
+// after Config.GuildLoadTimeout (30 seconds by default). See Client#PendingGuilds for the guilds that did
+// not load. A new channel is used for every new session.
+func (c *Client) GuildsLoaded() <-chan struct{} {
+	c.guildLoad.Lock()
+	defer c.guildLoad.Unlock()
+	return c.guildLoad.channel()
+}
+
+// PendingGuilds lists the guilds of the READY event that Discord has not sent a GUILD_CREATE for yet
+func (c *Client) PendingGuilds() (ids []Snowflake) {
+	c.guildLoad.Lock()
+	defer c.guildLoad.Unlock()
+
+	ids = make([]Snowflake, 0, len(c.guildLoad.pending))
+	for id := range c.guildLoad.pending {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// followGuildLoading keeps track of the guilds being loaded, see Client#GuildsLoaded. The READY and
+// GUILD_CREATE events are received through a subscription of their own, so they are not dispatched to the
+// handlers unless the application registers them. The subscription ends on disconnect, or when the
+// returned function is called.
+func (c *Client) followGuildLoading() (stop func()) {
+	events, unsubscribe := c.ws.SubscribeTo([]string{event.Ready, event.GuildCreate}, nil)
+	go func() {
+		defer unsubscribe()
+		for {
+			select {
+			case evt, open := <-events:
+				if !open {
+					return
+				}
+				c.trackGuildLoading(evt)
+			case <-c.shutdownChan:
+				return
+			}
+		}
+	}()
+	return unsubscribe
+}
+
+// trackGuildLoading only decodes the fields needed, as the events are decoded in full by the event handler
+// when they are registered
+func (c *Client) trackGuildLoading(evt *websocket.Event) {
+	switch evt.Name {
+	case event.Ready:
+		ready := &struct {
+			Guilds []*GuildUnavailable `json:"guilds"`
+		}{}
+		if err := unmarshal(evt.Data, ready); err != nil {
+			logrus.Error(err)
+			return
+		}
+		timeout := c.config.GuildLoadTimeout
+		if timeout == 0 {
+			timeout = defaultGuildLoadTimeout
+		}
+		c.guildLoad.ready(ready.Guilds, timeout)
+	case event.GuildCreate:
+		guild := &struct {
+			ID Snowflake `json:"id"`
+		}{}
+		if err := unmarshal(evt.Data, guild); err != nil {
+			logrus.Error(err)
+			return
+		}
+		c.guildLoad.created(guild.ID)
+	}
+}
@@ -0,0 +1,95 @@
+package disgord
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andersfylling/disgord/websocket"
+)
+
+func TestGuildLoader(t *testing.T) {
+	isLoaded := func(g *guildLoader) bool {
+		g.Lock()
+		c := g.channel()
+		g.Unlock()
+		select {
+		case <-c:
+			return true
+		default:
+			return false
+		}
+	}
+
+	t.Run("created", func(t *testing.T) {
+		g := &guildLoader{}
+		g.ready([]*GuildUnavailable{NewGuildUnavailable(1), NewGuildUnavailable(2)}, time.Hour)
+		g.created(1)
+		g.created(3)
+		if isLoaded(g) {
+			t.Fatal("expected guild 2 to be pending")
+		}
+		g.created(2)
+		if !isLoaded(g) {
+			t.Error("expected the guilds to be loaded")
+		}
+	})
+
+	t.Run("no guilds", func(t *testing.T) {
+		g := &guildLoader{}
+		g.ready(nil, time.Hour)
+		if !isLoaded(g) {
+			t.Error("expected the guilds to be loaded")
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		g := &guildLoader{}
+		g.ready([]*GuildUnavailable{NewGuildUnavailable(1)}, time.Millisecond)
+		g.Lock()
+		c := g.channel()
+		g.Unlock()
+		select {
+		case <-c:
+		case <-time.After(time.Second):
+			t.Fatal("expected the guild loading to time out")
+		}
+		if len(g.pending) != 1 {
+			t.Error("expected the guild to still be pending")
+		}
+
+		// a new session starts over
+		g.ready([]*GuildUnavailable{NewGuildUnavailable(2)}, time.Hour)
+		if isLoaded(g) {
+			t.Error("expected a new session to wait for its guilds")
+		}
+	})
+}
+
+func TestClient_FollowGuildLoading(t *testing.T) {
+	mocker := &mockerWSReceiveOnly{reading: make(chan []byte)}
+	ws, _ := websocket.NewTestClient(nil, mocker)
+	c := &Client{
+		ws:           ws,
+		config:       &Config{},
+		shutdownChan: make(chan interface{}),
+	}
+	go func() {
+		for range ws.EventChan() {
+		}
+	}()
+	stop := c.followGuildLoading()
+	defer stop()
+
+	loaded := c.GuildsLoaded()
+	mocker.reading <- []byte(`{"t":"READY","s":1,"op":0,"d":{"v":6,"guilds":[{"id":"1","unavailable":true}]}}`)
+	mocker.reading <- []byte(`{"t":"GUILD_CREATE","s":2,"op":0,"d":{"id":"1","name":"test"}}`)
+	select {
+	case <-loaded:
+	case <-time.After(time.Second):
+		t.Fatal("expected the guilds to be loaded")
+	}
+
+	if events := ws.TrackedEvents(); len(events) > 0 {
+		t.Errorf("expected the events to not be registered, got %v", events)
+	}
+}
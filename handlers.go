@@ -0,0 +1,71 @@
+package disgord
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/andersfylling/disgord/event"
+)
+
+// handlerMethodPrefix starts the name of every method RegisterHandlers subscribes
+const handlerMethodPrefix = "On"
+
+// RegisterHandlers subscribes the methods of obj to the events they are named after, and registers the
+// events, as if every method was given to Client#On. A handler method is named "On" followed by the event
+// struct name, and has the signature of the event callback:
+//
+//	func (b *Bot) OnMessageCreate(session disgord.Session, evt *disgord.MessageCreate)
+//	func (b *Bot) OnGuildMemberAdd(session disgord.Session, evt *disgord.GuildMemberAdd)
+//
+// Give a pointer when the methods have pointer receivers. Exported methods not named after an event are
+// ignored, so obj can have other methods too. Returns an error if a handler method has the wrong signature,
+// in which case no handlers are registered.
+func (c *Client) RegisterHandlers(obj interface{}) error {
+	if obj == nil {
+		return errors.New("cannot register handlers of a nil object")
+	}
+
+	names := make(map[string]string)
+	for _, evt := range event.All() {
+		names[handlerMethodPrefix+eventStructName(evt)] = evt
+	}
+
+	session := reflect.TypeOf((*Session)(nil)).Elem()
+	pkg := reflect.TypeOf(Client{}).PkgPath()
+
+	value := reflect.ValueOf(obj)
+	handlers := make(map[string]interface{})
+	for i := 0; i < value.NumMethod(); i++ {
+		method := value.Type().Method(i)
+		evt, ok := names[method.Name]
+		if !ok {
+			continue
+		}
+
+		// the type of the bound method, without the receiver
+		typ := value.Method(i).Type()
+		if typ.NumIn() != 2 || typ.NumOut() != 0 || typ.In(0) != session || typ.In(1).Kind() != reflect.Ptr ||
+			typ.In(1).Elem().PkgPath() != pkg || typ.In(1).Elem().Name() != eventStructName(evt) {
+			return errors.New("handler " + method.Name + " must have the signature func(disgord.Session, *disgord." +
+				eventStructName(evt) + ")")
+		}
+		handlers[evt] = value.Method(i).Interface()
+	}
+
+	for evt, handler := range handlers {
+		c.On(evt, handler)
+	}
+	return nil
+}
+
+// eventStructName converts the event name to the name of its struct, eg. MESSAGE_CREATE to MessageCreate
+func eventStructName(evt string) string {
+	words := strings.Split(strings.ToLower(evt), "_")
+	for i := range words {
+		if words[i] != "" {
+			words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+		}
+	}
+	return strings.Join(words, "")
+}
@@ -0,0 +1,54 @@
+package disgord
+
+import (
+	"context"
+	"testing"
+
+	"github.com/andersfylling/disgord/websocket"
+)
+
+type testHandlers struct {
+	messages int
+}
+
+func (h *testHandlers) OnMessageCreate(session Session, evt *MessageCreate) {
+	h.messages++
+}
+
+func (h *testHandlers) OnGuildMembersChunk(session Session, evt *GuildMembersChunk) {}
+
+func (h *testHandlers) OnSomethingElse() {}
+
+type testBadHandlers struct{}
+
+func (h testBadHandlers) OnMessageCreate(evt *MessageCreate) {}
+
+func TestClient_RegisterHandlers(t *testing.T) {
+	ws, _ := websocket.NewTestClient(nil, &mockerWSReceiveOnly{reading: make(chan []byte)})
+	c := &Client{ws: ws, evtDispatch: NewDispatch(ws, false, 1)}
+
+	if err := c.RegisterHandlers(testBadHandlers{}); err == nil {
+		t.Error("expected an error for a handler with the wrong signature")
+	}
+	if len(c.evtDispatch.listeners) != 0 {
+		t.Errorf("expected no handlers to be registered, got %d", len(c.evtDispatch.listeners))
+	}
+
+	handlers := &testHandlers{}
+	if err := c.RegisterHandlers(handlers); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.evtDispatch.listeners) != 2 {
+		t.Errorf("expected 2 events with handlers, got %d", len(c.evtDispatch.listeners))
+	}
+	for _, evt := range []string{EventMessageCreate, EventGuildMembersChunk} {
+		if len(c.evtDispatch.listeners[evt]) != 1 {
+			t.Errorf("expected a handler for %s", evt)
+		}
+	}
+
+	c.evtDispatch.triggerCallbacks(context.Background(), EventMessageCreate, c, &MessageCreate{})
+	if handlers.messages != 1 {
+		t.Errorf("expected the handler to be called once, got %d", handlers.messages)
+	}
+}
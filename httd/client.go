@@ -3,6 +3,7 @@ package httd
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -81,6 +83,43 @@ type Client struct {
 	reqHeader                    http.Header
 	httpClient                   *http.Client // TODO: decouple to allow better unit testing of REST requests
 	cancelRequestWhenRateLimited bool
+
+	// ctx is used for requests without a context of their own
+	ctx context.Context
+
+	// ctxFunc gives the context for requests without a context of their own when ctx is not set
+	ctxFunc func() context.Context
+
+	// priority marks every request as a priority request, see Request.Priority
+	priority bool
+
+	// dryRun records the requests instead of sending them, see Config.DryRun
+	dryRun *dryRun
+}
+
+// WithContext gives a client that sends the requests with the given context, unless the request has a
+// context of its own. The rate limits are shared with the original client.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	clone := *c
+	clone.ctx = ctx
+	return &clone
+}
+
+// WithContextFunc gives a client that asks the function for the context of every request without a
+// context of its own, eg. for a context that is replaced over time. WithContext takes precedence. The rate
+// limits are shared with the original client.
+func (c *Client) WithContextFunc(ctxFunc func() context.Context) *Client {
+	clone := *c
+	clone.ctxFunc = ctxFunc
+	return &clone
+}
+
+// WithPriority gives a client that sends every request as a priority request, see Request.Priority. The
+// rate limits are shared with the original client.
+func (c *Client) WithPriority() *Client {
+	clone := *c
+	clone.priority = true
+	return &clone
 }
 
 // Get handles Discord get requests
@@ -156,6 +195,9 @@ func NewClient(conf *Config) *Client {
 
 	// setup the required http request header fields
 	authorization := fmt.Sprintf(AuthorizationFormat, conf.BotToken)
+	if conf.TokenType != "" && conf.TokenType != TokenTypeBot {
+		authorization = string(conf.TokenType) + " " + conf.BotToken
+	}
 	userAgent := fmt.Sprintf(UserAgentFormat, conf.UserAgentSourceURL, conf.UserAgentVersion, conf.UserAgentExtra)
 	header := map[string][]string{
 		"Authorization":   {authorization},
@@ -163,20 +205,49 @@ func NewClient(conf *Config) *Client {
 		"Accept-Encoding": {"gzip"},
 	}
 
+	baseURL := conf.BaseURL
+	if baseURL == "" {
+		baseURL = BaseURL
+	}
+
+	var dry *dryRun
+	if conf.DryRun {
+		dry = &dryRun{respond: conf.DryRunResponder}
+	}
+
 	return &Client{
-		url:        BaseURL + "/v" + strconv.Itoa(conf.APIVersion),
+		url:        strings.TrimSuffix(baseURL, "/") + "/v" + strconv.Itoa(conf.APIVersion),
 		reqHeader:  header,
 		httpClient: conf.HTTPClient,
 		rateLimit:  NewRateLimit(),
+		dryRun:     dry,
 	}
 }
 
+// TokenType is the prefix of the Authorization header, which tells Discord what kind of token is used
+type TokenType string
+
+const (
+	// TokenTypeBot is used for bot tokens
+	TokenTypeBot TokenType = "Bot"
+
+	// TokenTypeBearer is used for OAuth2 access tokens, to send requests on behalf of a user
+	TokenTypeBearer TokenType = "Bearer"
+)
+
 // Config is the configuration options for the httd.Client structure. Essentially the behaviour of all requests
 // sent to Discord.
 type Config struct {
 	APIVersion int
 	BotToken   string
 
+	// TokenType of the BotToken. Defaults to TokenTypeBot.
+	TokenType TokenType
+
+	// BaseURL of the REST API, without the API version. Use it to send the requests to a mock server or a
+	// proxy. Defaults to httd.BaseURL.
+	BaseURL string
+
 	HTTPClient *http.Client
 
 	CancelRequestWhenRateLimited bool
@@ -185,6 +256,15 @@ type Config struct {
 	UserAgentVersion   string
 	UserAgentSourceURL string
 	UserAgentExtra     string
+
+	// DryRun records the requests instead of sending them, and responds with an empty success, see
+	// Client#RecordedRequests. The requests are still marshalled and rate limited, which makes it possible
+	// to test a bot without reaching Discord. Defaults to false.
+	DryRun bool
+
+	// DryRunResponder overrides the responses in dry run mode, eg. to return the JSON of a message for
+	// a test that reads it
+	DryRunResponder DryRunResponder
 }
 
 // Details ...
@@ -202,6 +282,13 @@ type Request struct {
 	Endpoint    string
 	Body        interface{} // will automatically marshal to JSON if the ContentType is httd.ContentTypeJSON
 	ContentType string
+
+	// Ctx cancels the request once done. Optional.
+	Ctx context.Context
+
+	// Priority lets the request go before the other requests waiting for the same rate limit bucket, such
+	// that eg. an alert is sent first under load. The rate limits of Discord still apply.
+	Priority bool
 }
 
 func (c *Client) decodeResponseBody(resp *http.Response) (body []byte, err error) {
@@ -234,33 +321,77 @@ func (c *Client) decodeResponseBody(resp *http.Response) (body []byte, err error
 	return
 }
 
+func (c *Client) requestContext(r *Request) context.Context {
+	if r.Ctx != nil {
+		return r.Ctx
+	} else if c.ctx != nil {
+		return c.ctx
+	} else if c.ctxFunc != nil {
+		if ctx := c.ctxFunc(); ctx != nil {
+			return ctx
+		}
+	}
+	return context.Background()
+}
+
 // WaitIfRateLimited if the deadtime set by the encountered rate limit does not overstep the http.Client.Timeout and
 // the client.config.CancelRequestWhenRateLimited is set to false, then we simply run a time.After to wait until the
 // rate limits has been reset by Discord. If the dead time is higher than the http.Client.Timeout,
 // we return a rate limit error.
 //
 // The client.config.CancelRequestWhenRateLimited forces an error if a rate limit is encountered, regardless of the
-// Client.Timeout value.
+// Client.Timeout value. The wait is aborted once the request context is done.
+//
+// Requests that are not a priority request also wait for the priority requests of the same bucket, see
+// Request.Priority.
 func WaitIfRateLimited(c *Client, r *Request) (waited bool, err error) {
-	deadtime := c.RateLimiter().WaitTime(r)
-	if deadtime.Nanoseconds() > 0 {
-		if c.cancelRequestWhenRateLimited {
-			err = errors.New("rate limited")
-			return
+	ctx := c.requestContext(r)
+	for {
+		deadtime := c.RateLimiter().WaitTime(r)
+		if deadtime.Nanoseconds() > 0 {
+			if c.cancelRequestWhenRateLimited {
+				err = errors.New("rate limited")
+				return
+			}
+			waitTimeLongerThanHTTPTimeout := c.httpClient.Timeout.Nanoseconds() <= deadtime.Nanoseconds()
+			if waitTimeLongerThanHTTPTimeout {
+				err = errors.New("rate limit timeout is higher than http.Client.Timeout, cannot wait")
+				return
+			}
+
+			select {
+			case <-time.After(deadtime):
+			case <-ctx.Done():
+				err = ctx.Err()
+				return
+			}
 		}
-		waitTimeLongerThanHTTPTimeout := c.httpClient.Timeout.Nanoseconds() <= deadtime.Nanoseconds()
-		if waitTimeLongerThanHTTPTimeout {
-			err = errors.New("rate limit timeout is higher than http.Client.Timeout, cannot wait")
-			return
+
+		if c.isPriority(r) {
+			break
+		}
+		pending := c.rateLimit.priorityPending(r.Ratelimiter)
+		if pending == nil {
+			break
 		}
 
-		<-time.After(deadtime)
+		// the priority requests might use up the remaining requests, so check the rate limit again after
+		select {
+		case <-pending:
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		}
 	}
 
 	waited = true
 	return
 }
 
+func (c *Client) isPriority(r *Request) bool {
+	return r.Priority || c.priority
+}
+
 // Request execute a Discord request
 func (c *Client) Request(r *Request) (resp *http.Response, body []byte, err error) {
 	var bodyReader io.Reader
@@ -281,6 +412,12 @@ func (c *Client) Request(r *Request) (resp *http.Response, body []byte, err erro
 		}
 	}
 
+	// other requests of the bucket give way until the priority request has completed
+	if c.isPriority(r) {
+		release := c.rateLimit.holdForPriority(r.Ratelimiter)
+		defer release()
+	}
+
 	// check the rate limiter for how long we must wait before sending the request
 	_, err = WaitIfRateLimited(c, r)
 	if err != nil {
@@ -294,17 +431,26 @@ func (c *Client) Request(r *Request) (resp *http.Response, body []byte, err erro
 	}
 	req.Header = c.reqHeader
 	req.Header.Set(ContentType, r.ContentType) // unique for each request
+	req = req.WithContext(c.requestContext(r))
 
-	// send request
-	resp, err = c.httpClient.Do(req)
-	if err != nil {
-		return
-	}
-	defer resp.Body.Close()
-	body, err = c.decodeResponseBody(resp)
+	if c.dryRun != nil {
+		// record the request instead, there are no rate limit headers to update from
+		resp, body, err = c.dryRun.record(r, req)
+		if err != nil {
+			return
+		}
+	} else {
+		// send request
+		resp, err = c.httpClient.Do(req)
+		if err != nil {
+			return
+		}
+		defer resp.Body.Close()
+		body, err = c.decodeResponseBody(resp)
 
-	// update rate limits
-	c.RateLimiter().UpdateRegisters(r.Ratelimiter, resp, body)
+		// update rate limits
+		c.RateLimiter().UpdateRegisters(r.Ratelimiter, resp, body)
+	}
 
 	// check if request was successful
 	noDiff := resp.StatusCode == http.StatusNotModified
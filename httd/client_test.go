@@ -3,8 +3,10 @@ package httd
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -91,3 +93,91 @@ func TestDecodingResponseBodyWithGZIP(t *testing.T) {
 	}
 
 }
+
+func TestClient_BaseURL(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "token",
+		BaseURL:            server.URL + "/api/",
+		UserAgentSourceURL: "source",
+		UserAgentVersion:   "version",
+	})
+	if _, _, err := client.Get(&Request{Ratelimiter: "test", Endpoint: "/users/@me"}); err != nil {
+		t.Fatal(err)
+	}
+	if wants := "/api/v6/users/@me"; path != wants {
+		t.Errorf("request was sent to the wrong path. Got %s, wants %s", path, wants)
+	}
+}
+
+func TestClient_WithContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "token",
+		BaseURL:            server.URL,
+		UserAgentSourceURL: "source",
+		UserAgentVersion:   "version",
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, _, err := client.WithContext(ctx).Get(&Request{Ratelimiter: "test", Endpoint: "/"}); err == nil {
+		t.Error("expected the request to be cancelled by the client context")
+	}
+	if _, _, err := client.Get(&Request{Ratelimiter: "test", Endpoint: "/", Ctx: ctx}); err == nil {
+		t.Error("expected the request to be cancelled by the request context")
+	}
+	if _, _, err := client.Get(&Request{Ratelimiter: "test", Endpoint: "/"}); err != nil {
+		t.Errorf("expected the original client to be unaffected, got %s", err)
+	}
+
+	current := context.Background()
+	withFunc := client.WithContextFunc(func() context.Context {
+		return current
+	})
+	if _, _, err := withFunc.Get(&Request{Ratelimiter: "test", Endpoint: "/"}); err != nil {
+		t.Errorf("expected the request to use the current context, got %s", err)
+	}
+	current = ctx
+	if _, _, err := withFunc.Get(&Request{Ratelimiter: "test", Endpoint: "/"}); err == nil {
+		t.Error("expected the request to be cancelled by the current context")
+	}
+	if _, _, err := withFunc.WithContext(context.Background()).Get(&Request{Ratelimiter: "test", Endpoint: "/"}); err != nil {
+		t.Errorf("expected the client context to take precedence, got %s", err)
+	}
+}
+
+func TestClient_TokenType(t *testing.T) {
+	testCases := []struct {
+		tokenType TokenType
+		wants     string
+	}{
+		{"", "Bot token"},
+		{TokenTypeBot, "Bot token"},
+		{TokenTypeBearer, "Bearer token"},
+	}
+	for _, tc := range testCases {
+		client := NewClient(&Config{
+			APIVersion:         6,
+			BotToken:           "token",
+			TokenType:          tc.tokenType,
+			UserAgentSourceURL: "source",
+			UserAgentVersion:   "version",
+		})
+		if got := client.reqHeader.Get("Authorization"); got != tc.wants {
+			t.Errorf("incorrect authorization header. Got %s, wants %s", got, tc.wants)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package httd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// RecordedRequest is a request that was recorded instead of sent, see Config.DryRun
+type RecordedRequest struct {
+	Method      string
+	URL         string
+	ContentType string
+	Body        []byte
+}
+
+// DryRunResponder gives the status code and body of the response to a request in dry run mode. A status code
+// outside of the 2xx range fails the request, like it would when sent to Discord.
+type DryRunResponder func(req *Request) (statusCode int, body []byte)
+
+// dryRun records the requests instead of sending them. It is shared by the clients of Client#WithContext
+// and Client#WithPriority.
+type dryRun struct {
+	sync.Mutex
+	requests []RecordedRequest
+	respond  DryRunResponder
+}
+
+// record keeps the request and responds with a canned success. DELETE and PUT requests get a
+// 204 No Content, as Discord gives for most of them, while the others get a 200 OK. Both are empty,
+// unless a DryRunResponder says otherwise.
+func (d *dryRun) record(r *Request, req *http.Request) (resp *http.Response, body []byte, err error) {
+	recorded := RecordedRequest{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		ContentType: r.ContentType,
+	}
+	if req.Body != nil {
+		if recorded.Body, err = ioutil.ReadAll(req.Body); err != nil {
+			return
+		}
+	}
+	d.Lock()
+	d.requests = append(d.requests, recorded)
+	d.Unlock()
+
+	statusCode := http.StatusOK
+	if req.Method == http.MethodDelete || req.Method == http.MethodPut {
+		statusCode = http.StatusNoContent
+	}
+	if d.respond != nil {
+		statusCode, body = d.respond(r)
+	}
+
+	resp = &http.Response{
+		Status:     http.StatusText(statusCode),
+		StatusCode: statusCode,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}
+	return
+}
+
+// RecordedRequests returns the requests recorded in dry run mode, oldest first. Returns nil unless
+// Config.DryRun is set.
+func (c *Client) RecordedRequests() []RecordedRequest {
+	if c.dryRun == nil {
+		return nil
+	}
+
+	c.dryRun.Lock()
+	defer c.dryRun.Unlock()
+	return append([]RecordedRequest(nil), c.dryRun.requests...)
+}
@@ -0,0 +1,94 @@
+package httd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_DryRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("expected no request to be sent in dry run mode, got %s %s", r.Method, r.URL.Path)
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "token",
+		BaseURL:            server.URL,
+		UserAgentSourceURL: "source",
+		UserAgentVersion:   "version",
+		DryRun:             true,
+	})
+
+	resp, body, err := client.WithPriority().Post(&Request{
+		Ratelimiter: "/channels/1/messages",
+		Endpoint:    "/channels/1/messages",
+		Body:        map[string]string{"content": "hello"},
+		ContentType: ContentTypeJSON,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || len(body) != 0 {
+		t.Errorf("expected an empty 200 OK, got %d %s", resp.StatusCode, string(body))
+	}
+	resp, _, err = client.Delete(&Request{Ratelimiter: "/channels/1", Endpoint: "/channels/1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected a delete to get 204 No Content, got %d", resp.StatusCode)
+	}
+
+	recorded := client.RecordedRequests()
+	if len(recorded) != 2 {
+		t.Fatalf("expected 2 recorded requests, got %d", len(recorded))
+	}
+	post := recorded[0]
+	if post.Method != http.MethodPost || post.URL != server.URL+"/v6/channels/1/messages" || post.ContentType != ContentTypeJSON {
+		t.Errorf("incorrect recorded request %+v", post)
+	}
+	if wants := `{"content":"hello"}`; string(post.Body) != wants {
+		t.Errorf("incorrect recorded body. Got %s, wants %s", string(post.Body), wants)
+	}
+	if recorded[1].Method != http.MethodDelete || recorded[1].Body != nil {
+		t.Errorf("incorrect recorded request %+v", recorded[1])
+	}
+}
+
+func TestClient_DryRunResponder(t *testing.T) {
+	client := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "token",
+		UserAgentSourceURL: "source",
+		UserAgentVersion:   "version",
+		DryRun:             true,
+		DryRunResponder: func(req *Request) (int, []byte) {
+			if req.Method == http.MethodDelete {
+				return http.StatusNotFound, []byte(`{"code":10003,"message":"Unknown Channel"}`)
+			}
+			return http.StatusCreated, []byte(`{"id":"1"}`)
+		},
+	})
+	if _, _, err := client.Delete(&Request{Ratelimiter: "/channels/1", Endpoint: "/channels/1"}); err == nil {
+		t.Error("expected an unsuccessful status code to fail the request")
+	}
+
+	resp, body, err := client.Get(&Request{Ratelimiter: "/users/@me", Endpoint: "/users/@me"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusCreated || string(body) != `{"id":"1"}` {
+		t.Errorf("expected the responder to give the response, got %d %s", resp.StatusCode, string(body))
+	}
+
+	if requests := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "token",
+		UserAgentSourceURL: "source",
+		UserAgentVersion:   "version",
+	}).RecordedRequests(); requests != nil {
+		t.Error("expected no recorded requests outside of dry run mode")
+	}
+}
@@ -13,6 +13,8 @@ const (
 	XRateLimitLimit      = "X-RateLimit-Limit"
 	XRateLimitRemaining  = "X-RateLimit-Remaining"
 	XRateLimitReset      = "X-RateLimit-Reset" // is converted from seconds to milliseconds!
+	XRateLimitResetAfter = "X-RateLimit-Reset-After"
+	XRateLimitBucket     = "X-RateLimit-Bucket"
 	XRateLimitGlobal     = "X-RateLimit-Global"
 	RateLimitRetryAfter  = "Retry-After"
 	GlobalRateLimiterKey = ""
@@ -43,6 +45,15 @@ type RateLimitInfo struct {
 	Remaining  int    `json:"-"`
 	Reset      int64  `json:"-"`
 	Empty      bool   `json:"-"`
+
+	// ResetAfter is the time until the rate limit resets, which unlike Reset does not depend on the clock
+	ResetAfter time.Duration `json:"-"`
+
+	// Bucket identifies the Discord rate limit bucket, which can be shared by several endpoints
+	Bucket string `json:"-"`
+
+	// Key is the rate limiter key of the request, see Request.Ratelimiter
+	Key string `json:"-"`
 }
 
 // RateLimited check if a response was rate limited
@@ -63,7 +74,9 @@ func ExtractRateLimitInfo(resp *http.Response, body []byte) (info *RateLimitInfo
 	limitStr := resp.Header.Get(XRateLimitLimit)
 	remainingStr := resp.Header.Get(XRateLimitRemaining)
 	resetStr := resp.Header.Get(XRateLimitReset)
+	resetAfterStr := resp.Header.Get(XRateLimitResetAfter)
 	retryAfterStr := resp.Header.Get(RateLimitRetryAfter)
+	info.Bucket = resp.Header.Get(XRateLimitBucket)
 
 	// convert types
 	if limitStr != "" {
@@ -85,6 +98,14 @@ func ExtractRateLimitInfo(resp *http.Response, body []byte) (info *RateLimitInfo
 		}
 		info.Reset *= 1000 // second => milliseconds
 	}
+	if resetAfterStr != "" {
+		var seconds float64
+		seconds, err = strconv.ParseFloat(resetAfterStr, 64)
+		if err != nil {
+			return
+		}
+		info.ResetAfter = time.Duration(seconds * float64(time.Second))
+	}
 	if retryAfterStr != "" {
 		info.RetryAfter, err = strconv.ParseInt(retryAfterStr, 10, 64)
 		if err != nil {
@@ -175,6 +196,9 @@ type RateLimit struct {
 	global   *Bucket
 	TimeDiff *DiscordTimeDiff
 
+	// priority holds the priority requests of each bucket that are waiting or in flight
+	priority map[string]*priorityRequests
+
 	mu sync.RWMutex
 }
 
@@ -197,6 +221,55 @@ func (r *RateLimit) Bucket(key string) *Bucket {
 	return bucket
 }
 
+// priorityRequests counts the priority requests of a bucket. done is closed once there are none left.
+type priorityRequests struct {
+	count int
+	done  chan struct{}
+}
+
+// holdForPriority registers a priority request for the bucket, which other requests of the bucket give way
+// to. Call the returned function once the request has completed.
+func (r *RateLimit) holdForPriority(key string) (release func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.priority == nil {
+		r.priority = map[string]*priorityRequests{}
+	}
+	requests, exists := r.priority[key]
+	if !exists {
+		requests = &priorityRequests{done: make(chan struct{})}
+		r.priority[key] = requests
+	}
+	requests.count++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+
+			requests.count--
+			if requests.count == 0 {
+				close(requests.done)
+				delete(r.priority, key)
+			}
+		})
+	}
+}
+
+// priorityPending returns a channel which is closed once the priority requests of the bucket have completed,
+// or nil if there are none.
+func (r *RateLimit) priorityPending(key string) <-chan struct{} {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if requests, exists := r.priority[key]; exists {
+		return requests.done
+	}
+	return nil
+}
+
 // RateLimitTimeout returns the time left before the rate limit for a given key
 // is reset. This takes the global rate limit into account.
 func (r *RateLimit) RateLimitTimeout(key string) int64 {
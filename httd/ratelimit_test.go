@@ -3,7 +3,9 @@ package httd
 import (
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 )
@@ -92,6 +94,27 @@ func TestExtractRateLimitInfo(t *testing.T) {
 	}
 }
 
+func TestExtractRateLimitInfoBucket(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     make(http.Header, 3),
+	}
+	resp.Header.Set(XRateLimitRemaining, "3")
+	resp.Header.Set(XRateLimitResetAfter, "1.5")
+	resp.Header.Set(XRateLimitBucket, "abcd1234")
+
+	info, err := ExtractRateLimitInfo(resp, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Remaining != 3 || info.ResetAfter != 1500*time.Millisecond {
+		t.Errorf("incorrect rate limit information: %+v", info)
+	}
+	if info.Bucket != "abcd1234" {
+		t.Errorf("incorrect bucket. Got %s, wants abcd1234", info.Bucket)
+	}
+}
+
 func TestExtractRateLimitInfoGlobal(t *testing.T) {
 	limit := 2
 	remaining := 4
@@ -139,3 +162,52 @@ func TestExtractRateLimitGlobal(t *testing.T) {
 		t.Error("was not rate limited on a global scale")
 	}
 }
+
+func TestClient_PriorityRequest(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		order = append(order, r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "token",
+		BaseURL:            server.URL,
+		UserAgentSourceURL: "source",
+		UserAgentVersion:   "version",
+	})
+	bucket := client.rateLimit.Bucket("test")
+	bucket.mu.Lock()
+	bucket.remaining = 0
+	bucket.reset = client.rateLimit.TimeDiff.Now().Add(100*time.Millisecond).UnixNano() / int64(time.Millisecond)
+	bucket.mu.Unlock()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if _, _, err := client.Get(&Request{Ratelimiter: "test", Endpoint: "/normal"}); err != nil {
+			t.Error(err)
+		}
+	}()
+	time.Sleep(20 * time.Millisecond)
+	go func() {
+		defer wg.Done()
+		if _, _, err := client.WithPriority().Get(&Request{Ratelimiter: "test", Endpoint: "/priority"}); err != nil {
+			t.Error(err)
+		}
+	}()
+	wg.Wait()
+
+	if len(order) != 2 || order[0] != "/v6/priority" {
+		t.Errorf("expected the priority request to be sent first, got %v", order)
+	}
+	if client.rateLimit.priorityPending("test") != nil {
+		t.Error("expected the priority request to be released")
+	}
+}
@@ -579,6 +579,24 @@ func DeleteMessage(client httd.Deleter, channelID, msgID Snowflake) (err error)
 	return
 }
 
+// bulkDeleteMaxAge is the age limit for messages that can be bulk deleted
+const bulkDeleteMaxAge = 14 * 24 * time.Hour
+
+// PartitionBulkDeletable splits the message IDs into the ones that are recent enough to be bulk deleted,
+// and the ones that are older than two weeks and must be deleted one by one
+func PartitionBulkDeletable(messageIDs []Snowflake) (deletable, tooOld []Snowflake) {
+	// a small margin, so the messages do not expire before the request reaches Discord
+	limit := time.Now().Add(-bulkDeleteMaxAge).Add(time.Minute)
+	for _, id := range messageIDs {
+		if SnowflakeBefore(id, limit) {
+			tooOld = append(tooOld, id)
+		} else {
+			deletable = append(deletable, id)
+		}
+	}
+	return
+}
+
 // BulkDeleteMessagesParams https://discordapp.com/developers/docs/resources/channel#bulk-delete-messages-json-params
 type BulkDeleteMessagesParams struct {
 	Messages []Snowflake `json:"messages"`
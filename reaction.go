@@ -80,13 +80,8 @@ func CreateReaction(client httd.Puter, channelID, messageID Snowflake, emoji int
 		return
 	}
 
-	emojiCode := ""
-	if _, ok := emoji.(*Emoji); ok {
-		emojiCode = emoji.(*Emoji).ID.String()
-	} else if _, ok := emoji.(string); ok {
-		emojiCode = emoji.(string) // unicode
-	} else {
-		err = errors.New("emoji type can only be a unicode string or a *Emoji struct")
+	emojiCode, err := emojiReactionCode(emoji)
+	if err != nil {
 		return
 	}
 
@@ -125,13 +120,9 @@ func DeleteOwnReaction(client httd.Deleter, channelID, messageID Snowflake, emoj
 		return
 	}
 
-	emojiCode := ""
-	if _, ok := emoji.(*Emoji); ok {
-		emojiCode = emoji.(*Emoji).ID.String()
-	} else if _, ok := emoji.(string); ok {
-		emojiCode = emoji.(string) // unicode
-	} else {
-		return errors.New("emoji type can only be a unicode string or a *Emoji struct")
+	emojiCode, err := emojiReactionCode(emoji)
+	if err != nil {
+		return
 	}
 
 	resp, _, err := client.Delete(&httd.Request{
@@ -171,13 +162,9 @@ func DeleteUserReaction(client httd.Deleter, channelID, messageID, userID Snowfl
 		return errors.New("userID must be set to target the specific user reaction")
 	}
 
-	emojiCode := ""
-	if _, ok := emoji.(*Emoji); ok {
-		emojiCode = emoji.(*Emoji).ID.String()
-	} else if _, ok := emoji.(string); ok {
-		emojiCode = emoji.(string) // unicode
-	} else {
-		return errors.New("emoji type can only be a unicode string or a *Emoji struct")
+	emojiCode, err := emojiReactionCode(emoji)
+	if err != nil {
+		return
 	}
 
 	resp, _, err := client.Delete(&httd.Request{
@@ -245,13 +232,9 @@ func GetReaction(client httd.Getter, channelID, messageID Snowflake, emoji inter
 		return
 	}
 
-	emojiCode := ""
-	if _, ok := emoji.(*Emoji); ok {
-		emojiCode = emoji.(*Emoji).ID.String()
-	} else if _, ok := emoji.(string); ok {
-		emojiCode = emoji.(string) // unicode
-	} else {
-		return nil, errors.New("emoji type can only be a unicode string or a *Emoji struct")
+	emojiCode, err := emojiReactionCode(emoji)
+	if err != nil {
+		return
 	}
 
 	query := ""
@@ -119,6 +119,9 @@ type RESTRequestBuilder struct {
 	urlParams         paramHolder
 	ignoreCache       bool
 	cancelOnRatelimit bool
+
+	// rateLimit holds the rate limit headers of the response, see LastRateLimit
+	rateLimit *httd.RateLimitInfo
 }
 
 func (b *RESTRequestBuilder) setup(cache *Cache, client httd.Requester, config *httd.Request, middleware fRESTRequestMiddleware) {
@@ -158,6 +161,9 @@ func (b *RESTRequestBuilder) execute() (v interface{}, err error) {
 	var resp *http.Response
 	var body []byte
 	resp, body, err = b.client.Request(b.config)
+	if resp != nil {
+		b.updateRateLimit(resp, body)
+	}
 	if err != nil {
 		return
 	}
@@ -187,6 +193,25 @@ func (b *RESTRequestBuilder) execute() (v interface{}, err error) {
 	return
 }
 
+func (b *RESTRequestBuilder) updateRateLimit(resp *http.Response, body []byte) {
+	info, err := httd.ExtractRateLimitInfo(resp, body)
+	if err != nil {
+		return
+	}
+	info.Key = b.config.Ratelimiter
+	b.rateLimit = info
+}
+
+// LastRateLimit returns the parsed rate limit headers of the response to this request, such as the
+// remaining requests and the time until the bucket resets, for callers that pace their own requests.
+// Returns false if no response has been received for the request.
+func (b *RESTRequestBuilder) LastRateLimit() (info httd.RateLimitInfo, ok bool) {
+	if b.rateLimit == nil {
+		return info, false
+	}
+	return *b.rateLimit, true
+}
+
 func (b *RESTRequestBuilder) Param(name string, v interface{}) *RESTRequestBuilder {
 	b.body[name] = v
 	return b
@@ -202,6 +227,13 @@ func (b *RESTRequestBuilder) CancelOnRatelimit() *RESTRequestBuilder {
 	return b
 }
 
+// Priority sends the request before the other requests waiting for the same rate limit, see
+// httd.Request#Priority
+func (b *RESTRequestBuilder) Priority() *RESTRequestBuilder {
+	b.config.Priority = true
+	return b
+}
+
 // GetGateway [REST] Returns an object with a single valid WSS URL, which the client can use for Connecting.
 // Clients should cacheLink this value and only call this endpoint to retrieve a new URL if they are unable to
 // properly establish a connection using the cached version of the URL.
@@ -216,7 +216,7 @@ func GetGuildChannels(client httd.Getter, id Snowflake) (ret []*Channel, err err
 // CreateGuildChannelParams https://discordapp.com/developers/docs/resources/guild#create-guild-channel-json-params
 type CreateGuildChannelParams struct {
 	Name                 string                `json:"name"` // required
-	Type                 *uint                 `json:"type,omitempty"`
+	Type                 *ChannelType          `json:"type,omitempty"`
 	Topic                *string               `json:"topic,omitempty"`
 	Bitrate              *uint                 `json:"bitrate,omitempty"`
 	UserLimit            *uint                 `json:"user_limit,omitempty"`
@@ -106,3 +106,38 @@ var _ httd.Puter = (*reqMocker)(nil)
 var _ httd.Patcher = (*reqMocker)(nil)
 var _ httd.Deleter = (*reqMocker)(nil)
 var _ httd.Requester = (*reqMocker)(nil)
+
+func TestRESTRequestBuilder_LastRateLimit(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     make(http.Header, 2),
+	}
+	resp.Header.Set(httd.XRateLimitRemaining, "3")
+	resp.Header.Set(httd.XRateLimitBucket, "abcd1234")
+	client := &reqMocker{
+		body: []byte(`{"code":"abc"}`),
+		resp: resp,
+	}
+
+	builder := &getInviteBuilder{}
+	builder.itemFactory = inviteFactory
+	builder.IgnoreCache().setup(nil, client, &httd.Request{
+		Method:      http.MethodGet,
+		Ratelimiter: "/invites",
+		Endpoint:    "/invites/abc",
+	}, nil)
+	if _, ok := builder.LastRateLimit(); ok {
+		t.Error("expected no rate limit information before the request")
+	}
+	if _, err := builder.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, ok := builder.LastRateLimit()
+	if !ok {
+		t.Fatal("expected rate limit information")
+	}
+	if info.Remaining != 3 || info.Bucket != "abcd1234" || info.Key != "/invites" {
+		t.Errorf("incorrect rate limit information: %+v", info)
+	}
+}
@@ -0,0 +1,144 @@
+package disgord
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CommandHandler is called by the CommandRouter for the command it was registered with
+type CommandHandler = func(session Session, cmd *Command)
+
+// Command is a message parsed by the CommandRouter
+type Command struct {
+	Name    string   // the first word after the prefix
+	Args    []string // the remaining words. Text in double quotes is one argument
+	Message *Message
+	Ctx     context.Context
+}
+
+// CommandRouterConfig decides which messages the CommandRouter reads as commands
+type CommandRouterConfig struct {
+	// Prefix starts every command, eg. "!" for "!ping"
+	Prefix string
+
+	// Mention also accepts a mention of the bot as prefix, eg. "@bot ping"
+	Mention bool
+}
+
+// CommandRouter is a simple prefix based command router. It is an ordinary MESSAGE_CREATE handler, so it
+// can be used next to other handlers:
+//
+//	router := disgord.NewCommandRouter(&disgord.CommandRouterConfig{Prefix: "!"})
+//	router.Command("ping", func(session disgord.Session, cmd *disgord.Command) {
+//		// reply to cmd.Message
+//	})
+//	client.On(disgord.EventMessageCreate, router.Handle)
+//
+// Messages of the bot itself, and messages without a registered command, are ignored.
+type CommandRouter struct {
+	sync.RWMutex
+	conf     CommandRouterConfig
+	handlers map[string]CommandHandler
+}
+
+// NewCommandRouter creates a command router without any commands
+func NewCommandRouter(conf *CommandRouterConfig) *CommandRouter {
+	return &CommandRouter{
+		conf:     *conf,
+		handlers: make(map[string]CommandHandler),
+	}
+}
+
+// Command registers the handler for the command name. A later handler for the same name replaces it.
+func (r *CommandRouter) Command(name string, handler CommandHandler) {
+	r.Lock()
+	r.handlers[name] = handler
+	r.Unlock()
+}
+
+// Handle parses the message and calls the handler of the command, see MessageCreateCallback
+func (r *CommandRouter) Handle(session Session, evt *MessageCreate) {
+	msg := evt.Message
+	if msg == nil || msg.Author == nil {
+		return
+	}
+
+	me, err := session.Myself()
+	if err != nil {
+		logrus.Error("command router: unable to get the bot user: " + err.Error())
+		return
+	}
+	if msg.Author.ID == me.ID {
+		return
+	}
+
+	content, ok := r.trimPrefix(msg.Content, me)
+	if !ok {
+		return
+	}
+	words := splitCommandArgs(content)
+	if len(words) == 0 {
+		return
+	}
+
+	r.RLock()
+	handler, ok := r.handlers[words[0]]
+	r.RUnlock()
+	if !ok {
+		return
+	}
+
+	handler(session, &Command{
+		Name:    words[0],
+		Args:    words[1:],
+		Message: msg,
+		Ctx:     evt.Ctx,
+	})
+}
+
+// trimPrefix removes the prefix, or the mention of the bot, from the content. Returns false if the content
+// does not start with either.
+func (r *CommandRouter) trimPrefix(content string, me *User) (string, bool) {
+	if r.conf.Prefix != "" && strings.HasPrefix(content, r.conf.Prefix) {
+		return content[len(r.conf.Prefix):], true
+	}
+	if r.conf.Mention {
+		// the nickname mention has an exclamation mark
+		for _, mention := range []string{"<@" + me.ID.String() + ">", "<@!" + me.ID.String() + ">"} {
+			if strings.HasPrefix(content, mention) {
+				return content[len(mention):], true
+			}
+		}
+	}
+	return "", false
+}
+
+// splitCommandArgs splits the text on white space, keeping text in double quotes as one word
+func splitCommandArgs(text string) (words []string) {
+	var word strings.Builder
+	var quoted, inWord bool
+	for _, c := range text {
+		switch {
+		case c == '"':
+			quoted = !quoted
+			inWord = true
+		case unicode.IsSpace(c) && !quoted:
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
@@ -0,0 +1,85 @@
+package disgord
+
+import (
+	"testing"
+)
+
+// routerSession only implements Myself, which is the only method the router uses
+type routerSession struct {
+	Session
+	me *User
+}
+
+func (s *routerSession) Myself() (*User, error) {
+	return s.me, nil
+}
+
+func TestSplitCommandArgs(t *testing.T) {
+	testCases := []struct {
+		text  string
+		words []string
+	}{
+		{"", nil},
+		{"  ping  ", []string{"ping"}},
+		{"ban 123 spamming", []string{"ban", "123", "spamming"}},
+		{`say "hello there"  world`, []string{"say", "hello there", "world"}},
+		{`say ""`, []string{"say", ""}},
+	}
+
+	for _, tc := range testCases {
+		words := splitCommandArgs(tc.text)
+		if len(words) != len(tc.words) {
+			t.Errorf("%q: got %q, wants %q", tc.text, words, tc.words)
+			continue
+		}
+		for i := range words {
+			if words[i] != tc.words[i] {
+				t.Errorf("%q: got %q, wants %q", tc.text, words, tc.words)
+			}
+		}
+	}
+}
+
+func TestCommandRouter(t *testing.T) {
+	session := &routerSession{me: &User{ID: 1}}
+	router := NewCommandRouter(&CommandRouterConfig{Prefix: "!", Mention: true})
+
+	var cmds []*Command
+	router.Command("say", func(session Session, cmd *Command) {
+		cmds = append(cmds, cmd)
+	})
+
+	testCases := []struct {
+		author  Snowflake
+		content string
+		handled bool
+	}{
+		{2, `!say "hello there"`, true},
+		{2, `<@1> say hi`, true},
+		{2, `<@!1>say hi`, true},
+		{2, `<@3> say hi`, false},
+		{2, `say hi`, false},
+		{2, `!unknown`, false},
+		{1, `!say hi`, false}, // the bot itself
+	}
+
+	for _, tc := range testCases {
+		cmds = nil
+		msg := &Message{Author: &User{ID: tc.author}, Content: tc.content}
+		router.Handle(session, &MessageCreate{Message: msg})
+
+		if !tc.handled {
+			if len(cmds) != 0 {
+				t.Errorf("%q: expected the message to be ignored", tc.content)
+			}
+			continue
+		}
+		if len(cmds) != 1 {
+			t.Errorf("%q: expected the command handler to be called once, got %d", tc.content, len(cmds))
+			continue
+		}
+		if cmds[0].Name != "say" || len(cmds[0].Args) == 0 || cmds[0].Message != msg {
+			t.Errorf("%q: incorrect command %+v", tc.content, cmds[0])
+		}
+	}
+}
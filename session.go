@@ -1,6 +1,7 @@
 package disgord
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"time"
@@ -18,10 +19,13 @@ func NewRESTClient(conf *Config) (client *httd.Client) {
 	reqConf := &httd.Config{
 		APIVersion:                   constant.DiscordVersion,
 		BotToken:                     conf.Token,
+		TokenType:                    conf.TokenType,
 		UserAgentSourceURL:           constant.GitHubURL,
 		UserAgentVersion:             constant.Version,
 		HTTPClient:                   conf.HTTPClient,
 		CancelRequestWhenRateLimited: conf.CancelRequestWhenRateLimited,
+		BaseURL:                      conf.RESTBaseURL,
+		DryRun:                       conf.DryRun,
 	}
 	client = httd.NewClient(reqConf)
 	return
@@ -49,6 +53,7 @@ func NewSession(conf *Config) (Session, error) {
 	}
 	dws, err := websocket.NewClient(&websocket.Config{
 		// identity
+		OS:                  conf.OS,
 		Browser:             LibraryInfo(),
 		Device:              conf.ProjectName,
 		GuildLargeThreshold: 250, // TODO: config
@@ -62,8 +67,12 @@ func NewSession(conf *Config) (Session, error) {
 		Endpoint:      conf.WebsocketURL,
 
 		// user settings
-		Token:      conf.Token,
-		HTTPClient: conf.HTTPClient,
+		Token:               conf.Token,
+		HTTPClient:          conf.HTTPClient,
+		OfflineOnDisconnect: conf.OfflineOnDisconnect,
+		DedupePresences:     conf.DedupePresences,
+		DryRun:              conf.DryRun,
+		BeforeIdentify:      conf.BeforeIdentify,
 	})
 	if err != nil {
 		return nil, err
@@ -127,7 +136,10 @@ func NewSession(conf *Config) (Session, error) {
 	}
 
 	// create a disgord client/instance/session
+	ctx, cancel := context.WithCancel(context.Background())
 	c := &Client{
+		ctx:           ctx,
+		cancel:        cancel,
 		shutdownChan:  make(chan interface{}),
 		config:        conf,
 		httpClient:    conf.HTTPClient,
@@ -136,7 +148,11 @@ func NewSession(conf *Config) (Session, error) {
 		token:         conf.Token,
 		evtDispatch:   evtDispatcher,
 		cache:         cacher,
-		req:           reqClient,
+	}
+	// requests without a context of their own are cancelled on disconnect
+	c.req = reqClient.WithContextFunc(c.Context)
+	if conf.EventWorkers > 0 {
+		c.workers = newEventWorkers(conf.EventWorkers, c.shutdownChan)
 	}
 
 	return c, nil
@@ -186,6 +202,11 @@ type EventChannels interface {
 	PresenceUpdate() <-chan *PresenceUpdate
 	PresencesReplace() <-chan *PresencesReplace
 	TypingStart() <-chan *TypingStart
+	ThreadCreate() <-chan *ThreadCreate
+	ThreadUpdate() <-chan *ThreadUpdate
+	ThreadDelete() <-chan *ThreadDelete
+	ThreadListSync() <-chan *ThreadListSync
+	ThreadMemberUpdate() <-chan *ThreadMemberUpdate
 	UserUpdate() <-chan *UserUpdate
 	VoiceStateUpdate() <-chan *VoiceStateUpdate
 	VoiceServerUpdate() <-chan *VoiceServerUpdate
@@ -200,6 +221,7 @@ type SocketHandler interface {
 
 	// event handlers
 	On(event string, handler ...interface{})
+	RegisterHandlers(obj interface{}) error
 	Emit(command SocketCommand, dataPointer interface{}) error
 	//Use(middleware ...interface{}) // TODO: is this useful?
 
@@ -360,6 +382,15 @@ type Session interface {
 	// CRUD operation and not the actual rest endpoints for discord (See Rest()).
 	Req() httd.Requester
 
+	// ReqWithContext is the same as Req, but the requests are cancelled once the context is done
+	ReqWithContext(ctx context.Context) httd.Requester
+
+	// ReqWithPriority is the same as Req, but the requests go before other requests waiting for the same rate limit
+	ReqWithPriority() httd.Requester
+
+	// Context is cancelled when the session disconnects
+	Context() context.Context
+
 	// Cache reflects the latest changes received from Discord gateway.
 	// Should be used instead of requesting objects.
 	Cache() Cacher
@@ -370,6 +401,7 @@ type Session interface {
 	// Discord Gateway, web socket
 	SocketHandler
 	HeartbeatLatency() (duration time.Duration, err error)
+	Ping(ctx context.Context) (duration time.Duration, err error)
 
 	// Generic CRUD operations for Discord interaction
 	DeleteFromDiscord(obj discordDeleter) error
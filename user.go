@@ -226,6 +226,20 @@ func (a *ActivityTimestamp) CopyOverTo(other interface{}) (err error) {
 	return
 }
 
+// ActivityButton is a link shown below the rich presence of the activity
+type ActivityButton struct {
+	Label string `json:"label"` // the text shown on the button, 1-32 characters
+	URL   string `json:"url"`   // the url opened when clicking the button, 1-512 characters
+}
+
+// limits for the activity fields set by a bot
+const (
+	maxActivityButtons     = 2
+	maxActivityTextLength  = 128
+	maxActivityLabelLength = 32
+	maxActivityURLLength   = 512
+)
+
 // NewActivity ...
 func NewActivity() (activity *Activity) {
 	return &Activity{
@@ -249,6 +263,39 @@ type Activity struct {
 	Secrets       *ActivitySecrets     `json:"secrets,omitempty"`        // secrets?	secrets object	secrets for Rich Presence joining and spectating
 	Instance      bool                 `json:"instance,omitempty"`       // instance?	boolean	whether or not the activity is an instanced game session
 	Flags         int                  `json:"flags,omitempty"`          // flags?	int	activity flags ORd together, describes what the payload includes
+	Buttons       []*ActivityButton    `json:"buttons,omitempty"`        // buttons?	array of buttons	at most 2 links shown below the rich presence
+}
+
+// Validate verifies the activity against the limits Discord has for activities in a presence update,
+// such that a rejected update does not go unnoticed.
+func (a *Activity) Validate() (err error) {
+	if a.Name == "" {
+		return errors.New("activity name is empty")
+	} else if len(a.Name) > maxActivityTextLength {
+		return errors.New("activity name is too long")
+	}
+	if a.Details != nil && len(*a.Details) > maxActivityTextLength {
+		return errors.New("activity details is too long")
+	}
+	if a.State != nil && len(*a.State) > maxActivityTextLength {
+		return errors.New("activity state is too long")
+	}
+
+	if len(a.Buttons) > maxActivityButtons {
+		return errors.New("activity has more than " + strconv.Itoa(maxActivityButtons) + " buttons")
+	}
+	for _, button := range a.Buttons {
+		if button == nil {
+			return errors.New("activity button is nil")
+		}
+		if button.Label == "" || len(button.Label) > maxActivityLabelLength {
+			return errors.New("activity button label must be 1-" + strconv.Itoa(maxActivityLabelLength) + " characters")
+		}
+		if button.URL == "" || len(button.URL) > maxActivityURLLength {
+			return errors.New("activity button url must be 1-" + strconv.Itoa(maxActivityURLLength) + " characters")
+		}
+	}
+	return nil
 }
 
 // DeepCopy see interface at struct.go#DeepCopier
@@ -311,6 +358,16 @@ func (a *Activity) CopyOverTo(other interface{}) (err error) {
 	if a.Secrets != nil {
 		activity.Secrets = a.Secrets.DeepCopy().(*ActivitySecrets)
 	}
+	if a.Buttons != nil {
+		activity.Buttons = make([]*ActivityButton, len(a.Buttons))
+		for i, button := range a.Buttons {
+			if button == nil {
+				continue
+			}
+			b := *button
+			activity.Buttons[i] = &b
+		}
+	}
 
 	if constant.LockedMethods {
 		a.RUnlock()
@@ -3,6 +3,7 @@ package disgord
 import (
 	"fmt"
 	"io/ioutil"
+	"strings"
 	"testing"
 
 	"github.com/andersfylling/disgord/httd"
@@ -282,3 +283,33 @@ func TestCreateGroupDM(t *testing.T) {
 func TestGetUserConnections(t *testing.T) {
 	// Missing OAuth2
 }
+
+func TestActivity_Validate(t *testing.T) {
+	long := strings.Repeat("a", 129)
+	button := func(label, url string) *ActivityButton {
+		return &ActivityButton{Label: label, URL: url}
+	}
+
+	testCases := []struct {
+		name     string
+		activity *Activity
+		valid    bool
+	}{
+		{"name only", &Activity{Name: "test"}, true},
+		{"long details", &Activity{Name: "test", Details: &long}, false},
+		{"buttons", &Activity{Name: "test", Buttons: []*ActivityButton{button("a", "https://a"), button("b", "https://b")}}, true},
+		{"empty name", &Activity{}, false},
+		{"long name", &Activity{Name: long}, false},
+		{"too many buttons", &Activity{Name: "test", Buttons: []*ActivityButton{button("a", "https://a"), button("b", "https://b"), button("c", "https://c")}}, false},
+		{"empty label", &Activity{Name: "test", Buttons: []*ActivityButton{button("", "https://a")}}, false},
+		{"empty url", &Activity{Name: "test", Buttons: []*ActivityButton{button("a", "")}}, false},
+	}
+	for _, tc := range testCases {
+		err := tc.activity.Validate()
+		if tc.valid && err != nil {
+			t.Errorf("%s: unexpected error: %s", tc.name, err)
+		} else if !tc.valid && err == nil {
+			t.Errorf("%s: expected error", tc.name)
+		}
+	}
+}
@@ -1,12 +1,16 @@
 package disgord
 
 import (
+	"context"
+	"errors"
 	"net/http"
 
 	"github.com/andersfylling/disgord/constant"
 	"github.com/andersfylling/disgord/endpoint"
+	"github.com/andersfylling/disgord/event"
 	"github.com/andersfylling/disgord/httd"
 	"github.com/andersfylling/disgord/ratelimit"
+	"github.com/andersfylling/disgord/websocket"
 )
 
 // VoiceState Voice State structure
@@ -200,3 +204,68 @@ func (b *listVoiceRegionsBuilder) Execute() (regions []*VoiceRegion, err error)
 	regions = v.([]*VoiceRegion)
 	return
 }
+
+// VoiceConnectionInfo holds what is needed to establish a voice websocket connection for a guild. It combines
+// the session ID from the bot's VOICE_STATE_UPDATE with the token and endpoint from VOICE_SERVER_UPDATE.
+type VoiceConnectionInfo struct {
+	GuildID   Snowflake
+	ChannelID Snowflake
+	UserID    Snowflake
+	SessionID string
+	Token     string
+	Endpoint  string
+}
+
+// WaitForVoiceConnectionInfo waits until both the voice state and the voice server of the bot user has been
+// received for the given guild, or the context is done. Call it before sending the voice state update
+// command, so none of the events are missed. A voice server update without an endpoint means the voice
+// server is being changed, and is ignored until the next update.
+func (c *Client) WaitForVoiceConnectionInfo(ctx context.Context, guildID Snowflake) (info *VoiceConnectionInfo, err error) {
+	events, unsubscribe := c.ws.SubscribeTo([]string{event.VoiceStateUpdate, event.VoiceServerUpdate}, nil)
+	defer unsubscribe()
+
+	myself, err := c.Myself()
+	if err != nil {
+		return nil, err
+	}
+
+	info = &VoiceConnectionInfo{
+		GuildID: guildID,
+		UserID:  myself.ID,
+	}
+	for info.SessionID == "" || info.Endpoint == "" {
+		var evt *websocket.Event
+		select {
+		case evt = <-events:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if evt == nil {
+			return nil, errors.New("voice event subscription was closed")
+		}
+
+		switch evt.Name {
+		case event.VoiceStateUpdate:
+			update := &VoiceStateUpdate{}
+			if err = unmarshal(evt.Data, update); err != nil {
+				return nil, err
+			}
+			state := update.VoiceState
+			if state.GuildID == guildID && state.UserID == info.UserID {
+				info.SessionID = state.SessionID
+				info.ChannelID = state.ChannelID
+			}
+		case event.VoiceServerUpdate:
+			update := &VoiceServerUpdate{}
+			if err = unmarshal(evt.Data, update); err != nil {
+				return nil, err
+			}
+			if update.GuildID == guildID {
+				info.Token = update.Token
+				info.Endpoint = update.Endpoint
+			}
+		}
+	}
+
+	return info, nil
+}
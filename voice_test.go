@@ -73,3 +73,16 @@ func TestListVoiceRegions(t *testing.T) {
 		t.Error("expected at least one voice region")
 	}
 }
+
+func TestVoiceStateUpdate_UnmarshalJSON(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/voice/state1.json")
+	check(err, t)
+
+	update := VoiceStateUpdate{}
+	err = httd.Unmarshal(data, &update)
+	check(err, t)
+
+	if update.VoiceState == nil || update.VoiceState.SessionID == "" {
+		t.Error("expected the voice state to be unmarshalled from the payload")
+	}
+}
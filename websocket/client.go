@@ -1,11 +1,13 @@
 package websocket
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/rand"
 	"net/http"
 	"runtime"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -19,11 +21,25 @@ import (
 
 const (
 	maxReconnectTries = 5
+
+	// latency probes share the command rate limit (120 per 60 seconds) with everything else, so make sure
+	// they can never use more than half of it
+	minLatencyProbeInterval = time.Second
+
+	// how long Disconnect waits for the emitter to pick up the close signal, before closing the connection
+	// itself
+	closeEmitTimeout = time.Second
 )
 
 // NewManager creates a new socket client manager for handling behavior and Discord events. Note that this
 // function initiates a go routine.
 func NewClient(config *Config) (client *Client, err error) {
+	if err = validateShard(config); err != nil {
+		return nil, err
+	}
+	if err = validateHeartbeatInterval(config); err != nil {
+		return nil, err
+	}
 	ws, err := newConn(config.HTTPClient)
 	if err != nil {
 		return nil, err
@@ -40,13 +56,43 @@ func NewClient(config *Config) (client *Client, err error) {
 		ratelimit:         newRatelimiter(),
 		timeoutMultiplier: 1,
 		disconnected:      true,
+		replay:            newEventReplay(config.ReplayBufferSize),
+		random:            newRandom(config.Rand),
+	}
+	if config.SessionStartLimit != nil {
+		client.sessionStarts = newSessionStartGuard(*config.SessionStartLimit, sessionStartReserve(config))
 	}
 	client.Start()
 
 	return
 }
 
+// validateShard verifies that the shard ID is within the shard count, as Discord otherwise closes the
+// connection after identifying. A shard count of 0 is the same as 1, where the shard ID must be 0.
+func validateShard(conf *Config) error {
+	count := conf.ShardCount
+	if count == 0 {
+		count = 1
+	}
+	if conf.ShardID >= count {
+		return errors.New("shard ID " + strconv.Itoa(int(conf.ShardID)) + " is out of range for a shard count of " + strconv.Itoa(int(count)))
+	}
+	return nil
+}
+
+// validateHeartbeatInterval verifies that a heartbeat interval override is at least a millisecond, as
+// Discord gives the interval in milliseconds and a shorter one would be truncated to none.
+func validateHeartbeatInterval(conf *Config) error {
+	if conf.HeartbeatInterval > 0 && conf.HeartbeatInterval < time.Millisecond {
+		return errors.New("heartbeat interval " + conf.HeartbeatInterval.String() + " is below the minimum of 1ms")
+	}
+	return nil
+}
+
 func NewTestClient(config *Config, conn Conn) (*Client, chan interface{}) {
+	if config == nil {
+		config = &Config{}
+	}
 	s := make(chan interface{})
 	c := &Client{
 		conf:              config,
@@ -59,18 +105,38 @@ func NewTestClient(config *Config, conn Conn) (*Client, chan interface{}) {
 		ratelimit:         newRatelimiter(),
 		timeoutMultiplier: 1,
 		disconnected:      true,
+		replay:            newEventReplay(config.ReplayBufferSize),
+		random:            newRandom(config.Rand),
 	}
 	c.Start()
-	go c.receiver()
+	go c.receiver(c.conn.Read, nil, nil)
 
 	return c, s
 }
 
 // Event is dispatched by the socket layer after parsing and extracting Discord data from a incoming packet.
-// This is the data structure used by Disgord for triggering handlers and channels with an event.
+// This is the data structure used by Disgord for triggering handlers and channels with an event. The same
+// Event, and its Data slice, is handed to the event channel, every subscriber and the replay buffer, so
+// it is shared and must be treated as read-only. Copy Data before changing it.
 type Event struct {
 	Name string
 	Data []byte
+
+	// discordName is the event name given by Discord, which Name differs from when Config.EventNameMapper
+	// is set
+	discordName string
+
+	// subscribersOnly is set for events that are not registered, but kept for Client#SubscribeTo
+	subscribersOnly bool
+}
+
+// eventName returns the event name given by Discord, which is used for routing regardless of
+// Config.EventNameMapper
+func (evt *Event) eventName() string {
+	if evt.discordName != "" {
+		return evt.discordName
+	}
+	return evt.Name
 }
 
 type Config struct {
@@ -80,79 +146,309 @@ type Config struct {
 	// HTTPClient custom http client to support the use of proxy
 	HTTPClient *http.Client
 
-	// ChannelBuffer is used to set the event channel buffer
+	// ChannelBuffer is used to set the event channel buffer, and the buffer of every subscriber channel
 	ChannelBuffer uint
 
+	// ReplayBufferSize is the number of recent events kept for consumers that attach late, see Client#ReplayRecent.
+	// Defaults to 0, which disables the replay buffer.
+	ReplayBufferSize uint
+
+	// DeliverUnregistered delivers every Discord event, including the ones that were not registered with
+	// Client#RegisterEvent, so new Discord events can be picked up without code changes. Defaults to false,
+	// which drops unregistered events in the socket layer.
+	DeliverUnregistered bool
+
+	// EventNameMapper renames the events before they are delivered, eg. to lowercase or dotted names for
+	// another event framework. Only Event.Name is changed; Client#RegisterEvent, Client#TypedChan and
+	// Client#WaitForEvent still take the names given by Discord. The disgord client relies on the Discord names,
+	// so this is for using the websocket package on its own. Defaults to nil, which keeps the Discord names.
+	EventNameMapper func(name string) string
+
+	// EventSendTimeout is how long the socket layer waits for the application to receive an event, before
+	// the SlowConsumerPolicy is applied. This makes sure an application that stops reading events never
+	// holds back heartbeats, which would otherwise cause Discord to close the connection. Defaults to
+	// 1 second.
+	EventSendTimeout time.Duration
+
+	// SlowConsumerPolicy decides what happens to events the application did not receive in time. Defaults
+	// to BufferEvents.
+	SlowConsumerPolicy SlowConsumerPolicy
+
+	// EventBufferSize is the number of events kept for an application that does not keep up, when the
+	// SlowConsumerPolicy is BufferEvents. Defaults to 1000.
+	EventBufferSize uint
+
+	// PauseBufferSize is the number of events kept while the event delivery is paused, see Client#Pause.
+	// Defaults to 1000.
+	PauseBufferSize uint
+
 	// Endpoint for establishing socket connection. Either endpoints, `Gateway` or `Gateway Bot`, is used to retrieve
 	// a valid socket endpoint from Discord
 	Endpoint string
 
-	// Encoding make sure we support the correct encoding
+	// GatewayRouteRetries is the number of times fetching the socket endpoint is retried, with an increasing
+	// delay, when Discord cannot be reached. If every attempt fails, the last endpoint fetched by any client
+	// in this process is used when there is one. Defaults to 0, which fails Connect on the first error.
+	GatewayRouteRetries uint
+
+	// DedupePresences drops PRESENCE_UPDATE events that are identical to the last one of the same user in
+	// the same guild, which reduces the load of presence heavy bots. The last presence of up to 131072 users
+	// per shard is remembered, counting a user once per guild, which takes at most about 5 MB. Defaults to
+	// false, which delivers every presence update.
+	DedupePresences bool
+
+	// DialHeaders are added to the websocket upgrade request, such as proxy authentication or a
+	// Sec-WebSocket-Protocol header. Defaults to no extra headers.
+	DialHeaders http.Header
+
+	// Encoding make sure we support the correct encoding. Only "json" is supported, which is the default.
 	Encoding string
 
 	// Version make sure we support the correct Discord version
 	Version int
 
+	// ResumeRetries is the number of times a resume is retried, with an increasing delay, when Discord
+	// answers it with an invalid session. Afterwards a new session is identified. Defaults to 0.
+	ResumeRetries uint
+
+	// DisableAutoReconnect stops the client from reconnecting when the connection is lost. Use OnDisconnect
+	// to be notified, and call Connect to reconnect.
+	DisableAutoReconnect bool
+
+	// OnDisconnect is called when the connection to Discord is lost, before any reconnect attempt
+	OnDisconnect func()
+
+	// OnDeprecation is called when the gateway version is known to be discontinued, or when Discord closes
+	// the connection because of an invalid gateway version. The warning is always logged.
+	OnDeprecation func(version int, msg string)
+
+	// OnCommand is called with the outcome of every command given to Emit, heartbeats included, at the same
+	// time as the counters of Client#Status are updated, eg. to export them as metrics. It is called by the
+	// emitter and must not block.
+	OnCommand func(command string, outcome CommandOutcome)
+
+	// Rand is the random source for the reconnect and invalid session delays. Set it to make those delays
+	// reproducible in tests. Defaults to a source seeded by crypto/rand.
+	Rand *rand.Rand
+
+	// LatencyProbeInterval sends extra heartbeats between the regular ones to sample the heartbeat latency
+	// more often than the heartbeat interval given by Discord. Probes are subject to the command rate limit,
+	// and any value below one second is treated as one second. Defaults to 0, which disables probing.
+	LatencyProbeInterval time.Duration
+
+	// HeartbeatInterval overrides the heartbeat interval given by Discord in the hello packet, eg. to drive
+	// several heartbeats in milliseconds during tests. Heartbeating slower than Discord asks for gets the
+	// connection closed. Must be at least 1ms. Defaults to 0, which uses the interval given by Discord.
+	HeartbeatInterval time.Duration
+
+	// PingInterval sends websocket ping frames, which are separate from the Discord heartbeats, and
+	// reconnects when a pong has not been received by the next ping. This detects dead connections through
+	// load balancers and proxies that drop idle TCP connections. Defaults to 0, which disables pinging.
+	PingInterval time.Duration
+
+	// IdentifyGate is asked for permission before every identify command, see IdentifyGate. Defaults to the
+	// gate shared by the shards of a ShardManager, or none for a stand-alone client.
+	IdentifyGate IdentifyGate
+
+	// SessionStartLimit is the session start limit of the bot token, as given by GatewayBot. When set,
+	// identify commands are held back until the limit resets once only SessionStartReserve session starts
+	// remain, such that a reconnect storm does not get the token banned from identifying. Shards of a
+	// ShardManager share the limit of the template token, while shards with a token override use the limit
+	// given by ShardManagerConfig.TokenSessionStartLimits. See also Client#SetSessionStartLimit. Defaults
+	// to nil, which does not guard session starts.
+	SessionStartLimit *SessionStartLimit
+
+	// SessionStartReserve is the number of session starts kept in reserve, see SessionStartLimit.
+	// Defaults to 5.
+	SessionStartReserve uint
+
+	// BeforeIdentify is called with the identify payload before the client waits for its turn to identify,
+	// see IdentifyGate, eg. to set a presence based on the current time. The payload may be changed, except
+	// for the token and the shard array: they must match Token, ShardID and ShardCount, as the identify
+	// budget and the shard routing depend on them, so changing either fails the identify without using up
+	// an identify slot. Called once for every identify command. Defaults to nil.
+	BeforeIdentify func(payload *IdentifyPayload)
+
+	// Intents limits the events Discord sends to the ones of the given intents, see EventIntents. Defaults
+	// to 0, which does not send any intents.
+	Intents Intent
+
+	// OfflineOnDisconnect sends an invisible presence before Disconnect and Shutdown close the connection,
+	// such that the bot is shown as offline right away, instead of once Discord notices the session is gone.
+	// Defaults to false.
+	OfflineOnDisconnect bool
+
+	// DryRun records the commands given to Emit instead of sending them, see Client#RecordedCommands. The
+	// commands are still validated, rate limited and encoded, but the client never connects: Connect and
+	// Disconnect do nothing, and commands are accepted without waiting for a session. Meant for testing
+	// bot logic without reaching Discord. Defaults to false.
+	DryRun bool
+
+	// StrictIntents makes Connect fail when a registered event is not enabled by the intents, instead of
+	// only logging a warning.
+	StrictIntents bool
+
 	// for identify packets
+	OS                  string // reported operating system, defaults to runtime.GOOS
 	Browser             string
 	Device              string
+	Compress            bool // whether Discord may compress the large payloads, such as READY
 	GuildLargeThreshold uint
 	ShardID             uint
 	ShardCount          uint
 }
 
+// Config returns a copy of the configuration the client uses, with the token left out. The endpoint is
+// filled in once it has been fetched from Discord.
+func (m *Client) Config() Config {
+	m.RLock()
+	conf := *m.conf
+	m.RUnlock()
+
+	conf.Token = ""
+	if conf.DialHeaders != nil {
+		headers := make(http.Header, len(conf.DialHeaders))
+		for key, values := range conf.DialHeaders {
+			headers[key] = append([]string(nil), values...)
+		}
+		conf.DialHeaders = headers
+	}
+	return conf
+}
+
 type Client struct {
 	sync.RWMutex
 	conf         *Config
 	shutdown     chan interface{}
 	restart      chan interface{}
-	lastRestart  int64 //unix
+	reconnecting bool
 	restartMutex sync.Mutex
 
 	eventChan     chan *Event
-	trackedEvents []string
+	trackedEvents map[string]struct{}
+	subscribed    map[string]uint // number of Client#SubscribeTo subscriptions per event
 	evtMutex      sync.RWMutex
+	replay        *eventReplay
+	random        *lockedRand
+	subscribers   subscribers
+	typed         typedChannels
+	pause         pauseGate
+	outboxes      outboxes
+	presences     presenceDedupe
+	guilds        guildTracker
 
 	heartbeatInterval uint
 	heartbeatLatency  time.Duration
 	lastHeartbeatAck  time.Time
+	lastHeartbeatSent time.Time
+
+	// pingWaiters are notified on the next heartbeat ACK, see Client#Ping
+	pingWaiters []chan time.Time
+	pingMutex   sync.Mutex
 
 	sessionID      string
 	trace          []string
+	readyData      *Ready // last READY payload
 	sequenceNumber uint
+	resuming       bool // until Discord answers the resume
+	resumeRetries  uint
 
 	ratelimit ratelimiter
+	commands  commandCounters
+	recorder  commandRecorder // see Config.DryRun
+
+	// identifyLimit is shared between shards using the same bot token. nil when not managed by a ShardManager.
+	// Config.IdentifyGate takes precedence.
+	identifyLimit *identifyLimiter
+
+	// sessionStarts holds back identify commands when few session starts remain. nil unless a session start
+	// limit is given. Guarded by the client lock.
+	sessionStarts *sessionStartGuard
 
 	pulsating  uint8
 	pulseMutex sync.Mutex
 
-	receiveChan       chan *discordPacket
-	emitChan          chan *clientPacket
-	conn              Conn
+	// receiveChan lives as long as the client and is never closed. Every connection gets its own receiver,
+	// but they all forward to this channel, such that the single operation handler keeps handling packets
+	// across reconnects.
+	receiveChan chan *discordPacket
+	emitChan    chan *clientPacket
+	conn        Conn
+
+	// routines tracks the receiver and emitter of the last connection, such that a new connection is not
+	// opened before they exit. Guarded by the client lock, as only Client#connect touches it.
+	routines *sync.WaitGroup
+
+	// connection state flags. These are read by Emit, which can not hold the client lock as it is called
+	// from methods already holding it. Always access them through stateMutex.
+	stateMutex        sync.RWMutex
 	disconnected      bool
 	haveConnectedOnce bool
+	shuttingDown      bool
+	helloReceived     bool // on the current connection
+	haveBeenReady     bool
+	ready             bool
+	readyChan         chan interface{} // closed once ready
+	connectedSince    time.Time
+	lastDisconnect    time.Time
+	connectLatency    time.Duration    // dial and upgrade of the current or last connection
+	helloLatency      time.Duration    // from the connection being established until hello, 0 until then
+	connection        uint             // incremented for every new connection
+	connectionClosed  chan interface{} // closed once the current connection is closed
+	closeCode         CloseCode        // of the current connection, 0 until Discord closes it
+	lastCloseCode     CloseCode
 
 	// identify timeout on invalid session
 	timeoutMultiplier int
 }
 
-// Connect establishes a socket connection with the Discord API
+// Connect establishes a socket connection with the Discord API. Returns an error if a connection
+// already exists, see Client#ConnectIfNeeded.
 func (m *Client) Connect() (err error) {
+	return m.connect(false)
+}
+
+// ConnectIfNeeded establishes a socket connection with the Discord API, unless a connection already
+// exists, in which case nil is returned.
+func (m *Client) ConnectIfNeeded() (err error) {
+	return m.connect(true)
+}
+
+func (m *Client) connect(ifNeeded bool) (err error) {
+	if m.conf.DryRun {
+		return nil
+	}
+
+	// fetching the route backs off between retries, so it must not hold the client lock
+	m.RLock()
+	endpoint := m.conf.Endpoint
+	m.RUnlock()
+	if endpoint == "" && m.isDisconnected() {
+		if endpoint, err = m.gatewayRoute(); err != nil {
+			return
+		}
+	}
+
 	m.Lock()
 	defer m.Unlock()
 
 	// m.conn.Disconnected can always tell us if we are disconnected, but it cannot with
 	// certainty say if we are connected
-	if !m.disconnected {
+	if !m.isDisconnected() {
+		if ifNeeded {
+			return nil
+		}
 		err = errors.New("cannot connect while a connection already exist")
 		return
 	}
 
 	if m.conf.Endpoint == "" {
-		m.conf.Endpoint, err = getGatewayRoute(m.conf.HTTPClient, m.conf.Version)
-		if err != nil {
+		if endpoint == "" {
+			// the connection was lost after the route was skipped
+			err = errors.New("missing gateway endpoint, try to connect again")
 			return
 		}
+		m.conf.Endpoint = endpoint
 	}
 
 	// ready the error handler
@@ -166,33 +462,194 @@ func (m *Client) Connect() (err error) {
 		return nil
 	}(err)
 
+	m.warnIfVersionDeprecated()
+	if err = validateShard(m.conf); err != nil {
+		return
+	}
+	if err = validateHeartbeatInterval(m.conf); err != nil {
+		return
+	}
+	if err = m.validateIntents(); err != nil {
+		return
+	}
+	if _, err = codecFor(m.conf.Encoding); err != nil {
+		return
+	}
+
+	// the goroutines of the previous connection must not read from or write to the new one
+	m.waitForRoutines()
+
 	// establish ws connection
-	err = m.conn.Open(m.conf.Endpoint, nil)
+	dialed := time.Now()
+	err = m.conn.Open(m.conf.Endpoint, m.conf.DialHeaders)
 	if err != nil {
 		return
 	}
+	connectLatency := time.Since(dialed)
 
 	// we can now interact with Discord
-	m.haveConnectedOnce = true
-	m.disconnected = false
-	go m.receiver()
-	go m.emitter()
+	m.setDisconnected(false)
+	m.stateMutex.Lock()
+	m.connectLatency = connectLatency
+	closed := m.connectionClosed
+	m.stateMutex.Unlock()
+	m.routines = &sync.WaitGroup{}
+	m.routines.Add(2)
+	go m.receiver(m.connReader(), closed, m.routines)
+	go m.emitter(closed, m.routines)
+	if conn, ok := m.conn.(pinger); ok && m.conf.PingInterval > 0 {
+		m.stateMutex.RLock()
+		connection := m.connection
+		m.stateMutex.RUnlock()
+		go m.keepAlive(conn, connection)
+	}
 	return
 }
 
+// connReader returns the read function of the connection that was just opened. Connections that can bind
+// it to the open connection do, such that a lingering receiver never reads from the next connection.
+func (m *Client) connReader() func() ([]byte, error) {
+	if conn, ok := m.conn.(boundReader); ok {
+		return conn.reader()
+	}
+	return m.conn.Read
+}
+
+// waitForRoutines waits for the receiver and emitter of the previous connection to exit. They stop once
+// the connection is closed, unless a read is stuck, so the wait is bounded. The caller must hold the
+// client lock.
+func (m *Client) waitForRoutines() {
+	if m.routines == nil {
+		return
+	}
+
+	stopped := make(chan interface{})
+	go func(routines *sync.WaitGroup) {
+		routines.Wait()
+		close(stopped)
+	}(m.routines)
+	select {
+	case <-stopped:
+	case <-time.After(closeEmitTimeout):
+		logrus.Debug("the goroutines of the previous connection did not stop in time")
+	}
+	m.routines = nil
+}
+
+// keepAlive pings the connection every Config#PingInterval, and reconnects if a pong was not received
+// since the previous ping. It stops once the given connection is closed.
+func (m *Client) keepAlive(conn pinger, connection uint) {
+	ticker := time.NewTicker(m.conf.PingInterval)
+	defer ticker.Stop()
+
+	var lastPing time.Time
+	for {
+		select {
+		case <-ticker.C:
+		case <-m.shutdown:
+			return
+		}
+
+		m.stateMutex.RLock()
+		closed := m.disconnected || m.connection != connection
+		m.stateMutex.RUnlock()
+		if closed {
+			return
+		}
+
+		if !lastPing.IsZero() && conn.LastPong().Before(lastPing) {
+			logrus.Info("websocket pong was not received, forcing reconnect")
+			go m.reconnect()
+			return
+		}
+
+		lastPing = time.Now()
+		if err := conn.Ping(); err != nil {
+			logrus.Debug("unable to ping the connection: " + err.Error())
+		}
+	}
+}
+
+// gatewayRoute fetches the socket endpoint, retrying as configured by Config#GatewayRouteRetries. The wait
+// between attempts doubles, starting at one second, and stops on shutdown.
+func (m *Client) gatewayRoute() (url string, err error) {
+	delay := time.Second * time.Duration(m.timeoutMultiplier)
+	for attempt := uint(0); ; attempt++ {
+		url, err = getGatewayRoute(m.conf.HTTPClient, m.conf.Version)
+		if err == nil {
+			cacheGatewayRoute(m.conf.Version, url)
+			return url, nil
+		}
+		if attempt == m.conf.GatewayRouteRetries {
+			break
+		}
+
+		logrus.Warn("unable to get gateway endpoint, retrying in " + delay.String() + ": " + err.Error())
+		select {
+		case <-time.After(delay):
+		case <-m.shutdown:
+			return "", errors.New("shutting down")
+		}
+		delay *= 2
+	}
+
+	if cached := cachedGatewayRoute(m.conf.Version); cached != "" {
+		logrus.Warn("unable to get gateway endpoint, using the last known endpoint: " + err.Error())
+		return cached, nil
+	}
+	return "", err
+}
+
 // Disconnect disconnects the socket connection
 func (m *Client) Disconnect() (err error) {
+	m.goOffline()
+	return m.disconnect(false)
+}
+
+// goOffline sends an invisible presence when configured by Config#OfflineOnDisconnect, and waits for it to
+// be written such that it reaches Discord before the connection is closed
+func (m *Client) goOffline() {
+	if !m.conf.OfflineOnDisconnect || m.ConnectionStatus() != StatusReady {
+		return
+	}
+
+	// emit blocks when the emitter has exited, so the wait is bounded like the close signal
+	sent := make(chan error, 1)
+	go func() {
+		if err := m.emit(cmd.UpdateStatus, &offlinePresence{Status: "invisible"}, sent); err != nil {
+			sent <- err
+		}
+	}()
+
+	var err error
+	select {
+	case err = <-sent:
+	case <-time.After(closeEmitTimeout):
+		err = errors.New("timed out")
+	}
+	if err != nil {
+		logrus.Warn("unable to send the offline presence: " + err.Error())
+	}
+}
+
+// disconnect closes the socket connection. A resumable close keeps the Discord session valid, such that it
+// can be resumed on the next connection.
+func (m *Client) disconnect(resumable bool) (err error) {
+	if m.conf.DryRun {
+		return nil
+	}
+
 	m.Lock()
 	defer m.Unlock()
-	if m.conn.Disconnected() || !m.haveConnectedOnce {
-		m.disconnected = true
+	if m.conn.Disconnected() || !m.haveConnected() {
+		m.setDisconnected(true)
 		err = errors.New("already disconnected")
 		return
 	}
 
 	// use the emitter to dispatch the close message
-	m.Emit(event.Close, nil)
-	m.disconnected = true
+	m.emitClose(&closeSignal{resumable: resumable})
+	m.setDisconnected(true)
 
 	// close connection
 	<-time.After(time.Second * 1 * time.Duration(m.timeoutMultiplier))
@@ -202,43 +659,203 @@ func (m *Client) Disconnect() (err error) {
 	return
 }
 
-// Emit emits a command, if supported, and its data to the Discord Socket API
+func (m *Client) setDisconnected(disconnected bool) {
+	m.stateMutex.Lock()
+	defer m.stateMutex.Unlock()
+
+	if disconnected && !m.disconnected {
+		m.lastDisconnect = time.Now()
+		m.connectedSince = time.Time{}
+		if m.connectionClosed != nil {
+			close(m.connectionClosed)
+			m.connectionClosed = nil
+		}
+	} else if !disconnected && m.disconnected {
+		m.connectedSince = time.Now()
+		m.connectionClosed = make(chan interface{})
+		m.helloLatency = 0
+		m.closeCode = 0
+		m.connection++
+	}
+
+	m.disconnected = disconnected
+	if disconnected {
+		m.helloReceived = false
+		m.setReady(false)
+	} else {
+		m.haveConnectedOnce = true
+	}
+}
+
+func (m *Client) isDisconnected() bool {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+
+	return m.disconnected
+}
+
+func (m *Client) haveConnected() bool {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+
+	return m.haveConnectedOnce
+}
+
+// Emit emits a command, if supported, and its data to the Discord Socket API.
+//
+// Emit is safe for concurrent use. Every command passes through the same rate limiter and is then handed
+// to a single emitter go routine which writes them in the order they were handed over. Emit blocks until the
+// emitter has picked up the command, so a slow connection (or a reconnect) will hold back the callers; once
+// the client is shut down Emit returns an error instead of blocking.
+//
+// Commands given before Discord accepts them return a *ErrorNotReady: gateway commands such as
+// UPDATE_STATUS require the session to have been ready at least once. Commands larger than MaxPayloadSize
+// once encoded return a *ErrorPayloadTooLarge, as Discord would close the connection.
 func (m *Client) Emit(command string, data interface{}) (err error) {
-	if !m.haveConnectedOnce {
-		return errors.New("race condition detected: you must connect to the socket API/Gateway before you can send gateway commands!")
+	return m.emit(command, data, nil)
+}
+
+// EmitWait is the same as Emit, but waits for the command rate limit to allow the command instead of
+// returning ErrRateLimited. Returns the context error if the context is done first.
+func (m *Client) EmitWait(ctx context.Context, command string, data interface{}) error {
+	for {
+		if wait := m.ratelimit.RetryAfter(command); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		// another command might have taken the capacity in the meantime
+		if err := m.Emit(command, data); err != ErrRateLimited {
+			return err
+		}
+	}
+}
+
+// EmitSync is the same as Emit, but blocks until the command has been written to the socket. The returned
+// error tells whether the command actually reached Discord, eg. it was rate limited, or the write failed.
+func (m *Client) EmitSync(command string, data interface{}) (err error) {
+	sent := make(chan error, 1)
+	if err = m.emit(command, data, sent); err != nil {
+		return err
+	}
+
+	select {
+	case err = <-sent:
+	case <-m.shutdown:
+		err = errors.New("client has shut down")
+	}
+	return
+}
+
+// commandOpcodes maps the commands accepted by Emit to their operation code
+var commandOpcodes = map[string]uint{
+	event.Shutdown:          opcode.Shutdown,
+	event.Close:             opcode.Close,
+	event.Heartbeat:         opcode.Heartbeat,
+	event.Identify:          opcode.Identify,
+	event.Resume:            opcode.Resume,
+	cmd.RequestGuildMembers: opcode.RequestGuildMembers,
+	cmd.UpdateVoiceState:    opcode.VoiceStateUpdate,
+	cmd.UpdateStatus:        opcode.StatusUpdate,
+}
+
+// SupportedCommands returns the commands that can be given to Client#Emit, sorted by name
+func SupportedCommands() []string {
+	commands := make([]string, 0, len(commandOpcodes))
+	for command, op := range commandOpcodes {
+		if op == opcode.Shutdown || op == opcode.Close {
+			continue // internal
+		}
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+	return commands
+}
+
+// SupportedOpcodes returns the operation codes received from Discord that the client handles, sorted
+func SupportedOpcodes() []uint {
+	ops := make([]uint, len(handledOpcodes))
+	copy(ops, handledOpcodes)
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i] < ops[j]
+	})
+	return ops
+}
+
+func (m *Client) emit(command string, data interface{}, sent chan error) (err error) {
+	m.stateMutex.RLock()
+	connected := m.haveConnectedOnce
+	shuttingDown := m.shuttingDown
+	helloReceived := m.helloReceived
+	haveBeenReady := m.haveBeenReady
+	m.stateMutex.RUnlock()
+	if m.conf.DryRun {
+		// there is no connection or session to wait for
+		connected, helloReceived, haveBeenReady = true, true, true
+	}
+	if !connected {
+		return &ErrorNotReady{Command: command, Status: StatusDisconnected}
+	}
+	// the close command is still needed to disconnect during shutdown
+	if shuttingDown && command != event.Close {
+		m.countCommand(command, CommandDropped)
+		return errors.New("client is shutting down")
 	}
 
-	var op uint
 	switch command {
-	case event.Shutdown:
-		op = opcode.Shutdown
-	case event.Close:
-		op = opcode.Close
-	case event.Heartbeat:
-		op = opcode.Heartbeat
-	case event.Identify:
-		op = opcode.Identify
-	case event.Resume:
-		op = opcode.Resume
-	case cmd.RequestGuildMembers:
-		op = opcode.RequestGuildMembers
-	case cmd.UpdateVoiceState:
-		op = opcode.VoiceStateUpdate
-	case cmd.UpdateStatus:
-		op = opcode.StatusUpdate
-	default:
+	case event.Heartbeat, event.Identify, event.Resume:
+		// Discord must have said hello on this connection
+		if !helloReceived {
+			return &ErrorNotReady{Command: command, Status: StatusConnected}
+		}
+	case cmd.RequestGuildMembers, cmd.UpdateVoiceState, cmd.UpdateStatus:
+		// once the first session is ready, commands are held back during reconnects instead
+		if !haveBeenReady {
+			return &ErrorNotReady{Command: command, Status: StatusConnected}
+		}
+	}
+
+	op, supported := commandOpcodes[command]
+	if !supported {
 		err = errors.New("unsupported command: " + command)
 		return
 	}
 
+	// the internal commands are never sent to Discord, they only tell the emitter to close the connection
+	if _, isSignal := data.(*closeSignal); isSignal != (op == opcode.Shutdown || op == opcode.Close) {
+		err = errors.New("close signals are only valid for the internal close commands")
+		return
+	}
+
+	// an oversized command would get the connection closed, so it does not take any rate limit capacity
+	if op != opcode.Shutdown && op != opcode.Close {
+		if err = m.checkPayloadSize(command, op, data); err != nil {
+			return
+		}
+	}
+
 	accepted := m.ratelimit.Request(command)
 	if !accepted {
-		return errors.New("rate limited")
+		m.countCommand(command, CommandRateLimited)
+		return ErrRateLimited
+	}
+	if m.conf.DryRun {
+		return m.record(command, op, data, sent)
 	}
 
-	m.emitChan <- &clientPacket{
-		Op:   op,
-		Data: data,
+	select {
+	case m.emitChan <- &clientPacket{
+		Op:      op,
+		Data:    data,
+		command: command,
+		sent:    sent,
+	}:
+	case <-m.shutdown:
+		m.countCommand(command, CommandDropped)
+		err = errors.New("client has shut down")
 	}
 	return
 }
@@ -248,8 +865,10 @@ func (m *Client) Receive() <-chan *discordPacket {
 	return m.receiveChan
 }
 
-// emitter holds the actually dispatching logic for the Emit method. See DefaultClient#Emit.
-func (m *Client) emitter() {
+// emitter holds the actually dispatching logic for the Emit method. See DefaultClient#Emit. Every
+// connection has its own emitter, which exits once closed is closed.
+func (m *Client) emitter(closed <-chan interface{}, routines *sync.WaitGroup) {
+	defer routines.Done()
 	for {
 		var msg *clientPacket
 		var open bool
@@ -257,42 +876,131 @@ func (m *Client) emitter() {
 		select {
 		case <-m.shutdown:
 			// m.connection got closed
+		case <-closed:
+			// the connection was lost without a close signal, the next connection starts a new emitter
+			return
 		case msg, open = <-m.emitChan:
 		}
-		if !open || (msg.Data == nil && (msg.Op == opcode.Shutdown || msg.Op == opcode.Close)) {
+		var signal *closeSignal
+		if open {
+			signal, _ = msg.Data.(*closeSignal)
+		}
+		if !open || signal != nil {
 			// TODO: what if we get a connection error, how do we restart?
-			m.conn.Close()
+			m.closeConn(signal != nil && signal.resumable)
 			return
 		}
 
-		err := m.conn.WriteJSON(msg)
+		err := m.write(msg)
 		if err != nil {
 			// TODO-logging
 			fmt.Printf("could not send data to discord: %+v\n", msg)
+			m.countCommand(msg.command, CommandDropped)
+		} else {
+			m.countCommand(msg.command, CommandEmitted)
+		}
+		if msg.sent != nil {
+			msg.sent <- err
 		}
 	}
 }
 
-func (m *Client) receiver() {
+// closeConn closes the socket connection. A resumable close keeps the Discord session valid, if the
+// connection supports it.
+func (m *Client) closeConn(resumable bool) {
+	if closer, ok := m.conn.(resumableCloser); ok && resumable {
+		closer.CloseResumable()
+	} else {
+		m.conn.Close()
+	}
+}
+
+// emitClose hands the close signal to the emitter, such that the commands before it are written first. If
+// the emitter does not pick it up in time, eg. because it has exited, the connection is closed directly.
+func (m *Client) emitClose(signal *closeSignal) {
+	select {
+	case m.emitChan <- &clientPacket{Op: opcode.Close, Data: signal}:
+		return
+	case <-m.shutdown:
+	case <-time.After(closeEmitTimeout):
+	}
+
+	logrus.Debug("emitter did not pick up the close signal, closing the connection directly")
+	m.closeConn(signal.resumable)
+}
+
+// closedWith records the close code Discord closed the connection with, and forgets the session when
+// it can not be resumed
+func (m *Client) closedWith(closeErr *ErrorUnexpectedClose) {
+	code := closeErr.code
+	m.stateMutex.Lock()
+	m.closeCode = code
+	m.lastCloseCode = code
+	m.stateMutex.Unlock()
+
+	if code == CloseInvalidAPIVersion {
+		m.deprecationWarning("Discord rejected gateway version " + strconv.Itoa(m.conf.Version) + ": " + closeErr.Error())
+	}
+	if code.IsFatal() {
+		logrus.Error("Discord closed the connection with " + code.String() + ", which will not be reconnected")
+	}
+	if !code.IsResumable() {
+		m.Lock()
+		m.sessionID = ""
+		m.sequenceNumber = 0
+		m.resumeRetries = 0
+		m.Unlock()
+	}
+}
+
+// receiver reads the packets of a connection and hands them to the operation handler. It exits once the
+// connection fails or closed is closed. Packets read after that are dropped, as they are either replayed
+// when resuming, or belong to a session that is gone.
+func (m *Client) receiver(read func() ([]byte, error), closed <-chan interface{}, routines *sync.WaitGroup) {
+	if routines != nil {
+		defer routines.Done()
+	}
 	for {
-		packet, err := m.conn.Read()
+		packet, err := read()
 		if err != nil {
+			if closeErr, ok := err.(*ErrorUnexpectedClose); ok && closeErr.code != 0 {
+				m.closedWith(closeErr)
+			}
 			logrus.Debug("closing readPump")
 			return
 		}
+		select {
+		case <-closed:
+			logrus.Debug("dropping a packet read after the connection was closed")
+			return
+		default:
+		}
 
 		//fmt.Printf("<-: %+v\n", string(packet))
 
 		// parse to gateway payload object
-		evt := &discordPacket{}
-		err = evt.UnmarshalJSON(packet)
+		evt := getPacket()
+		err = m.codec().decode(packet, evt)
 		if err != nil {
 			logrus.Error(err)
+			putPacket(evt)
 			continue
 		}
 
+		// the data is a slice of the read buffer, which the connection might reuse for the next message.
+		// Consumers share the event data, so it must outlive the buffer.
+		evt.Data = append([]byte(nil), evt.Data...)
+
 		// notify listeners
-		m.receiveChan <- evt
+		select {
+		case m.receiveChan <- evt:
+		case <-closed:
+			putPacket(evt)
+			return
+		case <-m.shutdown:
+			putPacket(evt)
+			return
+		}
 
 		// check if application has closed
 		select {
@@ -305,7 +1013,9 @@ func (m *Client) receiver() {
 
 // HeartbeatLatency get the time diff between sending a heartbeat and Discord replying with a heartbeat ack
 func (m *Client) HeartbeatLatency() (duration time.Duration, err error) {
+	m.RLock()
 	duration = m.heartbeatLatency
+	m.RUnlock()
 	if duration == 0 {
 		err = errors.New("latency not determined yet")
 	}
@@ -313,19 +1023,63 @@ func (m *Client) HeartbeatLatency() (duration time.Duration, err error) {
 	return
 }
 
+// Ping sends a heartbeat and returns the time until Discord responds with the next heartbeat ACK. Unlike
+// HeartbeatLatency, which is only updated on every heartbeat interval, this is a fresh measurement.
+// A slow ACK does not force a reconnect; once ctx is done the context error is returned.
+func (m *Client) Ping(ctx context.Context) (latency time.Duration, err error) {
+	ack := make(chan time.Time, 1)
+	m.pingMutex.Lock()
+	m.pingWaiters = append(m.pingWaiters, ack)
+	m.pingMutex.Unlock()
+	defer m.removePingWaiter(ack)
+
+	sent := time.Now()
+	if err = m.sendHeartbeat(); err != nil {
+		return
+	}
+
+	select {
+	case received := <-ack:
+		latency = received.Sub(sent)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	return
+}
+
+func (m *Client) notifyPingWaiters(received time.Time) {
+	m.pingMutex.Lock()
+	defer m.pingMutex.Unlock()
+
+	for _, waiter := range m.pingWaiters {
+		waiter <- received
+	}
+	m.pingWaiters = nil
+}
+
+func (m *Client) removePingWaiter(waiter chan time.Time) {
+	m.pingMutex.Lock()
+	defer m.pingMutex.Unlock()
+
+	for i := range m.pingWaiters {
+		if m.pingWaiters[i] == waiter {
+			m.pingWaiters = append(m.pingWaiters[:i], m.pingWaiters[i+1:]...)
+			break
+		}
+	}
+}
+
 // RegisterEvent tells the socket layer which event types are of interest. Any event that are not registered
-// will be discarded once the socket info is extracted from the event.
+// will be discarded once the socket info is extracted from the event. It is safe to register events while
+// events are being dispatched, and an event registered several times, even concurrently, is only tracked once.
 func (m *Client) RegisterEvent(event string) {
 	m.evtMutex.Lock()
 	defer m.evtMutex.Unlock()
 
-	for i := range m.trackedEvents {
-		if event == m.trackedEvents[i] {
-			return
-		}
+	if m.trackedEvents == nil {
+		m.trackedEvents = map[string]struct{}{}
 	}
-
-	m.trackedEvents = append(m.trackedEvents, event)
+	m.trackedEvents[event] = struct{}{}
 }
 
 // RemoveEvent removes an event type from the registry. This will cause the event type to be discarded
@@ -334,42 +1088,157 @@ func (m *Client) RemoveEvent(event string) {
 	m.evtMutex.Lock()
 	defer m.evtMutex.Unlock()
 
-	for i := range m.trackedEvents {
-		if event == m.trackedEvents[i] {
-			m.trackedEvents[i] = m.trackedEvents[len(m.trackedEvents)-1]
-			m.trackedEvents = m.trackedEvents[:len(m.trackedEvents)-1]
-			break
+	delete(m.trackedEvents, event)
+}
+
+// subscribeEvents keeps the events for a subscription, without registering them
+func (m *Client) subscribeEvents(events []string) {
+	m.evtMutex.Lock()
+	defer m.evtMutex.Unlock()
+
+	if m.subscribed == nil {
+		m.subscribed = map[string]uint{}
+	}
+	for _, event := range events {
+		m.subscribed[event]++
+	}
+}
+
+func (m *Client) unsubscribeEvents(events []string) {
+	m.evtMutex.Lock()
+	defer m.evtMutex.Unlock()
+
+	for _, event := range events {
+		if m.subscribed[event] <= 1 {
+			delete(m.subscribed, event)
+		} else {
+			m.subscribed[event]--
 		}
 	}
-	return
+}
+
+// TrackedEvents returns the event types registered through RegisterEvent, in no particular order
+func (m *Client) TrackedEvents() []string {
+	m.evtMutex.RLock()
+	defer m.evtMutex.RUnlock()
+
+	events := make([]string, 0, len(m.trackedEvents))
+	for event := range m.trackedEvents {
+		events = append(events, event)
+	}
+	return events
 }
 
 func (m *Client) EventChan() <-chan *Event {
 	return m.eventChan
 }
 
+// Trace returns the _trace content of the last READY or RESUMED event, which identifies the Discord servers
+// that handled the session. Discord asks for it when reporting gateway issues.
+func (m *Client) Trace() []string {
+	m.RLock()
+	defer m.RUnlock()
+
+	trace := make([]string, len(m.trace))
+	copy(trace, m.trace)
+	return trace
+}
+
+// ReadyData returns a copy of the last READY payload, or nil if the client has not yet been ready. Unlike the
+// READY event given to subscribers, it is kept between reconnects and can be read at any time.
+func (m *Client) ReadyData() *Ready {
+	m.RLock()
+	defer m.RUnlock()
+
+	if m.readyData == nil {
+		return nil
+	}
+	return m.readyData.copy()
+}
+
+// ReplayRecent returns the most recent events, oldest first, that were dispatched before the caller attached to
+// the event channel. Returns nil unless Config.ReplayBufferSize is set.
+func (m *Client) ReplayRecent() []*Event {
+	if m.replay == nil {
+		return nil
+	}
+	return m.replay.list()
+}
+
 func (m *Client) Start() {
 	go m.operationHandlers()
 }
 
 func (m *Client) Shutdown() (err error) {
-	m.Disconnect()
+	// commands are rejected once shutting down
+	m.goOffline()
+
+	m.stateMutex.Lock()
+	if m.shuttingDown {
+		m.stateMutex.Unlock()
+		return errors.New("already shut down")
+	}
+	m.shuttingDown = true
+	m.stateMutex.Unlock()
+
+	m.disconnect(false)
 	close(m.shutdown)
 	return
 }
 
+// lockRestart makes sure only one reconnect runs at a time. Returns false if a reconnect is already in
+// progress, in which case the caller should leave it to the running one. Call unlockRestart once done.
 func (m *Client) lockRestart() bool {
 	m.restartMutex.Lock()
 	defer m.restartMutex.Unlock()
 
-	now := time.Now().UnixNano()
-	locked := (now - m.lastRestart) > (time.Second.Nanoseconds() / 2)
+	if m.reconnecting {
+		return false
+	}
+	m.reconnecting = true
+	return true
+}
+
+func (m *Client) unlockRestart() {
+	m.restartMutex.Lock()
+	m.reconnecting = false
+	m.restartMutex.Unlock()
+}
+
+func (m *Client) haveSession() bool {
+	m.RLock()
+	defer m.RUnlock()
+	return m.sessionID != ""
+}
+
+// ForceResume closes the connection without invalidating the session, and connects again to resume it.
+// Returns an error if there is no session to resume.
+func (m *Client) ForceResume() (err error) {
+	if !m.haveSession() {
+		return errors.New("there is no session to resume")
+	}
+	if !m.lockRestart() {
+		return errors.New("a reconnect is already in progress")
+	}
+	defer m.unlockRestart()
+
+	if err = m.CloseForResume(); err != nil {
+		return
+	}
+	return m.Connect()
+}
 
-	if locked {
-		m.lastRestart = now
+// CloseForResume closes the connection without invalidating the session, for when a reconnect is expected
+// shortly, such as when switching networks. The heartbeats are stopped, but the session ID and sequence
+// number are kept, so the next call to Client#Connect resumes the session. Returns an error if there is
+// no session to resume.
+func (m *Client) CloseForResume() (err error) {
+	if !m.haveSession() {
+		return errors.New("there is no session to resume")
 	}
 
-	return locked
+	m.stopPulse()
+	return m.disconnect(true)
 }
 
 func (m *Client) reconnect() (err error) {
@@ -378,9 +1247,24 @@ func (m *Client) reconnect() (err error) {
 	if !m.lockRestart() {
 		return
 	}
+	defer m.unlockRestart()
 
-	m.restart <- 1
-	_ = m.Disconnect()
+	m.stopPulse()
+	_ = m.disconnect(false)
+
+	if m.conf.OnDisconnect != nil {
+		go m.conf.OnDisconnect()
+	}
+	if m.conf.DisableAutoReconnect {
+		logrus.Info("automatic reconnect is disabled, staying disconnected")
+		return
+	}
+	m.stateMutex.RLock()
+	code := m.closeCode
+	m.stateMutex.RUnlock()
+	if code.IsFatal() {
+		return errors.New("not reconnecting, Discord closed the connection with " + code.String())
+	}
 
 	for try := 0; try <= maxReconnectTries; try++ {
 		logrus.Debugf("Reconnect attempt #%d\n", try)
@@ -391,6 +1275,7 @@ func (m *Client) reconnect() (err error) {
 		}
 		if try == maxReconnectTries {
 			err = errors.New("Too many reconnect attempts")
+			logrus.WithField("trace", m.Trace()).Error(err)
 			return err
 		}
 
@@ -425,47 +1310,105 @@ func (m *Client) eventHandler(p *discordPacket) {
 	}
 	m.Unlock()
 
+	// the guild count is kept whether the events are registered or not
+	if p.EventName == eventGuildCreate || p.EventName == eventGuildDelete {
+		m.guilds.update(p.EventName, p.Data)
+	}
+
+	var subscribersOnly bool
 	if p.EventName == event.Ready {
 
 		// always store the session id & update the trace content
-		ready := readyPacket{}
-		err := httd.Unmarshal(p.Data, &ready)
+		ready := &Ready{}
+		err := httd.Unmarshal(p.Data, ready)
 		if err != nil {
 			logrus.Error(err)
 		}
 
+		m.guilds.reset(ready.Guilds)
+
 		m.Lock()
+		m.readyData = ready
 		m.sessionID = ready.SessionID
 		m.trace = ready.Trace
+		m.resuming = false
+		m.resumeRetries = 0
 		m.Unlock()
-	} else if p.EventName == event.Resume {
-		// eh? debugging.
-		// TODO
-	} else if p.Op == opcode.DiscordEvent && !m.eventOfInterest(p.EventName) {
+		logrus.WithField("trace", ready.Trace).Debug("websocket session is ready")
+
+		m.stateMutex.Lock()
+		m.setReady(true)
+		m.stateMutex.Unlock()
+	} else if p.EventName == event.Resumed {
+		resumed := resumedPacket{}
+		err := httd.Unmarshal(p.Data, &resumed)
+		if err != nil {
+			logrus.Error(err)
+		}
+
+		m.Lock()
+		m.trace = resumed.Trace
+		m.resuming = false
+		m.resumeRetries = 0
+		m.Unlock()
+		logrus.WithField("trace", resumed.Trace).Debug("websocket session was resumed")
+
+		m.stateMutex.Lock()
+		m.setReady(true)
+		m.stateMutex.Unlock()
+	} else if p.Op == opcode.DiscordEvent && !m.conf.DeliverUnregistered && !m.eventOfInterest(p.EventName) {
+		if !m.eventSubscribed(p.EventName) {
+			return
+		}
+		subscribersOnly = true
+	} else if p.EventName == eventPresenceUpdate && m.conf.DedupePresences && m.presences.duplicate(p.Data) {
 		return
 	}
 
-	// dispatch event
-	m.eventChan <- &Event{
-		Name: p.EventName,
-		Data: p.Data,
+	evt := &Event{
+		Name:            p.EventName,
+		Data:            p.Data,
+		discordName:     p.EventName,
+		subscribersOnly: subscribersOnly,
+	}
+	if m.conf.EventNameMapper != nil {
+		evt.Name = m.conf.EventNameMapper(p.EventName)
+	}
+	if m.replay != nil && !subscribersOnly {
+		m.replay.add(evt)
 	}
+
+	// dispatch event
+	m.deliver(evt)
 } // end eventHandler()
 
 func (m *Client) eventOfInterest(name string) bool {
 	m.evtMutex.RLock()
 	defer m.evtMutex.RUnlock()
 
-	for i := range m.trackedEvents {
-		if name == m.trackedEvents[i] {
-			return true
-		}
-	}
+	_, tracked := m.trackedEvents[name]
+	return tracked
+}
 
-	return false
+func (m *Client) eventSubscribed(name string) bool {
+	m.evtMutex.RLock()
+	defer m.evtMutex.RUnlock()
+
+	return m.subscribed[name] > 0
+}
+
+// handledOpcodes are the operation codes handled by operationHandlers. Keep in sync with the switch.
+var handledOpcodes = []uint{
+	opcode.DiscordEvent,
+	opcode.Heartbeat,
+	opcode.Reconnect,
+	opcode.InvalidSession,
+	opcode.Hello,
+	opcode.HeartbeatAck,
 }
 
-// operation handler demultiplexer
+// operation handler demultiplexer. It runs from Client#Start until shutdown, independent of the
+// connections, so it must not exit on a reconnect.
 func (m *Client) operationHandlers() {
 	logrus.Debug("Ready to receive operation codes...")
 	for {
@@ -474,7 +1417,8 @@ func (m *Client) operationHandlers() {
 		select {
 		case p, open = <-m.Receive():
 			if !open {
-				logrus.Debug("operationChan is dead..")
+				// the receive channel is never closed, see Client#receiveChan
+				logrus.Error("receive channel was closed, operation codes are no longer handled")
 				return
 			}
 		// case <-m.restart:
@@ -491,22 +1435,50 @@ func (m *Client) operationHandlers() {
 			logrus.Info("Discord requested a reconnect")
 			go m.reconnect()
 		case opcode.InvalidSession:
-			// invalid session. Must respond with a identify packet
-			logrus.Info("Discord invalidated session")
+			// invalid session. The data tells whether the session can be resumed, otherwise a new
+			// session must be identified
+			// https://discordapp.com/developers/docs/topics/gateway#invalid-session
+			var resumable bool
+			if err := httd.Unmarshal(p.Data, &resumable); err != nil {
+				logrus.Error(err)
+			}
+			logrus.Info("Discord invalidated session, resumable: " + strconv.FormatBool(resumable))
+
+			// a failed resume can be retried a few times, as a full identify is expensive
+			backoff := 1
+			m.Lock()
+			if !resumable && m.resuming && m.resumeRetries < m.conf.ResumeRetries {
+				m.resumeRetries++
+				backoff = int(m.resumeRetries) + 1
+				resumable = true
+				logrus.Info("retrying resume, attempt " + strconv.Itoa(int(m.resumeRetries)))
+			} else if !resumable {
+				m.sessionID = ""
+				m.sequenceNumber = 0
+				m.resumeRetries = 0
+			}
+			m.resuming = false
+			m.Unlock()
+
 			go func() {
-				rand.Seed(time.Now().UnixNano())
-				delay := rand.Intn(4) + 1
-				delay *= m.timeoutMultiplier
+				delay := m.random.Intn(4) + 1
+				delay *= m.timeoutMultiplier * backoff
 				randomDelay := time.Second * time.Duration(delay)
 				<-time.After(randomDelay)
-				err := sendIdentityPacket(m)
+
+				var err error
+				if resumable {
+					err = m.sendResumePacket()
+				} else {
+					err = sendIdentityPacket(m)
+				}
 				if err != nil {
 					logrus.Error(err)
 				}
 			}()
 		case opcode.Heartbeat:
 			// https://discordapp.com/developers/docs/topics/gateway#heartbeating
-			_ = m.Emit(event.Heartbeat, m.sequenceNumber)
+			_ = m.sendHeartbeat()
 		case opcode.Hello:
 			// hello
 			helloPk := &helloPacket{}
@@ -514,20 +1486,50 @@ func (m *Client) operationHandlers() {
 			if err != nil {
 				logrus.Debug(err)
 			}
+			if m.conf.HeartbeatInterval > 0 {
+				helloPk.HeartbeatInterval = uint(m.conf.HeartbeatInterval / time.Millisecond)
+			}
+			if helloPk.HeartbeatInterval == 0 {
+				// without a heartbeat interval the session can not be kept alive
+				logrus.Error("hello packet is missing the heartbeat interval, forcing reconnect")
+				go m.reconnect()
+				break
+			}
 			m.Lock()
 			m.heartbeatInterval = helloPk.HeartbeatInterval
 			m.Unlock()
 
+			m.stateMutex.Lock()
+			repeated := m.helloReceived
+			m.helloReceived = true
+			if !repeated && !m.connectedSince.IsZero() {
+				m.helloLatency = time.Since(m.connectedSince)
+			}
+			m.stateMutex.Unlock()
+
+			// the connection is already identified or resumed, a repeated hello only updates the interval
+			if repeated {
+				logrus.Debug("ignoring repeated hello on the same connection")
+				break
+			}
 			m.sendHelloPacket()
 		case opcode.HeartbeatAck:
 			// heartbeat received
 			m.Lock()
 			m.lastHeartbeatAck = time.Now()
+			if !m.lastHeartbeatSent.IsZero() {
+				m.heartbeatLatency = m.lastHeartbeatAck.Sub(m.lastHeartbeatSent)
+			}
+			received := m.lastHeartbeatAck
 			m.Unlock()
+			m.notifyPingWaiters(received)
 		default:
 			// unknown
 			logrus.Debugf("Unknown operation: %+v\n", p)
 		}
+
+		// the handlers must not keep the packet, only its data
+		putPacket(p)
 	}
 }
 
@@ -536,25 +1538,36 @@ func (m *Client) sendHelloPacket() {
 	go m.pulsate()
 
 	// if this is a new connection we can drop the resume packet
-	if m.sessionID == "" && m.sequenceNumber == 0 {
-		err := sendIdentityPacket(m)
-		if err != nil {
-			logrus.Error(err)
-		}
+	m.RLock()
+	newSession := m.sessionID == "" && m.sequenceNumber == 0
+	m.RUnlock()
+	if newSession {
+		// identifying might have to wait for other shards, don't block the operation handler
+		go func() {
+			err := sendIdentityPacket(m)
+			if err != nil {
+				logrus.Error(err)
+			}
+		}()
 		return
 	}
 
-	m.RLock()
+	m.sendResumePacket()
+}
+
+func (m *Client) sendResumePacket() error {
+	m.Lock()
 	token := m.conf.Token
 	session := m.sessionID
 	sequence := m.sequenceNumber
-	m.RUnlock()
+	m.resuming = true
+	m.Unlock()
 
-	m.Emit(event.Resume, struct {
-		Token      string `json:"token"`
-		SessionID  string `json:"session_id"`
-		SequenceNr *uint  `json:"seq"`
-	}{token, session, &sequence})
+	return m.Emit(event.Resume, &resumePacket{
+		Token:          token,
+		SessionID:      session,
+		SequenceNumber: lastSequenceNumber(sequence),
+	})
 }
 
 // AllowedToStartPulsating you must notify when you are done pulsating!
@@ -579,32 +1592,67 @@ func (m *Client) StopPulsating(serviceID uint8) {
 	}
 }
 
+// stopPulse stops the heartbeats of the current connection, if they are running. The pulse also stops by
+// itself once its connection is closed, so this checks again until the pulse is gone or picks up the signal.
+func (m *Client) stopPulse() {
+	for {
+		m.pulseMutex.Lock()
+		pulsating := m.pulsating != 0
+		m.pulseMutex.Unlock()
+		if !pulsating {
+			return
+		}
+
+		select {
+		case m.restart <- 1:
+			return
+		case <-m.shutdown:
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
 func (m *Client) pulsate() {
-	serviceID := uint8(rand.Intn(254) + 1) // uint8 cap
+	serviceID := uint8(m.random.Intn(254) + 1) // uint8 cap
 	if !m.AllowedToStartPulsating(serviceID) {
 		return
 	}
 	defer m.StopPulsating(serviceID)
 
+	// the heartbeats belong to the connection that received hello
+	m.stateMutex.RLock()
+	closed := m.connectionClosed
+	m.stateMutex.RUnlock()
+
 	m.RLock()
 	ticker := time.NewTicker(time.Millisecond * time.Duration(m.heartbeatInterval))
 	m.RUnlock()
 	defer ticker.Stop()
 
+	var probe <-chan time.Time
+	if m.conf.LatencyProbeInterval > 0 {
+		interval := m.conf.LatencyProbeInterval
+		if interval < minLatencyProbeInterval {
+			interval = minLatencyProbeInterval
+		}
+		probeTicker := time.NewTicker(interval)
+		defer probeTicker.Stop()
+		probe = probeTicker.C
+	}
+
 	var last time.Time
-	var snr uint
 	for {
 		m.RLock()
 		last = m.lastHeartbeatAck
-		snr = m.sequenceNumber
 		m.RUnlock()
 
-		m.Emit(event.Heartbeat, snr)
+		m.sendHeartbeat()
 
 		stopChan := make(chan interface{})
 
 		// verify the heartbeat ACK
-		go func(m *Client, last time.Time, sent time.Time, cancel chan interface{}) {
+		go func(m *Client, last time.Time, cancel chan interface{}) {
 			select {
 			case <-cancel:
 				return
@@ -618,17 +1666,11 @@ func (m *Client) pulsate() {
 			if !receivedHeartbeatAck {
 				logrus.Info("heartbeat ACK was not received, forcing reconnect")
 				m.reconnect()
-			} else {
-				// update "latency"
-				m.heartbeatLatency = m.lastHeartbeatAck.Sub(sent)
 			}
-		}(m, last, time.Now(), stopChan)
+		}(m, last, stopChan)
 
-		select {
-		case <-ticker.C:
+		if m.waitForNextHeartbeat(ticker.C, probe, closed) {
 			continue
-		case <-m.shutdown:
-		case <-m.restart:
 		}
 
 		logrus.Debug("Stopping pulse")
@@ -637,22 +1679,48 @@ func (m *Client) pulsate() {
 	}
 }
 
+// waitForNextHeartbeat sends latency probes, if any, until it is time for the next heartbeat.
+// Returns false when pulsating should stop, which is also once the connection is closed.
+func (m *Client) waitForNextHeartbeat(heartbeat, probe <-chan time.Time, closed <-chan interface{}) bool {
+	for {
+		select {
+		case <-heartbeat:
+			return true
+		case <-probe:
+			// a rate limited probe is simply skipped
+			_ = m.sendHeartbeat()
+		case <-m.shutdown:
+			return false
+		case <-m.restart:
+			return false
+		case <-closed:
+			return false
+		}
+	}
+}
+
+// sendHeartbeat emits a heartbeat and remembers when it was sent, such that the latency can be
+// calculated once Discord responds with a heartbeat ACK.
+func (m *Client) sendHeartbeat() error {
+	m.Lock()
+	snr := m.sequenceNumber
+	m.lastHeartbeatSent = time.Now()
+	m.Unlock()
+
+	return m.Emit(event.Heartbeat, lastSequenceNumber(snr))
+}
+
 func sendIdentityPacket(m *Client) (err error) {
 	// https://discordapp.com/developers/docs/topics/gateway#identify
-	identityPayload := struct {
-		Token          string      `json:"token"`
-		Properties     interface{} `json:"properties"`
-		Compress       bool        `json:"compress"`
-		LargeThreshold uint        `json:"large_threshold"`
-		Shard          *[2]uint    `json:"shard,omitempty"`
-		Presence       interface{} `json:"presence,omitempty"`
-	}{
-		Token: m.conf.Token,
-		Properties: struct {
-			OS      string `json:"$os"`
-			Browser string `json:"$browser"`
-			Device  string `json:"$device"`
-		}{runtime.GOOS, m.conf.Browser, m.conf.Device},
+	os := m.conf.OS
+	if os == "" {
+		os = runtime.GOOS
+	}
+	identityPayload := IdentifyPayload{
+		Token:          m.conf.Token,
+		Properties:     IdentifyPayloadProperties{os, m.conf.Browser, m.conf.Device},
+		Compress:       m.conf.Compress,
+		Intents:        m.conf.Intents,
 		LargeThreshold: m.conf.GuildLargeThreshold,
 		// Presence: struct {
 		// 	Since  *uint       `json:"since"`
@@ -663,9 +1731,76 @@ func sendIdentityPacket(m *Client) (err error) {
 	}
 
 	if m.conf.ShardCount > 1 {
-		identityPayload.Shard = &[2]uint{m.conf.ShardID, m.conf.ShardCount}
+		identityPayload.Shard = m.identifyShard()
+	}
+
+	// a rejected payload must not use up an identify slot, so the hook runs before the gate
+	if m.conf.BeforeIdentify != nil {
+		m.conf.BeforeIdentify(&identityPayload)
+		if identityPayload.Token != m.conf.Token {
+			return errors.New("BeforeIdentify must not change the token")
+		}
+		var shard *[2]uint
+		if m.conf.ShardCount > 1 {
+			shard = m.identifyShard()
+		}
+		if !sameShard(shard, identityPayload.Shard) {
+			return errors.New("BeforeIdentify must not change the shard")
+		}
+	}
+
+	if err = m.waitForIdentifyGate(); err != nil {
+		return err
 	}
 
 	err = m.Emit(event.Identify, &identityPayload)
 	return
 }
+
+// identifyShard returns the shard array of the identify command
+func (m *Client) identifyShard() *[2]uint {
+	return &[2]uint{m.conf.ShardID, m.conf.ShardCount}
+}
+
+func sameShard(a, b *[2]uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// waitForIdentifyGate blocks until the session start limit and the identify gate allow the client to
+// identify. Shutting down aborts the wait.
+func (m *Client) waitForIdentifyGate() error {
+	var gates []IdentifyGate
+	m.RLock()
+	if m.sessionStarts != nil {
+		gates = append(gates, m.sessionStarts)
+	}
+	m.RUnlock()
+	if m.conf.IdentifyGate != nil {
+		gates = append(gates, m.conf.IdentifyGate)
+	} else if m.identifyLimit != nil {
+		gates = append(gates, m.identifyLimit)
+	}
+	if len(gates) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-m.shutdown:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for _, gate := range gates {
+		if err := gate.Wait(ctx, m.conf.ShardID); err != nil {
+			return errors.New("not allowed to identify: " + err.Error())
+		}
+	}
+	return nil
+}
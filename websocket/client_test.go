@@ -1,15 +1,22 @@
 package websocket
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"runtime"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/andersfylling/disgord/constant"
+	"github.com/andersfylling/disgord/httd"
+	"github.com/andersfylling/disgord/websocket/cmd"
+	"github.com/andersfylling/disgord/websocket/event"
 	"github.com/andersfylling/disgord/websocket/opcode"
 )
 
@@ -19,13 +26,50 @@ type testWS struct {
 	writing      chan interface{}
 	reading      chan []byte
 	disconnected bool
+	header       http.Header
+	closed       chan interface{} // unblocks the readers of the current connection once closed
 	sync.Mutex
 }
 
+// newTestWS gives a disconnected connection, where every call blocks until the test handles it. See
+// acceptAll and newMockGateway for gateways that handle the calls.
+func newTestWS() *testWS {
+	return &testWS{
+		closing:      make(chan interface{}),
+		opening:      make(chan interface{}),
+		writing:      make(chan interface{}),
+		reading:      make(chan []byte),
+		disconnected: true,
+	}
+}
+
+// acceptAll mocks a gateway that accepts every write, open and close without answering, until the returned
+// channel is closed. Every open is reported to opened, unless it is nil.
+func (g *testWS) acceptAll(opened chan interface{}) chan interface{} {
+	done := make(chan interface{})
+	go func() {
+		for {
+			select {
+			case <-g.writing:
+			case <-g.opening:
+				if opened != nil {
+					opened <- true
+				}
+			case <-g.closing:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return done
+}
+
 func (g *testWS) Open(endpoint string, requestHeader http.Header) (err error) {
 	g.opening <- 1
 	g.Lock()
+	g.header = requestHeader
 	g.disconnected = false
+	g.closed = make(chan interface{})
 	g.Unlock()
 	return
 }
@@ -39,12 +83,33 @@ func (g *testWS) Close() (err error) {
 	g.closing <- 1
 	g.Lock()
 	g.disconnected = true
+	g.closeReaders()
 	g.Unlock()
 	return
 }
 
+// closeReaders unblocks the readers of the current connection. The caller must hold the lock.
+func (g *testWS) closeReaders() {
+	if g.closed == nil {
+		return
+	}
+	select {
+	case <-g.closed:
+	default:
+		close(g.closed)
+	}
+}
+
 func (g *testWS) Read() (packet []byte, err error) {
-	packet = <-g.reading
+	g.Lock()
+	closed := g.closed
+	g.Unlock()
+
+	select {
+	case packet = <-g.reading:
+	case <-closed:
+		return nil, errors.New("closed")
+	}
 	if packet == nil {
 		err = errors.New("empty")
 	}
@@ -52,6 +117,8 @@ func (g *testWS) Read() (packet []byte, err error) {
 }
 
 func (g *testWS) Disconnected() bool {
+	g.Lock()
+	defer g.Unlock()
 	return g.disconnected
 }
 
@@ -92,14 +159,92 @@ func TestManager_RemoveEvent(t *testing.T) {
 	}
 }
 
-func TestManager_reconnect(t *testing.T) {
-	conn := &testWS{
-		closing:      make(chan interface{}),
-		opening:      make(chan interface{}),
-		writing:      make(chan interface{}),
-		reading:      make(chan []byte),
-		disconnected: true,
+func TestManager_RegisterEventConcurrently(t *testing.T) {
+	m := Client{}
+	events := []string{"a", "b", "c", "d"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for _, evt := range events {
+				m.RegisterEvent(evt)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for _, evt := range events {
+				m.eventOfInterest(evt)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(m.trackedEvents) != len(events) {
+		t.Errorf("expected %d tracked events, got %d", len(events), len(m.trackedEvents))
+	}
+}
+
+func TestClient_DeliverUnregistered(t *testing.T) {
+	for _, deliver := range []bool{false, true} {
+		m, _ := NewTestClient(&Config{
+			HTTPClient:          &http.Client{},
+			DeliverUnregistered: deliver,
+		}, &testWS{})
+		m.RegisterEvent("KNOWN")
+
+		go func() {
+			m.receiveChan <- &discordPacket{EventName: "UNKNOWN", SequenceNumber: 1}
+			m.receiveChan <- &discordPacket{EventName: "KNOWN", SequenceNumber: 2}
+		}()
+
+		evt := <-m.EventChan()
+		if deliver && evt.Name != "UNKNOWN" {
+			t.Errorf("expected the unregistered event to be delivered, got %s", evt.Name)
+		}
+		if deliver {
+			evt = <-m.EventChan()
+		}
+		if evt.Name != "KNOWN" {
+			t.Errorf("expected the registered event to be delivered, got %s", evt.Name)
+		}
+	}
+}
+
+func TestClient_EventNameMapper(t *testing.T) {
+	m, _ := NewTestClient(&Config{
+		HTTPClient:      &http.Client{},
+		EventNameMapper: strings.ToLower,
+	}, &testWS{})
+	m.RegisterEvent("MESSAGE_CREATE")
+	typed := m.TypedChan("GUILD_CREATE")
+
+	go func() {
+		m.receiveChan <- &discordPacket{EventName: "MESSAGE_CREATE", SequenceNumber: 1}
+		m.receiveChan <- &discordPacket{EventName: "GUILD_CREATE", SequenceNumber: 2}
+	}()
+
+	select {
+	case evt := <-m.EventChan():
+		if evt.Name != "message_create" {
+			t.Errorf("expected the event name to be mapped, got %s", evt.Name)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the registered event to be delivered")
 	}
+	select {
+	case evt := <-typed:
+		if evt.Name != "guild_create" {
+			t.Errorf("expected the event name to be mapped, got %s", evt.Name)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the event to be routed to its typed channel by the Discord name")
+	}
+}
+
+func TestManager_reconnect(t *testing.T) {
+	conn := newTestWS()
 
 	m := &Client{
 		conf: &Config{
@@ -130,6 +275,7 @@ func TestManager_reconnect(t *testing.T) {
 		conn:         conn,
 		disconnected: true,
 		ratelimit:    newRatelimiter(),
+		random:       newRandom(nil),
 	}
 	seq := uint(1)
 
@@ -240,12 +386,1758 @@ func TestManager_reconnect(t *testing.T) {
 		t.Errorf("incorrect sequence number. Got %d, wants %d\n", sequence, seq)
 		return
 	}
-	seq++
 
-	// what if there is a session invalidate event
+	// what if there is a session invalidate event. The new session starts over at sequence number 1
+	seq = 1
 	wg[identify].Add(1)
 	conn.reading <- []byte(`{"t":null,"s":null,"op":9,"d":false}`)
 
 	// wait for identify
 	wg[identify].Wait()
 }
+
+// mockGateway answers the heartbeats, identifies and resumes of a client, and keeps track of the
+// sequence number of the session
+type mockGateway struct {
+	conn   *testWS
+	opened chan interface{}
+	done   chan interface{}
+
+	sync.Mutex
+	seq uint
+}
+
+func newMockGateway() *mockGateway {
+	g := &mockGateway{
+		conn:   newTestWS(),
+		opened: make(chan interface{}, 10),
+		done:   make(chan interface{}),
+	}
+	go g.serve()
+	return g
+}
+
+func (g *mockGateway) serve() {
+	for {
+		select {
+		case v := <-g.conn.writing:
+			switch v.(*clientPacket).Op {
+			case opcode.Heartbeat:
+				g.reply(`{"t":null,"s":null,"op":11,"d":null}`)
+			case opcode.Identify:
+				g.Lock()
+				g.seq = 0
+				g.Unlock()
+				g.dispatch(event.Ready, `{"session_id":"abc"}`)
+			case opcode.Resume:
+				g.dispatch(event.Resumed, `{}`)
+			}
+		case <-g.conn.opening:
+			g.opened <- true
+		case <-g.conn.closing:
+		case <-g.done:
+			return
+		}
+	}
+}
+
+// reply sends the packets on their own goroutine, as the receiver might be busy delivering an event while
+// the client writes
+func (g *mockGateway) reply(packets ...string) {
+	go func() {
+		for _, packet := range packets {
+			select {
+			case g.conn.reading <- []byte(packet):
+			case <-g.done:
+				return
+			}
+		}
+	}()
+}
+
+func (g *mockGateway) dispatch(name, data string) {
+	g.Lock()
+	g.seq++
+	seq := g.seq
+	g.Unlock()
+	g.reply(`{"t":"` + name + `","s":` + strconv.Itoa(int(seq)) + `,"op":0,"d":` + data + `}`)
+}
+
+func (g *mockGateway) hello() {
+	g.reply(`{"t":null,"s":null,"op":10,"d":{"heartbeat_interval":45000}}`)
+}
+
+func (g *mockGateway) client() *Client {
+	return &Client{
+		conf: &Config{
+			Endpoint:   "sfkjsdlfsf",
+			Token:      "sifhsdoifhsdifhsdf",
+			HTTPClient: &http.Client{},
+		},
+		shutdown:     make(chan interface{}),
+		restart:      make(chan interface{}),
+		eventChan:    make(chan *Event),
+		receiveChan:  make(chan *discordPacket),
+		emitChan:     make(chan *clientPacket),
+		conn:         g.conn,
+		disconnected: true,
+		ratelimit:    newRatelimiter(),
+		random:       newRandom(nil),
+	}
+}
+
+func waitForEvent(t *testing.T, m *Client, name string) {
+	for {
+		select {
+		case evt := <-m.eventChan:
+			if evt.Name == name {
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected a %s event", name)
+		}
+	}
+}
+
+// reconnectTestClient connects the client and waits for READY
+func reconnectTestClient(t *testing.T, gateway *mockGateway) *Client {
+	m := gateway.client()
+	m.RegisterEvent("MESSAGE_CREATE")
+	m.Start()
+	if err := m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	<-gateway.opened
+	gateway.hello()
+	waitForEvent(t, m, event.Ready)
+	return m
+}
+
+// requestReconnect asks the client to reconnect, like Discord does with op 7, and waits until the session
+// is resumed on the new connection
+func requestReconnect(t *testing.T, m *Client, gateway *mockGateway) {
+	gateway.reply(`{"t":null,"s":null,"op":7,"d":null}`)
+	select {
+	case <-gateway.opened:
+	case <-time.After(time.Second):
+		t.Fatal("expected the client to reconnect")
+	}
+	gateway.hello()
+	waitForEvent(t, m, event.Resumed)
+}
+
+func TestClient_EventsAfterReconnect(t *testing.T) {
+	gateway := newMockGateway()
+	defer close(gateway.done)
+	m := reconnectTestClient(t, gateway)
+	defer m.Shutdown()
+
+	// Discord asks for a reconnect. The new connection must still have its packets handled.
+	requestReconnect(t, m, gateway)
+	gateway.dispatch("MESSAGE_CREATE", `{"content":"after reconnect"}`)
+	waitForEvent(t, m, "MESSAGE_CREATE")
+}
+
+func TestClient_ReconnectGoroutines(t *testing.T) {
+	gateway := newMockGateway()
+	defer close(gateway.done)
+	m := reconnectTestClient(t, gateway)
+	defer m.Shutdown()
+
+	// let the first connection settle before counting
+	requestReconnect(t, m, gateway)
+	time.Sleep(20 * time.Millisecond)
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 10; i++ {
+		requestReconnect(t, m, gateway)
+		gateway.dispatch("MESSAGE_CREATE", `{}`)
+		waitForEvent(t, m, "MESSAGE_CREATE")
+	}
+
+	// the goroutines of a closed connection exit on their own, give them a moment
+	var after int
+	for i := 0; i < 50; i++ {
+		if after = runtime.NumGoroutine(); after <= before+2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after > before+2 {
+		t.Errorf("expected the goroutines of closed connections to exit, went from %d to %d goroutines", before, after)
+	}
+
+	m.RLock()
+	routines := m.routines
+	m.RUnlock()
+	if routines == nil {
+		t.Fatal("expected the goroutines of the current connection to be tracked")
+	}
+}
+
+func TestClient_EmitConcurrently(t *testing.T) {
+	conn := newTestWS()
+	defer close(conn.acceptAll(nil))
+
+	m, _ := NewTestClient(&Config{
+		Endpoint:   "sfkjsdlfsf",
+		HTTPClient: &http.Client{},
+	}, conn)
+	m.timeoutMultiplier = 0
+	defer close(conn.reading)
+
+	if err := m.Emit(cmd.RequestGuildMembers, nil); err == nil {
+		t.Error("expected emit to fail before connecting")
+	}
+	if err := m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	m.stateMutex.Lock()
+	m.setReady(true)
+	m.stateMutex.Unlock()
+
+	const workers = 20
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				// rate limited commands are fine, we only care about races and deadlocks
+				_ = m.Emit(cmd.RequestGuildMembers, struct{}{})
+			}
+		}()
+	}
+
+	// simulate reconnects while emitting
+	for i := 0; i < 3; i++ {
+		_ = m.Disconnect()
+		if err := m.Connect(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	finished := make(chan interface{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(2 * time.Second):
+		t.Fatal("emitting go routines are stuck")
+	}
+
+	m.Shutdown()
+	if err := m.Emit(cmd.RequestGuildMembers, struct{}{}); err == nil {
+		t.Error("expected emit to fail after shutdown")
+	}
+}
+
+func TestClient_ConnectAndEmitConcurrently(t *testing.T) {
+	conn := newTestWS()
+	defer close(conn.acceptAll(nil))
+
+	m, _ := NewTestClient(&Config{
+		Endpoint:   "sfkjsdlfsf",
+		HTTPClient: &http.Client{},
+	}, conn)
+	m.timeoutMultiplier = 0
+	defer close(conn.reading)
+
+	var wg sync.WaitGroup
+	wg.Add(10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			defer wg.Done()
+			// depending on the scheduling this is either before or after the connection is established
+			_ = m.Emit(cmd.RequestGuildMembers, struct{}{})
+			_ = m.isDisconnected()
+		}()
+	}
+	if err := m.Connect(); err != nil {
+		t.Error(err)
+	}
+	wg.Wait()
+
+	if m.isDisconnected() || !m.haveConnected() {
+		t.Error("expected client to be connected")
+	}
+	m.Shutdown()
+	if !m.isDisconnected() {
+		t.Error("expected client to be disconnected")
+	}
+}
+
+func TestClient_LatencyProbe(t *testing.T) {
+	m := &Client{
+		conf:         &Config{},
+		shutdown:     make(chan interface{}),
+		restart:      make(chan interface{}),
+		receiveChan:  make(chan *discordPacket),
+		emitChan:     make(chan *clientPacket),
+		ratelimit:    newRatelimiter(),
+		random:       newRandom(nil),
+		disconnected: true,
+	}
+	m.setDisconnected(false)
+	m.helloReceived = true
+	m.Start()
+	defer close(m.shutdown)
+
+	heartbeat := make(chan time.Time)
+	probe := make(chan time.Time)
+	stopped := make(chan bool)
+	go func() {
+		stopped <- m.waitForNextHeartbeat(heartbeat, probe, nil)
+	}()
+
+	probe <- time.Now()
+	if packet := <-m.emitChan; packet.Op != opcode.Heartbeat {
+		t.Errorf("expected probe to emit a heartbeat, got op %d", packet.Op)
+	}
+
+	<-time.After(time.Millisecond)
+	m.receiveChan <- &discordPacket{Op: opcode.HeartbeatAck}
+
+	heartbeat <- time.Now()
+	if !<-stopped {
+		t.Error("expected to continue pulsating")
+	}
+
+	// the ACK is handled asynchronously
+	deadline := time.Now().Add(time.Second)
+	for {
+		latency, err := m.HeartbeatLatency()
+		if err == nil && latency > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected latency to be measured by the probe. Got %s, %v", latency, err)
+		}
+		<-time.After(time.Millisecond)
+	}
+}
+
+func TestClient_Ping(t *testing.T) {
+	m := &Client{
+		conf:         &Config{},
+		shutdown:     make(chan interface{}),
+		restart:      make(chan interface{}),
+		receiveChan:  make(chan *discordPacket),
+		emitChan:     make(chan *clientPacket),
+		ratelimit:    newRatelimiter(),
+		random:       newRandom(nil),
+		disconnected: true,
+	}
+	m.setDisconnected(false)
+	m.helloReceived = true
+	m.Start()
+	defer close(m.shutdown)
+
+	// mocked Discord that replies to every heartbeat once told to
+	reply := make(chan bool)
+	go func() {
+		for {
+			select {
+			case packet := <-m.emitChan:
+				if packet.Op == opcode.Heartbeat && <-reply {
+					m.receiveChan <- &discordPacket{Op: opcode.HeartbeatAck}
+				}
+			case <-m.shutdown:
+				return
+			}
+		}
+	}()
+
+	t.Run("timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		go func() {
+			reply <- false
+		}()
+		if _, err := m.Ping(ctx); err != context.DeadlineExceeded {
+			t.Errorf("expected deadline exceeded, got %v", err)
+		}
+
+		m.pingMutex.Lock()
+		waiters := len(m.pingWaiters)
+		m.pingMutex.Unlock()
+		if waiters != 0 {
+			t.Errorf("expected ping waiter to be removed, got %d waiters", waiters)
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		wg.Add(5)
+		for i := 0; i < 5; i++ {
+			go func() {
+				defer wg.Done()
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				defer cancel()
+				if latency, err := m.Ping(ctx); err != nil || latency <= 0 {
+					t.Errorf("expected a latency. Got %s, %v", latency, err)
+				}
+			}()
+		}
+		for i := 0; i < 5; i++ {
+			reply <- true
+		}
+		wg.Wait()
+	})
+}
+
+func TestClient_EmitDuringShutdown(t *testing.T) {
+	conn := newTestWS()
+	defer close(conn.acceptAll(nil))
+
+	m, _ := NewTestClient(&Config{
+		Endpoint:   "sfkjsdlfsf",
+		HTTPClient: &http.Client{},
+	}, conn)
+	defer close(conn.reading)
+	if err := m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the disconnect during shutdown waits a second
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- m.Shutdown()
+	}()
+	for !m.isDisconnected() {
+		<-time.After(time.Millisecond)
+	}
+
+	start := time.Now()
+	if err := m.Emit(cmd.RequestGuildMembers, struct{}{}); err == nil {
+		t.Error("expected emit to fail during shutdown")
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Error("expected emit to return immediately during shutdown")
+	}
+	if dropped := m.Status().DroppedCommands; dropped != 1 {
+		t.Errorf("expected 1 dropped command, got %d", dropped)
+	}
+
+	if err := <-shutdown; err != nil {
+		t.Error(err)
+	}
+	if err := m.Shutdown(); err == nil {
+		t.Error("expected error on second shutdown")
+	}
+}
+
+func TestClient_CommandCounters(t *testing.T) {
+	gateway := newMockGateway()
+	defer close(gateway.done)
+	m := gateway.client()
+
+	var mu sync.Mutex
+	var emitted uint64
+	reported := map[CommandOutcome]uint64{}
+	m.conf.OnCommand = func(command string, outcome CommandOutcome) {
+		mu.Lock()
+		defer mu.Unlock()
+		if outcome == CommandEmitted {
+			emitted++
+		}
+		if command == cmd.UpdateStatus {
+			reported[outcome]++
+		}
+	}
+	m.RegisterEvent("MESSAGE_CREATE")
+	m.Start()
+	defer m.Shutdown()
+	if err := m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	<-gateway.opened
+	gateway.hello()
+	waitForEvent(t, m, event.Ready)
+
+	// the presence bucket allows 5 updates a minute
+	for i := 0; i < 5; i++ {
+		if err := m.EmitSync(cmd.UpdateStatus, struct{}{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Emit(cmd.UpdateStatus, struct{}{}); err != ErrRateLimited {
+		t.Fatalf("expected the presence update to be rate limited, got %v", err)
+	}
+
+	status := m.Status()
+	mu.Lock()
+	defer mu.Unlock()
+	if status.EmittedCommands != emitted || emitted < 6 {
+		t.Errorf("expected the identify and 5 presence updates to be emitted, got %d and %d reported", status.EmittedCommands, emitted)
+	}
+	if status.RateLimitedCommands != 1 {
+		t.Errorf("expected 1 rate limited command, got %d", status.RateLimitedCommands)
+	}
+	if reported[CommandEmitted] != 5 || reported[CommandRateLimited] != 1 || reported[CommandDropped] != 0 {
+		t.Errorf("incorrect outcomes given to OnCommand: %v", reported)
+	}
+}
+
+func TestClient_Trace(t *testing.T) {
+	m, _ := NewTestClient(&Config{
+		HTTPClient: &http.Client{},
+	}, &testWS{})
+
+	packets := []*discordPacket{
+		{EventName: event.Ready, SequenceNumber: 1, Data: []byte(`{"session_id":"a","_trace":["gateway-prd-1"]}`)},
+		{EventName: event.Resumed, SequenceNumber: 2, Data: []byte(`{"_trace":["gateway-prd-2","discord-sessions-1"]}`)},
+	}
+	wants := [][]string{{"gateway-prd-1"}, {"gateway-prd-2", "discord-sessions-1"}}
+	for i := range packets {
+		m.receiveChan <- packets[i]
+		<-m.EventChan()
+
+		trace := m.Trace()
+		if len(trace) != len(wants[i]) {
+			t.Fatalf("incorrect trace. Got %v, wants %v", trace, wants[i])
+		}
+		for j := range trace {
+			if trace[j] != wants[i][j] {
+				t.Errorf("incorrect trace. Got %v, wants %v", trace, wants[i])
+			}
+		}
+	}
+}
+
+func TestClient_ReadyData(t *testing.T) {
+	m, _ := NewTestClient(&Config{
+		HTTPClient: &http.Client{},
+	}, &testWS{})
+
+	if m.ReadyData() != nil {
+		t.Error("expected no ready data before the first READY")
+	}
+
+	data := `{"v":6,"session_id":"a","resume_gateway_url":"wss://gateway-us-east1-b.discord.gg",` +
+		`"user":{"id":"486832262592069632","username":"test","discriminator":"0001","bot":true},` +
+		`"guilds":[{"id":"486833041486299148","unavailable":true}],"application":{"id":"486832262592069632","flags":0},` +
+		`"shard":[1,2],"private_channels":[],"unknown_field":{"x":1},"_trace":["gateway-prd-1"]}`
+	m.receiveChan <- &discordPacket{EventName: event.Ready, SequenceNumber: 1, Data: []byte(data)}
+	<-m.EventChan()
+
+	ready := m.ReadyData()
+	if ready == nil {
+		t.Fatal("expected ready data")
+	}
+	if ready.Version != 6 || ready.SessionID != "a" || ready.ResumeGatewayURL != "wss://gateway-us-east1-b.discord.gg" {
+		t.Errorf("incorrect ready data. Got %+v", ready)
+	}
+	if ready.User == nil || ready.User.ID != "486832262592069632" || !ready.User.Bot {
+		t.Errorf("incorrect user. Got %+v", ready.User)
+	}
+	if len(ready.Guilds) != 1 || ready.Guilds[0].ID != "486833041486299148" || !ready.Guilds[0].Unavailable {
+		t.Errorf("incorrect guilds. Got %+v", ready.Guilds)
+	}
+	if ready.Application == nil || ready.Application.ID != "486832262592069632" {
+		t.Errorf("incorrect application. Got %+v", ready.Application)
+	}
+	if ready.Shard == nil || *ready.Shard != [2]uint{1, 2} {
+		t.Errorf("incorrect shard. Got %v", ready.Shard)
+	}
+
+	// the returned data is a copy
+	ready.User.Username = "changed"
+	ready.Guilds[0].Unavailable = false
+	if again := m.ReadyData(); again.User.Username != "test" || !again.Guilds[0].Unavailable {
+		t.Error("expected the ready data to be unaffected by changes to a copy")
+	}
+}
+
+func TestClient_DialHeaders(t *testing.T) {
+	conn := newTestWS()
+	go func() {
+		<-conn.opening
+	}()
+
+	header := http.Header{}
+	header.Set("Sec-WebSocket-Protocol", "disgord")
+	m, _ := NewTestClient(&Config{
+		Endpoint:    "sfkjsdlfsf",
+		HTTPClient:  &http.Client{},
+		DialHeaders: header,
+	}, conn)
+	defer close(conn.reading)
+	if err := m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.Lock()
+	defer conn.Unlock()
+	if conn.header.Get("Sec-WebSocket-Protocol") != "disgord" {
+		t.Error("expected the dial headers to be used for the upgrade request")
+	}
+}
+
+func TestClient_InvalidSession(t *testing.T) {
+	conn := newTestWS()
+	go func() {
+		<-conn.opening
+	}()
+
+	m, _ := NewTestClient(&Config{
+		Endpoint:   "sfkjsdlfsf",
+		HTTPClient: &http.Client{},
+	}, conn)
+	m.timeoutMultiplier = 0
+	defer close(conn.reading)
+	if err := m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	m.stateMutex.Lock()
+	m.helloReceived = true
+	m.stateMutex.Unlock()
+
+	tests := []struct {
+		data    string
+		op      uint
+		session string
+	}{
+		{"true", opcode.Resume, "abc"},
+		{"false", opcode.Identify, ""},
+	}
+	for _, test := range tests {
+		m.Lock()
+		m.sessionID = "abc"
+		m.sequenceNumber = 3
+		m.Unlock()
+
+		conn.reading <- []byte(`{"t":null,"s":null,"op":9,"d":` + test.data + `}`)
+		select {
+		case v := <-conn.writing:
+			if pk := v.(*clientPacket); pk.Op != test.op {
+				t.Errorf("resumable %s: expected op %d, got %d", test.data, test.op, pk.Op)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("resumable %s: nothing was sent", test.data)
+		}
+
+		m.RLock()
+		session := m.sessionID
+		m.RUnlock()
+		if session != test.session {
+			t.Errorf("resumable %s: expected session id %q, got %q", test.data, test.session, session)
+		}
+	}
+}
+
+func BenchmarkClient_eventOfInterest(b *testing.B) {
+	events := []string{
+		event.Ready, event.Resumed, "CHANNEL_CREATE", "CHANNEL_UPDATE", "CHANNEL_DELETE", "GUILD_CREATE",
+		"GUILD_UPDATE", "GUILD_DELETE", "GUILD_MEMBER_ADD", "GUILD_MEMBER_UPDATE", "GUILD_MEMBER_REMOVE",
+		"MESSAGE_CREATE", "MESSAGE_UPDATE", "MESSAGE_DELETE", "PRESENCE_UPDATE", "TYPING_START",
+	}
+	name := "TYPING_START"
+
+	b.Run("scan", func(b *testing.B) {
+		var mu sync.RWMutex
+		for i := 0; i < b.N; i++ {
+			mu.RLock()
+			for j := range events {
+				if events[j] == name {
+					break
+				}
+			}
+			mu.RUnlock()
+		}
+	})
+	b.Run("map", func(b *testing.B) {
+		m := Client{}
+		for _, evt := range events {
+			m.RegisterEvent(evt)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			m.eventOfInterest(name)
+		}
+	})
+}
+
+func TestClient_EmitBeforeReady(t *testing.T) {
+	conn := newTestWS()
+	go func() {
+		<-conn.opening
+	}()
+
+	m, _ := NewTestClient(&Config{
+		Endpoint:   "sfkjsdlfsf",
+		HTTPClient: &http.Client{},
+	}, conn)
+	defer close(conn.reading)
+
+	err := m.Emit(cmd.UpdateStatus, struct{}{})
+	if e, ok := err.(*ErrorNotReady); !ok || e.Status != StatusDisconnected {
+		t.Errorf("expected a not connected error, got %v", err)
+	}
+
+	if err = m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	for _, command := range []string{cmd.UpdateStatus, event.Heartbeat} {
+		err = m.Emit(command, struct{}{})
+		if e, ok := err.(*ErrorNotReady); !ok || e.Status != StatusConnected || e.Command != command {
+			t.Errorf("expected a not ready error for %s, got %v", command, err)
+		}
+	}
+}
+
+func TestClient_HeartbeatRequest(t *testing.T) {
+	m := &Client{
+		conf:         &Config{},
+		shutdown:     make(chan interface{}),
+		restart:      make(chan interface{}),
+		eventChan:    make(chan *Event),
+		receiveChan:  make(chan *discordPacket),
+		emitChan:     make(chan *clientPacket),
+		ratelimit:    newRatelimiter(),
+		random:       newRandom(nil),
+		disconnected: true,
+	}
+	m.setDisconnected(false)
+	m.helloReceived = true
+	m.RegisterEvent("TEST")
+	m.Start()
+	defer close(m.shutdown)
+
+	const events = 50
+	go func() {
+		for i := 1; i <= events; i++ {
+			m.receiveChan <- &discordPacket{Op: opcode.DiscordEvent, EventName: "TEST", SequenceNumber: uint(i)}
+			m.receiveChan <- &discordPacket{Op: opcode.Heartbeat}
+		}
+	}()
+	go func() {
+		for i := 1; i <= events; i++ {
+			<-m.EventChan()
+		}
+	}()
+	go func() {
+		// regular heartbeats sent while the events are processed
+		for i := 0; i < events; i++ {
+			_ = m.sendHeartbeat()
+		}
+	}()
+
+	var requested uint
+	for i := 0; i < 2*events; i++ {
+		select {
+		case packet := <-m.emitChan:
+			if packet.Op != opcode.Heartbeat {
+				t.Fatalf("expected heartbeat, got op %d", packet.Op)
+			}
+			// a regular heartbeat sent before the first event has no sequence number
+			if seq, ok := packet.Data.(*uint); !ok {
+				t.Fatal("expected heartbeat to carry a nullable sequence number")
+			} else if seq != nil && *seq > requested {
+				requested = *seq
+			}
+		case <-time.After(time.Second):
+			t.Fatal("heartbeat was not sent")
+		}
+	}
+	if requested != events {
+		t.Errorf("expected the last heartbeat to carry sequence number %d, got %d", events, requested)
+	}
+}
+
+func TestClient_DisableAutoReconnect(t *testing.T) {
+	conn := newTestWS()
+	opened := make(chan interface{}, 10)
+	defer close(conn.acceptAll(opened))
+
+	disconnected := make(chan interface{})
+	m, _ := NewTestClient(&Config{
+		Endpoint:             "sfkjsdlfsf",
+		HTTPClient:           &http.Client{},
+		DisableAutoReconnect: true,
+		OnDisconnect: func() {
+			close(disconnected)
+		},
+	}, conn)
+	m.timeoutMultiplier = 0
+	defer close(conn.reading)
+	if err := m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	<-opened
+
+	_ = m.reconnect()
+
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Fatal("expected the disconnect callback to be called")
+	}
+	if !m.isDisconnected() {
+		t.Error("expected client to stay disconnected")
+	}
+	select {
+	case <-opened:
+		t.Error("expected no reconnect")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+type resumableTestWS struct {
+	*testWS
+	resumableClose chan interface{}
+}
+
+func (g *resumableTestWS) CloseResumable() error {
+	g.resumableClose <- 1
+	g.Lock()
+	g.disconnected = true
+	g.closeReaders()
+	g.Unlock()
+	return nil
+}
+
+func TestClient_ForceResume(t *testing.T) {
+	conn := &resumableTestWS{
+		testWS:         newTestWS(),
+		resumableClose: make(chan interface{}, 1),
+	}
+	opened := make(chan interface{}, 10)
+	done := make(chan interface{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-conn.opening:
+				opened <- true
+			case <-conn.closing:
+				t.Error("expected the connection to be closed with a resumable close")
+			case <-conn.writing:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	m, _ := NewTestClient(&Config{
+		Endpoint:   "sfkjsdlfsf",
+		HTTPClient: &http.Client{},
+	}, conn)
+	m.timeoutMultiplier = 0
+	defer close(conn.reading)
+	if err := m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	<-opened
+
+	if err := m.ForceResume(); err == nil {
+		t.Error("expected error when there is no session")
+	}
+
+	m.Lock()
+	m.sessionID = "abc"
+	m.sequenceNumber = 5
+	m.Unlock()
+	if err := m.ForceResume(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-conn.resumableClose:
+	case <-time.After(time.Second):
+		t.Error("expected a resumable close")
+	}
+	select {
+	case <-opened:
+	case <-time.After(time.Second):
+		t.Error("expected a new connection")
+	}
+
+	m.RLock()
+	session, seq := m.sessionID, m.sequenceNumber
+	m.RUnlock()
+	if session != "abc" || seq != 5 {
+		t.Error("expected the session to be kept for resuming")
+	}
+}
+
+func TestClient_CloseForResume(t *testing.T) {
+	conn := &resumableTestWS{
+		testWS:         newTestWS(),
+		resumableClose: make(chan interface{}, 1),
+	}
+	opened := make(chan interface{}, 10)
+	written := make(chan *clientPacket, 10)
+	done := make(chan interface{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-conn.opening:
+				opened <- true
+			case <-conn.closing:
+				t.Error("expected the connection to be closed with a resumable close")
+			case v := <-conn.writing:
+				written <- v.(*clientPacket)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	m, _ := NewTestClient(&Config{
+		Endpoint:   "sfkjsdlfsf",
+		HTTPClient: &http.Client{},
+	}, conn)
+	m.timeoutMultiplier = 0
+	defer close(conn.reading)
+	if err := m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	<-opened
+
+	if err := m.CloseForResume(); err == nil {
+		t.Error("expected error when there is no session")
+	}
+
+	m.Lock()
+	m.sessionID = "abc"
+	m.sequenceNumber = 5
+	m.Unlock()
+	if err := m.CloseForResume(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-conn.resumableClose:
+	case <-time.After(time.Second):
+		t.Fatal("expected a resumable close")
+	}
+
+	m.RLock()
+	session, seq := m.sessionID, m.sequenceNumber
+	m.RUnlock()
+	if session != "abc" || seq != 5 {
+		t.Error("expected the session to be kept for resuming")
+	}
+
+	// connecting again resumes the session
+	if err := m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	<-opened
+	conn.reading <- []byte(`{"t":null,"s":null,"op":10,"d":{"heartbeat_interval":45000}}`)
+	for {
+		select {
+		case pk := <-written:
+			if pk.Op == opcode.Identify {
+				t.Fatal("expected the session to be resumed, not identified")
+			}
+			if pk.Op != opcode.Resume {
+				continue
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected a resume packet")
+		}
+		break
+	}
+}
+
+func TestClient_ResumeRetries(t *testing.T) {
+	conn := newTestWS()
+	go func() {
+		<-conn.opening
+	}()
+
+	m, _ := NewTestClient(&Config{
+		Endpoint:      "sfkjsdlfsf",
+		HTTPClient:    &http.Client{},
+		ResumeRetries: 1,
+	}, conn)
+	m.timeoutMultiplier = 0
+	defer close(conn.reading)
+	if err := m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	m.stateMutex.Lock()
+	m.helloReceived = true
+	m.stateMutex.Unlock()
+
+	m.Lock()
+	m.sessionID = "abc"
+	m.sequenceNumber = 3
+	m.Unlock()
+	go m.sendResumePacket()
+
+	// the first failed resume is retried, the second falls back to identify
+	for _, op := range []uint{opcode.Resume, opcode.Resume, opcode.Identify} {
+		select {
+		case v := <-conn.writing:
+			if pk := v.(*clientPacket); pk.Op != op {
+				t.Fatalf("expected op %d, got %d", op, pk.Op)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected op %d to be sent", op)
+		}
+		if op == opcode.Resume {
+			conn.reading <- []byte(`{"t":null,"s":null,"op":9,"d":false}`)
+		}
+	}
+
+	m.RLock()
+	session := m.sessionID
+	m.RUnlock()
+	if session != "" {
+		t.Error("expected the session to be cleared after the resume retries")
+	}
+}
+
+func TestClient_HelloWithoutHeartbeatInterval(t *testing.T) {
+	conn := newTestWS()
+	opened := make(chan interface{}, 10)
+	closed := make(chan interface{}, 10)
+	done := make(chan interface{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-conn.opening:
+				opened <- true
+			case <-conn.closing:
+				closed <- true
+			case v := <-conn.writing:
+				t.Errorf("expected nothing to be sent, got op %d", v.(*clientPacket).Op)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	m, _ := NewTestClient(&Config{
+		Endpoint:   "sfkjsdlfsf",
+		HTTPClient: &http.Client{},
+	}, conn)
+	m.timeoutMultiplier = 0
+	defer close(conn.reading)
+	if err := m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	<-opened
+
+	conn.reading <- []byte(`{"t":null,"s":null,"op":10,"d":{"heartbeat_interval":"abc"}}`)
+	for _, state := range []chan interface{}{closed, opened} {
+		select {
+		case <-state:
+		case <-time.After(time.Second):
+			t.Fatal("expected the client to reconnect")
+		}
+	}
+
+	m.stateMutex.RLock()
+	hello := m.helloReceived
+	m.stateMutex.RUnlock()
+	if hello {
+		t.Error("expected the malformed hello to be ignored")
+	}
+}
+
+func TestClient_ConcurrentReconnects(t *testing.T) {
+	conn := newTestWS()
+	opened := make(chan interface{}, 10)
+	defer close(conn.acceptAll(opened))
+
+	var disconnects int
+	var mu sync.Mutex
+	m, _ := NewTestClient(&Config{
+		Endpoint:   "sfkjsdlfsf",
+		HTTPClient: &http.Client{},
+		OnDisconnect: func() {
+			mu.Lock()
+			disconnects++
+			mu.Unlock()
+		},
+	}, conn)
+	m.timeoutMultiplier = 0
+	defer close(conn.reading)
+	if err := m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	<-opened
+
+	// eg. a heartbeat ACK timeout, a reconnect request and a sequence number mismatch at once
+	start := make(chan interface{})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_ = m.reconnect()
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	select {
+	case <-opened:
+	case <-time.After(time.Second):
+		t.Fatal("expected the client to reconnect")
+	}
+	select {
+	case <-opened:
+		t.Error("expected only one reconnect")
+	case <-time.After(50 * time.Millisecond):
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if disconnects != 1 {
+		t.Errorf("expected one reconnect cycle, got %d", disconnects)
+	}
+}
+
+func TestClient_ConnectIfNeeded(t *testing.T) {
+	conn := &testWS{
+		opening:      make(chan interface{}, 2),
+		disconnected: true,
+	}
+	m, _ := NewTestClient(&Config{
+		Endpoint:   "sfkjsdlfsf",
+		HTTPClient: &http.Client{},
+	}, conn)
+
+	if err := m.ConnectIfNeeded(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ConnectIfNeeded(); err != nil {
+		t.Errorf("expected no error when already connected, got %s", err)
+	}
+	if err := m.Connect(); err == nil {
+		t.Error("expected Connect to fail when already connected")
+	}
+	if len(conn.opening) != 1 {
+		t.Errorf("expected one connection, got %d", len(conn.opening))
+	}
+}
+
+func TestClient_EmitSync(t *testing.T) {
+	conn := &testWS{
+		opening:      make(chan interface{}, 1),
+		writing:      make(chan interface{}, 1),
+		reading:      make(chan []byte),
+		disconnected: true,
+	}
+	m, _ := NewTestClient(&Config{
+		Endpoint:   "sfkjsdlfsf",
+		HTTPClient: &http.Client{},
+	}, conn)
+	m.ratelimit.global = newRatelimitBucket(1, 60)
+	defer close(conn.reading)
+
+	if err := m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	m.stateMutex.Lock()
+	m.setReady(true)
+	m.stateMutex.Unlock()
+
+	if err := m.EmitSync(cmd.RequestGuildMembers, struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(conn.writing) != 1 {
+		t.Error("expected the command to be written once EmitSync returns")
+	}
+	if err := m.EmitSync(cmd.RequestGuildMembers, struct{}{}); err == nil {
+		t.Error("expected EmitSync to report the rate limit")
+	}
+}
+
+type testIdentifyGate struct {
+	shards chan uint
+	err    error
+}
+
+func (g *testIdentifyGate) Wait(ctx context.Context, shardID uint) error {
+	g.shards <- shardID
+	return g.err
+}
+
+func TestClient_IdentifyGate(t *testing.T) {
+	gate := &testIdentifyGate{shards: make(chan uint, 2)}
+	m := &Client{
+		conf: &Config{
+			ShardID:      3,
+			ShardCount:   4,
+			IdentifyGate: gate,
+		},
+		shutdown:     make(chan interface{}),
+		emitChan:     make(chan *clientPacket, 1),
+		ratelimit:    newRatelimiter(),
+		random:       newRandom(nil),
+		disconnected: true,
+	}
+	m.setDisconnected(false)
+	m.helloReceived = true
+	defer close(m.shutdown)
+
+	if err := sendIdentityPacket(m); err != nil {
+		t.Fatal(err)
+	}
+	if id := <-gate.shards; id != 3 {
+		t.Errorf("expected the gate to be asked for shard 3, got %d", id)
+	}
+	if len(m.emitChan) != 1 {
+		t.Fatal("expected identify to be sent")
+	}
+	<-m.emitChan
+
+	gate.err = errors.New("identified by another process")
+	if err := sendIdentityPacket(m); err == nil {
+		t.Error("expected the gate to stop the identify")
+	}
+	if len(m.emitChan) != 0 {
+		t.Error("expected no identify to be sent")
+	}
+}
+
+func TestClient_CloseSignal(t *testing.T) {
+	m := &Client{
+		conf:         &Config{},
+		shutdown:     make(chan interface{}),
+		emitChan:     make(chan *clientPacket, 1),
+		ratelimit:    newRatelimiter(),
+		random:       newRandom(nil),
+		disconnected: true,
+	}
+	m.setDisconnected(false)
+	m.helloReceived = true
+	defer close(m.shutdown)
+
+	if err := m.Emit(event.Close, nil); err == nil {
+		t.Error("expected the close command to require a close signal")
+	}
+	if err := m.Emit(event.Heartbeat, &closeSignal{}); err == nil {
+		t.Error("expected the close signal to be rejected for other commands")
+	}
+	if err := m.Emit(event.Close, &closeSignal{resumable: true}); err != nil {
+		t.Fatal(err)
+	}
+	if signal, ok := (<-m.emitChan).Data.(*closeSignal); !ok || !signal.resumable {
+		t.Error("expected the close signal to be handed to the emitter")
+	}
+}
+
+func TestClient_ConnectionTimestamps(t *testing.T) {
+	m := &Client{
+		conf:         &Config{},
+		random:       newRandom(nil),
+		disconnected: true,
+	}
+	if !m.ConnectedSince().IsZero() || !m.LastDisconnect().IsZero() {
+		t.Fatal("expected no timestamps before connecting")
+	}
+
+	before := time.Now()
+	m.setDisconnected(false)
+	connected := m.ConnectedSince()
+	if connected.Before(before) {
+		t.Error("expected the connection time to be set on connect")
+	}
+	m.setDisconnected(false)
+	if !m.ConnectedSince().Equal(connected) {
+		t.Error("expected the connection time to be kept while connected")
+	}
+
+	m.setDisconnected(true)
+	status := m.Status()
+	if !status.ConnectedSince.IsZero() {
+		t.Error("expected the connection time to be reset on disconnect")
+	}
+	if status.LastDisconnect.Before(connected) {
+		t.Error("expected the disconnect time to be set on disconnect")
+	}
+}
+
+func TestClient_ConnectLatency(t *testing.T) {
+	conn := newTestWS()
+	done := make(chan interface{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-conn.closing:
+			case <-conn.writing:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	m, _ := NewTestClient(&Config{
+		Endpoint:   "sfkjsdlfsf",
+		HTTPClient: &http.Client{},
+	}, conn)
+	m.timeoutMultiplier = 0
+	defer close(conn.reading)
+	if m.ConnectLatency() != 0 || m.HelloLatency() != 0 {
+		t.Fatal("expected no latencies before connecting")
+	}
+
+	const dialDelay = 20 * time.Millisecond
+	go func() {
+		time.Sleep(dialDelay)
+		<-conn.opening
+	}()
+	if err := m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if latency := m.ConnectLatency(); latency < dialDelay {
+		t.Errorf("expected the connect latency to include the dial, got %s", latency)
+	}
+	if m.HelloLatency() != 0 {
+		t.Error("expected no hello latency before hello")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	conn.reading <- []byte(`{"t":null,"s":null,"op":10,"d":{"heartbeat_interval":45000}}`)
+	var status Status
+	for i := 0; i < 100; i++ {
+		if status = m.Status(); status.HelloLatency != 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if status.HelloLatency < 10*time.Millisecond {
+		t.Errorf("expected the hello latency to be measured from the connection, got %s", status.HelloLatency)
+	}
+	if status.ConnectLatency < dialDelay {
+		t.Errorf("expected the status to hold the connect latency, got %s", status.ConnectLatency)
+	}
+}
+
+func TestValidateShard(t *testing.T) {
+	testCases := []struct {
+		id, count uint
+		valid     bool
+	}{
+		{0, 0, true},
+		{0, 1, true},
+		{3, 4, true},
+		{1, 0, false},
+		{3, 1, false},
+		{4, 4, false},
+	}
+	for _, tc := range testCases {
+		err := validateShard(&Config{ShardID: tc.id, ShardCount: tc.count})
+		if tc.valid && err != nil {
+			t.Errorf("shard %d/%d: unexpected error: %s", tc.id, tc.count, err)
+		} else if !tc.valid && err == nil {
+			t.Errorf("shard %d/%d: expected error", tc.id, tc.count)
+		}
+	}
+
+	if err := validateShard(&Config{ShardID: 1}); err == nil || !strings.HasSuffix(err.Error(), "shard count of 1") {
+		t.Errorf("expected the error to give the shard count in effect, got %v", err)
+	}
+
+	if _, err := NewClient(&Config{ShardID: 3, ShardCount: 1, HTTPClient: &http.Client{}}); err == nil {
+		t.Error("expected NewClient to reject the shard")
+	}
+}
+
+func TestClient_IdentifyOS(t *testing.T) {
+	m := &Client{
+		conf:         &Config{},
+		shutdown:     make(chan interface{}),
+		emitChan:     make(chan *clientPacket, 1),
+		ratelimit:    newRatelimiter(),
+		random:       newRandom(nil),
+		disconnected: true,
+	}
+	m.setDisconnected(false)
+	m.helloReceived = true
+	defer close(m.shutdown)
+
+	identifyOS := func() string {
+		if err := sendIdentityPacket(m); err != nil {
+			t.Fatal(err)
+		}
+		data, err := httd.Marshal((<-m.emitChan).Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		identify := struct {
+			Properties struct {
+				OS string `json:"$os"`
+			} `json:"properties"`
+		}{}
+		if err = httd.Unmarshal(data, &identify); err != nil {
+			t.Fatal(err)
+		}
+		return identify.Properties.OS
+	}
+
+	if os := identifyOS(); os != runtime.GOOS {
+		t.Errorf("expected the os to default to %s, got %s", runtime.GOOS, os)
+	}
+	m.conf.OS = "linux"
+	if os := identifyOS(); os != "linux" {
+		t.Errorf("expected the os to be overridden, got %s", os)
+	}
+}
+
+func TestClient_BeforeIdentify(t *testing.T) {
+	gate := &testIdentifyGate{shards: make(chan uint, 10)}
+	m := &Client{
+		conf: &Config{
+			Token:        "my_token",
+			ShardID:      1,
+			ShardCount:   2,
+			IdentifyGate: gate,
+		},
+		shutdown:     make(chan interface{}),
+		emitChan:     make(chan *clientPacket, 1),
+		ratelimit:    newRatelimiter(),
+		random:       newRandom(nil),
+		disconnected: true,
+	}
+	m.setDisconnected(false)
+	m.helloReceived = true
+	defer close(m.shutdown)
+
+	m.conf.BeforeIdentify = func(payload *IdentifyPayload) {
+		payload.Properties.Device = "feature-flags"
+		payload.Presence = map[string]string{"status": "idle"}
+	}
+	if err := sendIdentityPacket(m); err != nil {
+		t.Fatal(err)
+	}
+	data, err := httd.Marshal((<-m.emitChan).Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"$device":"feature-flags"`) || !strings.Contains(string(data), `"presence":{"status":"idle"}`) {
+		t.Errorf("expected the payload changes to be sent, got %s", string(data))
+	}
+	if !strings.Contains(string(data), `"shard":[1,2]`) {
+		t.Errorf("expected the shard array to be sent, got %s", string(data))
+	}
+
+	// the token and shard array must not be tampered with
+	tampering := []func(payload *IdentifyPayload){
+		func(payload *IdentifyPayload) { payload.Token = "other_token" },
+		func(payload *IdentifyPayload) { payload.Shard = nil },
+		func(payload *IdentifyPayload) { payload.Shard[0] = 0 },
+	}
+	for i, tamper := range tampering {
+		m.conf.BeforeIdentify = tamper
+		if err := sendIdentityPacket(m); err == nil {
+			t.Errorf("%d: expected the identify to fail", i)
+		}
+		if len(m.emitChan) != 0 {
+			t.Fatalf("%d: expected no identify to be sent", i)
+		}
+	}
+	if len(gate.shards) != 1 {
+		t.Errorf("expected the rejected identifies to not use the identify gate, it was asked %d times", len(gate.shards))
+	}
+}
+
+func TestSupportedCommands(t *testing.T) {
+	commands := SupportedCommands()
+	wants := []string{event.Heartbeat, event.Identify, cmd.RequestGuildMembers, event.Resume, cmd.UpdateStatus, cmd.UpdateVoiceState}
+	sort.Strings(wants)
+	if fmt.Sprint(commands) != fmt.Sprint(wants) {
+		t.Errorf("incorrect supported commands. Got %v, wants %v", commands, wants)
+	}
+
+	ops := SupportedOpcodes()
+	if len(ops) != len(handledOpcodes) || !sort.SliceIsSorted(ops, func(i, j int) bool { return ops[i] < ops[j] }) {
+		t.Errorf("expected the handled opcodes sorted, got %v", ops)
+	}
+	for _, op := range ops {
+		if op == opcode.Shutdown || op == opcode.Close {
+			t.Errorf("internal opcode %d is not received from Discord", op)
+		}
+	}
+}
+
+func TestClient_RepeatedHello(t *testing.T) {
+	m := &Client{
+		conf:         &Config{Token: "my_token"},
+		shutdown:     make(chan interface{}),
+		restart:      make(chan interface{}),
+		eventChan:    make(chan *Event),
+		receiveChan:  make(chan *discordPacket),
+		emitChan:     make(chan *clientPacket),
+		ratelimit:    newRatelimiter(),
+		random:       newRandom(nil),
+		disconnected: true,
+	}
+	m.setDisconnected(false)
+	m.Start()
+	defer close(m.shutdown)
+
+	go func() {
+		m.receiveChan <- &discordPacket{Op: opcode.Hello, Data: []byte(`{"heartbeat_interval":45000}`)}
+		m.receiveChan <- &discordPacket{Op: opcode.Hello, Data: []byte(`{"heartbeat_interval":30000}`)}
+	}()
+
+	var identifies, heartbeats int
+	timeout := time.After(200 * time.Millisecond)
+	for done := false; !done; {
+		select {
+		case packet := <-m.emitChan:
+			switch packet.Op {
+			case opcode.Identify:
+				identifies++
+			case opcode.Heartbeat:
+				heartbeats++
+			}
+		case <-timeout:
+			done = true
+		}
+	}
+	if identifies != 1 {
+		t.Errorf("expected one identify on the connection, got %d", identifies)
+	}
+	if heartbeats != 1 {
+		t.Errorf("expected one heartbeat loop on the connection, got %d heartbeats", heartbeats)
+	}
+
+	m.RLock()
+	interval := m.heartbeatInterval
+	m.RUnlock()
+	if interval != 30000 {
+		t.Errorf("expected the repeated hello to update the heartbeat interval, got %d", interval)
+	}
+}
+
+func TestClient_DisconnectWithoutEmitter(t *testing.T) {
+	conn := &testWS{
+		closing: make(chan interface{}, 1),
+		reading: make(chan []byte),
+	}
+	m, _ := NewTestClient(&Config{
+		HTTPClient: &http.Client{},
+	}, conn)
+	m.timeoutMultiplier = 0
+	defer close(conn.reading)
+
+	// connected, but the emitter has exited
+	m.setDisconnected(false)
+
+	done := make(chan error)
+	go func() {
+		done <- m.Disconnect()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(3 * closeEmitTimeout):
+		t.Fatal("Disconnect is stuck")
+	}
+	if len(conn.closing) != 1 {
+		t.Error("expected the connection to be closed")
+	}
+}
+
+func TestClient_HeartbeatIntervalOverride(t *testing.T) {
+	m := &Client{
+		conf:         &Config{HeartbeatInterval: 10 * time.Millisecond},
+		shutdown:     make(chan interface{}),
+		restart:      make(chan interface{}),
+		eventChan:    make(chan *Event),
+		receiveChan:  make(chan *discordPacket),
+		emitChan:     make(chan *clientPacket),
+		ratelimit:    newRatelimiter(),
+		random:       newRandom(nil),
+		disconnected: true,
+	}
+	m.setDisconnected(false)
+	m.Start()
+	defer close(m.shutdown)
+
+	go func() {
+		m.receiveChan <- &discordPacket{Op: opcode.Hello, Data: []byte(`{"heartbeat_interval":45000}`)}
+	}()
+
+	const beats = 3
+	timeout := time.After(time.Second)
+	for heartbeats := 0; heartbeats < beats; {
+		select {
+		case packet := <-m.emitChan:
+			if packet.Op == opcode.Heartbeat {
+				heartbeats++
+			}
+		case <-timeout:
+			t.Fatalf("expected %d heartbeats with the overridden interval, got %d", beats, heartbeats)
+		}
+	}
+}
+
+func TestValidateHeartbeatInterval(t *testing.T) {
+	testCases := []struct {
+		interval time.Duration
+		valid    bool
+	}{
+		{0, true},
+		{time.Millisecond, true},
+		{time.Millisecond - 1, false},
+		{time.Microsecond, false},
+	}
+	for _, tc := range testCases {
+		err := validateHeartbeatInterval(&Config{HeartbeatInterval: tc.interval})
+		if tc.valid && err != nil {
+			t.Errorf("%s: unexpected error: %s", tc.interval, err)
+		} else if !tc.valid && err == nil {
+			t.Errorf("%s: expected error", tc.interval)
+		}
+	}
+
+	if _, err := NewClient(&Config{HeartbeatInterval: time.Microsecond, HTTPClient: &http.Client{}}); err == nil {
+		t.Error("expected NewClient to reject the heartbeat interval")
+	}
+}
+
+func TestClient_OfflineOnDisconnect(t *testing.T) {
+	m := &Client{
+		conf:         &Config{OfflineOnDisconnect: true},
+		shutdown:     make(chan interface{}),
+		emitChan:     make(chan *clientPacket),
+		conn:         &testWS{},
+		ratelimit:    newRatelimiter(),
+		random:       newRandom(nil),
+		disconnected: true,
+	}
+	m.setDisconnected(false)
+	m.stateMutex.Lock()
+	m.setReady(true)
+	m.stateMutex.Unlock()
+
+	done := make(chan error)
+	go func() {
+		done <- m.Disconnect()
+	}()
+
+	// the presence is written before the close signal
+	packet := <-m.emitChan
+	presence, ok := packet.Data.(*offlinePresence)
+	if packet.Op != opcode.StatusUpdate || !ok || presence.Status != "invisible" {
+		t.Errorf("expected an invisible presence update, got %+v", packet)
+	}
+	packet.sent <- nil
+
+	packet = <-m.emitChan
+	if _, ok := packet.Data.(*closeSignal); !ok {
+		t.Errorf("expected the close signal, got %+v", packet)
+	}
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClient_Config(t *testing.T) {
+	header := http.Header{}
+	header.Set("Sec-WebSocket-Protocol", "disgord")
+	m := &Client{
+		conf: &Config{
+			Token:       "secret",
+			ShardID:     1,
+			ShardCount:  2,
+			Intents:     IntentGuilds,
+			DialHeaders: header,
+		},
+	}
+
+	conf := m.Config()
+	if conf.Token != "" {
+		t.Error("expected the token to be left out")
+	}
+	if conf.ShardID != 1 || conf.ShardCount != 2 || conf.Intents != IntentGuilds {
+		t.Errorf("incorrect config copy, got %+v", conf)
+	}
+
+	// changes to the copy do not affect the client
+	conf.DialHeaders.Set("Sec-WebSocket-Protocol", "changed")
+	conf.ShardID = 0
+	if m.conf.DialHeaders.Get("Sec-WebSocket-Protocol") != "disgord" || m.conf.ShardID != 1 {
+		t.Error("expected the client config to be unaffected by changes to the copy")
+	}
+	if m.conf.Token != "secret" {
+		t.Error("expected the client to keep the token")
+	}
+}
+
+// testPingWS answers pings, until pongs are stopped
+type testPingWS struct {
+	testWS
+	mu       sync.Mutex
+	pings    int
+	answer   bool
+	lastPong time.Time
+}
+
+func (g *testPingWS) Ping() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.pings++
+	if g.answer {
+		g.lastPong = time.Now()
+	}
+	return nil
+}
+
+func (g *testPingWS) LastPong() time.Time {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.lastPong
+}
+
+func TestClient_KeepAlive(t *testing.T) {
+	conn := &testPingWS{
+		testWS: testWS{closing: make(chan interface{}, 1)},
+		answer: true,
+	}
+	disconnected := make(chan struct{})
+	m := &Client{
+		conf: &Config{
+			PingInterval:         10 * time.Millisecond,
+			DisableAutoReconnect: true,
+			OnDisconnect: func() {
+				close(disconnected)
+			},
+		},
+		shutdown:     make(chan interface{}),
+		restart:      make(chan interface{}),
+		emitChan:     make(chan *clientPacket),
+		conn:         conn,
+		ratelimit:    newRatelimiter(),
+		random:       newRandom(nil),
+		disconnected: true,
+	}
+	defer close(m.shutdown)
+	m.setDisconnected(false)
+	go m.keepAlive(conn, m.connection)
+
+	<-time.After(100 * time.Millisecond)
+	select {
+	case <-disconnected:
+		t.Fatal("expected the connection to be kept while pongs are received")
+	default:
+	}
+	conn.mu.Lock()
+	if conn.pings < 2 {
+		t.Errorf("expected several pings, got %d", conn.pings)
+	}
+	conn.answer = false
+	conn.mu.Unlock()
+
+	select {
+	case <-disconnected:
+	case <-time.After(3 * closeEmitTimeout):
+		t.Fatal("expected a reconnect when pongs are missing")
+	}
+}
@@ -0,0 +1,111 @@
+package websocket
+
+import "strconv"
+
+// CloseCode is the code Discord closes the socket connection with, which tells why it was closed and
+// whether the client should reconnect.
+// https://discordapp.com/developers/docs/topics/opcodes-and-status-codes#gateway-gateway-close-event-codes
+type CloseCode int
+
+const (
+	// CloseUnknownError something went wrong on Discord's side. Also used by the client to close a
+	// connection without invalidating the session.
+	CloseUnknownError CloseCode = 4000
+
+	// CloseUnknownOpcode an invalid opcode or payload was sent
+	CloseUnknownOpcode CloseCode = 4001
+
+	// CloseDecodeError a payload Discord could not decode was sent
+	CloseDecodeError CloseCode = 4002
+
+	// CloseNotAuthenticated a payload was sent before identifying
+	CloseNotAuthenticated CloseCode = 4003
+
+	// CloseAuthenticationFailed the bot token is incorrect
+	CloseAuthenticationFailed CloseCode = 4004
+
+	// CloseAlreadyAuthenticated more than one identify was sent
+	CloseAlreadyAuthenticated CloseCode = 4005
+
+	// CloseInvalidSequence the sequence number of a resume was invalid
+	CloseInvalidSequence CloseCode = 4007
+
+	// CloseRateLimited too many commands were sent
+	CloseRateLimited CloseCode = 4008
+
+	// CloseSessionTimedOut the session timed out
+	CloseSessionTimedOut CloseCode = 4009
+
+	// CloseInvalidShard the shard given when identifying is invalid
+	CloseInvalidShard CloseCode = 4010
+
+	// CloseShardingRequired the session would handle too many guilds, more shards are needed
+	CloseShardingRequired CloseCode = 4011
+
+	// CloseInvalidAPIVersion the gateway version is not accepted
+	CloseInvalidAPIVersion CloseCode = 4012
+
+	// CloseInvalidIntents the intents given when identifying are invalid
+	CloseInvalidIntents CloseCode = 4013
+
+	// CloseDisallowedIntents the intents given when identifying are not enabled for the bot
+	CloseDisallowedIntents CloseCode = 4014
+)
+
+func (c CloseCode) String() string {
+	switch c {
+	case CloseUnknownError:
+		return "unknown error"
+	case CloseUnknownOpcode:
+		return "unknown opcode"
+	case CloseDecodeError:
+		return "decode error"
+	case CloseNotAuthenticated:
+		return "not authenticated"
+	case CloseAuthenticationFailed:
+		return "authentication failed"
+	case CloseAlreadyAuthenticated:
+		return "already authenticated"
+	case CloseInvalidSequence:
+		return "invalid sequence"
+	case CloseRateLimited:
+		return "rate limited"
+	case CloseSessionTimedOut:
+		return "session timed out"
+	case CloseInvalidShard:
+		return "invalid shard"
+	case CloseShardingRequired:
+		return "sharding required"
+	case CloseInvalidAPIVersion:
+		return "invalid API version"
+	case CloseInvalidIntents:
+		return "invalid intents"
+	case CloseDisallowedIntents:
+		return "disallowed intents"
+	default:
+		return "close code " + strconv.Itoa(int(c))
+	}
+}
+
+// IsFatal reports whether reconnecting can never succeed without changing the configuration, such as the
+// bot token, the shards or the intents.
+func (c CloseCode) IsFatal() bool {
+	switch c {
+	case CloseAuthenticationFailed, CloseInvalidShard, CloseShardingRequired, CloseInvalidAPIVersion,
+		CloseInvalidIntents, CloseDisallowedIntents:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsResumable reports whether the session can be resumed after the connection was closed with this code.
+// Otherwise a new session must be identified, if the code is not fatal.
+func (c CloseCode) IsResumable() bool {
+	switch c {
+	case CloseInvalidSequence, CloseSessionTimedOut:
+		return false
+	default:
+		return !c.IsFatal()
+	}
+}
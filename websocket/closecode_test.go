@@ -0,0 +1,62 @@
+package websocket
+
+import (
+	"testing"
+)
+
+func TestCloseCode(t *testing.T) {
+	testCases := []struct {
+		code      CloseCode
+		fatal     bool
+		resumable bool
+	}{
+		{0, false, true},
+		{CloseUnknownError, false, true},
+		{CloseRateLimited, false, true},
+		{CloseInvalidSequence, false, false},
+		{CloseSessionTimedOut, false, false},
+		{CloseAuthenticationFailed, true, false},
+		{CloseShardingRequired, true, false},
+		{CloseDisallowedIntents, true, false},
+	}
+
+	for _, tc := range testCases {
+		if tc.code.IsFatal() != tc.fatal {
+			t.Errorf("%s: expected fatal to be %t", tc.code, tc.fatal)
+		}
+		if tc.code.IsResumable() != tc.resumable {
+			t.Errorf("%s: expected resumable to be %t", tc.code, tc.resumable)
+		}
+	}
+}
+
+func TestClient_ClosedWith(t *testing.T) {
+	m := &Client{
+		conf:         &Config{},
+		shutdown:     make(chan interface{}),
+		conn:         &testWS{disconnected: true},
+		disconnected: true,
+		sessionID:    "a",
+	}
+
+	m.closedWith(&ErrorUnexpectedClose{code: CloseRateLimited})
+	if m.LastCloseCode() != CloseRateLimited {
+		t.Errorf("incorrect last close code, got %s", m.LastCloseCode())
+	}
+	if !m.haveSession() {
+		t.Error("expected the session to be kept for a resumable close code")
+	}
+
+	m.closedWith(&ErrorUnexpectedClose{code: CloseSessionTimedOut})
+	if m.haveSession() {
+		t.Error("expected the session to be forgotten for a close code that is not resumable")
+	}
+
+	m.closedWith(&ErrorUnexpectedClose{code: CloseAuthenticationFailed})
+	if err := m.reconnect(); err == nil {
+		t.Error("expected no reconnect after a fatal close code")
+	}
+	if status := m.Status(); status.LastCloseCode != CloseAuthenticationFailed {
+		t.Errorf("incorrect last close code in status, got %s", status.LastCloseCode)
+	}
+}
@@ -0,0 +1,79 @@
+package websocket
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/andersfylling/disgord/websocket/cmd"
+	"github.com/sirupsen/logrus"
+)
+
+// presenceCoalescer sends at most one presence update per window to each shard. The first update is sent
+// right away, while the updates given during the window replace each other, such that only the latest one
+// is sent once the window ends.
+type presenceCoalescer struct {
+	sync.Mutex
+	window  time.Duration
+	open    map[*Client]bool        // shards within a window
+	pending map[*Client]interface{} // latest status to send once the window ends
+}
+
+func newPresenceCoalescer(window time.Duration) *presenceCoalescer {
+	return &presenceCoalescer{
+		window:  window,
+		open:    map[*Client]bool{},
+		pending: map[*Client]interface{}{},
+	}
+}
+
+// update sends the status to the shard, or holds it back until the current window ends. Errors of held
+// back updates are logged, as the caller has already returned.
+func (c *presenceCoalescer) update(shard *Client, status interface{}) error {
+	c.Lock()
+	if c.open[shard] {
+		c.pending[shard] = status
+		c.Unlock()
+		return nil
+	}
+	c.open[shard] = true
+	c.Unlock()
+
+	time.AfterFunc(c.window, func() {
+		c.flush(shard)
+	})
+	return shard.Emit(cmd.UpdateStatus, status)
+}
+
+// flush sends the held back status, if any, and starts a new window. Otherwise the window is closed.
+func (c *presenceCoalescer) flush(shard *Client) {
+	c.Lock()
+	status, exists := c.pending[shard]
+	delete(c.pending, shard)
+	if !exists {
+		delete(c.open, shard)
+	}
+	c.Unlock()
+	if !exists {
+		return
+	}
+
+	time.AfterFunc(c.window, func() {
+		c.flush(shard)
+	})
+	if err := shard.Emit(cmd.UpdateStatus, status); err != nil {
+		logrus.Error("unable to update the presence of shard " + strconv.Itoa(int(shard.conf.ShardID)) + ": " + err.Error())
+	}
+}
+
+// forget drops the state of shards that are no longer used. A window that is still open ends without
+// sending the held back status.
+func (c *presenceCoalescer) forget(shards []*Client) {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, shard := range shards {
+		delete(c.open, shard)
+		delete(c.pending, shard)
+	}
+}
@@ -0,0 +1,55 @@
+package websocket
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"github.com/andersfylling/disgord/httd"
+)
+
+const eventPresenceUpdate = "PRESENCE_UPDATE"
+
+// maxPresenceDedupeEntries bounds the memory of the presence dedupe. A map entry of two hashes takes 20 to
+// 40 bytes depending on how full the map is, so the dedupe of a shard stays below 5 MB. Once reached, the
+// last seen presences are forgotten and tracked anew.
+const maxPresenceDedupeEntries = 1 << 17
+
+// presenceDedupe remembers a hash of the last PRESENCE_UPDATE of every user in every guild, such that
+// repeated identical updates can be dropped, see Config.DedupePresences. Only a hash of the user and guild,
+// and a hash of the update, is kept per entry.
+type presenceDedupe struct {
+	sync.Mutex
+	last map[uint64]uint64
+}
+
+// duplicate reports whether the presence update is identical to the last one of the user in the guild
+func (d *presenceDedupe) duplicate(data []byte) bool {
+	var presence struct {
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+		GuildID string `json:"guild_id"`
+	}
+	if err := httd.Unmarshal(data, &presence); err != nil || presence.User.ID == "" {
+		return false
+	}
+
+	key := fnv.New64a()
+	key.Write([]byte(presence.User.ID))
+	key.Write([]byte{':'})
+	key.Write([]byte(presence.GuildID))
+	content := fnv.New64a()
+	content.Write(data)
+
+	d.Lock()
+	defer d.Unlock()
+	if d.last == nil || len(d.last) >= maxPresenceDedupeEntries {
+		d.last = make(map[uint64]uint64)
+	}
+	k, sum := key.Sum64(), content.Sum64()
+	if last, seen := d.last[k]; seen && last == sum {
+		return true
+	}
+	d.last[k] = sum
+	return false
+}
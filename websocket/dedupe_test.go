@@ -0,0 +1,52 @@
+package websocket
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPresenceDedupe(t *testing.T) {
+	d := &presenceDedupe{}
+	online := []byte(`{"user":{"id":"1"},"guild_id":"2","status":"online"}`)
+	idle := []byte(`{"user":{"id":"1"},"guild_id":"2","status":"idle"}`)
+	otherGuild := []byte(`{"user":{"id":"1"},"guild_id":"3","status":"online"}`)
+
+	if d.duplicate(online) {
+		t.Error("expected the first presence to be kept")
+	}
+	if !d.duplicate(online) {
+		t.Error("expected a repeated presence to be a duplicate")
+	}
+	if d.duplicate(otherGuild) {
+		t.Error("expected the presence in another guild to be kept")
+	}
+	if d.duplicate(idle) || d.duplicate(online) {
+		t.Error("expected changed presences to be kept")
+	}
+	if d.duplicate([]byte(`{}`)) || d.duplicate([]byte(`{}`)) {
+		t.Error("expected presences without a user to be kept")
+	}
+}
+
+func TestClient_DedupePresences(t *testing.T) {
+	m, _ := NewTestClient(&Config{
+		HTTPClient:      &http.Client{},
+		DedupePresences: true,
+	}, &testWS{})
+	m.RegisterEvent(eventPresenceUpdate)
+	m.RegisterEvent("TYPING_START")
+
+	presence := []byte(`{"user":{"id":"1"},"guild_id":"2","status":"online"}`)
+	go func() {
+		m.receiveChan <- &discordPacket{EventName: eventPresenceUpdate, SequenceNumber: 1, Data: presence}
+		m.receiveChan <- &discordPacket{EventName: eventPresenceUpdate, SequenceNumber: 2, Data: presence}
+		m.receiveChan <- &discordPacket{EventName: "TYPING_START", SequenceNumber: 3, Data: []byte(`{}`)}
+	}()
+
+	if evt := <-m.EventChan(); evt.Name != eventPresenceUpdate {
+		t.Errorf("expected the first presence update, got %s", evt.Name)
+	}
+	if evt := <-m.EventChan(); evt.Name != "TYPING_START" {
+		t.Errorf("expected the repeated presence update to be dropped, got %s", evt.Name)
+	}
+}
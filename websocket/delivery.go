@@ -0,0 +1,163 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultEventSendTimeout is used when Config.EventSendTimeout is not set
+	defaultEventSendTimeout = time.Second
+
+	// defaultEventBufferSize is used when Config.EventBufferSize is not set
+	defaultEventBufferSize = 1000
+)
+
+// SlowConsumerPolicy decides what happens to events that the application does not receive within
+// Config.EventSendTimeout
+type SlowConsumerPolicy uint8
+
+const (
+	// BufferEvents keeps the events in a bounded buffer, see Config.EventBufferSize, and delivers them in
+	// order once the application catches up. Events that do not fit in the buffer are dropped.
+	BufferEvents SlowConsumerPolicy = iota
+
+	// DropEvents drops events until the application receives from the channel again
+	DropEvents
+)
+
+// outbox holds the events of one application channel that were not received within the send timeout
+type outbox struct {
+	queue    []*Event
+	draining bool
+	stalled  bool
+}
+
+// outboxes makes sure the operation handler never waits on the application for longer than the send
+// timeout, so heartbeats and other operation codes are handled even if nobody reads the event channel.
+// Every application channel has an outbox of its own, such that a channel that is not read from never
+// holds back the events of the other channels.
+type outboxes struct {
+	sync.Mutex
+	channels map[chan *Event]*outbox
+	dropped  uint64
+}
+
+// get returns the outbox of the channel. Must be called while holding the lock.
+func (o *outboxes) get(c chan *Event) *outbox {
+	if o.channels == nil {
+		o.channels = map[chan *Event]*outbox{}
+	}
+	box, exists := o.channels[c]
+	if !exists {
+		box = &outbox{}
+		o.channels[c] = box
+	}
+	return box
+}
+
+// drop counts the event as dropped. Must be called while holding the lock.
+func (o *outboxes) drop(evt *Event) {
+	o.dropped++
+	logrus.Warnf("the event channel is not read from, dropping event %s", evt.Name)
+}
+
+// send hands the event to the application channel. Must be called while holding the delivery lock.
+func (m *Client) send(c chan *Event, evt *Event) {
+	o := &m.outboxes
+	o.Lock()
+	box := o.get(c)
+	if box.draining {
+		// the buffered events must be delivered first
+		if uint(len(box.queue)) < m.eventBufferSize() {
+			box.queue = append(box.queue, evt)
+		} else {
+			o.drop(evt)
+		}
+		o.Unlock()
+		return
+	}
+	stalled := box.stalled
+	o.Unlock()
+
+	select {
+	case c <- evt:
+		if stalled {
+			o.Lock()
+			box.stalled = false
+			o.Unlock()
+		}
+		return
+	default:
+	}
+	if stalled {
+		o.Lock()
+		o.drop(evt)
+		o.Unlock()
+		return
+	}
+
+	timer := time.NewTimer(m.eventSendTimeout())
+	defer timer.Stop()
+	select {
+	case c <- evt:
+		return
+	case <-timer.C:
+	case <-m.shutdown:
+		return
+	}
+
+	o.Lock()
+	defer o.Unlock()
+	if m.conf.SlowConsumerPolicy == DropEvents {
+		box.stalled = true
+		o.drop(evt)
+		return
+	}
+	box.queue = append(box.queue, evt)
+	box.draining = true
+	go m.drainOutbox(c, box)
+}
+
+func (m *Client) eventBufferSize() uint {
+	if m.conf.EventBufferSize == 0 {
+		return defaultEventBufferSize
+	}
+	return m.conf.EventBufferSize
+}
+
+// drainOutbox delivers the buffered events of the channel, in order, as the application receives them. An
+// event is only removed from the queue once delivered, so new events are not sent ahead of it.
+func (m *Client) drainOutbox(c chan *Event, box *outbox) {
+	o := &m.outboxes
+	for {
+		o.Lock()
+		if len(box.queue) == 0 {
+			box.queue = nil
+			box.draining = false
+			o.Unlock()
+			return
+		}
+		next := box.queue[0]
+		o.Unlock()
+
+		select {
+		case c <- next:
+		case <-m.shutdown:
+			return
+		}
+
+		o.Lock()
+		box.queue = box.queue[1:]
+		o.Unlock()
+	}
+}
+
+func (m *Client) eventSendTimeout() time.Duration {
+	if m.conf.EventSendTimeout == 0 {
+		return defaultEventSendTimeout
+	}
+	return m.conf.EventSendTimeout
+}
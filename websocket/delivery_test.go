@@ -0,0 +1,138 @@
+package websocket
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/andersfylling/disgord/websocket/opcode"
+)
+
+func newSlowConsumerClient(policy SlowConsumerPolicy, buffer uint) *Client {
+	m := &Client{
+		conf: &Config{
+			EventSendTimeout:   10 * time.Millisecond,
+			SlowConsumerPolicy: policy,
+			EventBufferSize:    buffer,
+			ChannelBuffer:      1,
+		},
+		shutdown:     make(chan interface{}),
+		restart:      make(chan interface{}),
+		eventChan:    make(chan *Event),
+		receiveChan:  make(chan *discordPacket),
+		emitChan:     make(chan *clientPacket),
+		ratelimit:    newRatelimiter(),
+		random:       newRandom(nil),
+		disconnected: true,
+	}
+	m.setDisconnected(false)
+	m.helloReceived = true
+	return m
+}
+
+func TestClient_SlowConsumer(t *testing.T) {
+	const events = 20
+	const buffer = 5
+
+	for _, policy := range []SlowConsumerPolicy{BufferEvents, DropEvents} {
+		m := newSlowConsumerClient(policy, buffer)
+		m.RegisterEvent("TEST")
+		m.Start()
+
+		// nobody reads the event channel, yet heartbeat requests must still be answered
+		go func() {
+			for i := 1; i <= events; i++ {
+				m.receiveChan <- &discordPacket{
+					Op:             opcode.DiscordEvent,
+					EventName:      "TEST",
+					SequenceNumber: uint(i),
+					Data:           []byte(strconv.Itoa(i)),
+				}
+				m.receiveChan <- &discordPacket{Op: opcode.Heartbeat}
+			}
+		}()
+		for i := 1; i <= events; i++ {
+			select {
+			case packet := <-m.emitChan:
+				if packet.Op != opcode.Heartbeat {
+					t.Fatalf("expected heartbeat, got op %d", packet.Op)
+				}
+				if seq := packet.Data.(*uint); seq == nil || *seq != uint(i) {
+					t.Fatalf("expected heartbeat %d to carry sequence number %d", i, i)
+				}
+			case <-m.restart:
+				t.Fatal("the client reconnected")
+			case <-time.After(time.Second):
+				t.Fatal("heartbeat request was not answered while the event channel was full")
+			}
+		}
+
+		var delivered int
+		if policy == BufferEvents {
+			for ; delivered < buffer; delivered++ {
+				evt := <-m.EventChan()
+				if wants := strconv.Itoa(delivered + 1); string(evt.Data) != wants {
+					t.Errorf("expected buffered events in order. Got %s, wants %s", string(evt.Data), wants)
+				}
+			}
+		}
+		if dropped := m.Status().DroppedEvents; dropped != uint64(events-delivered) {
+			t.Errorf("expected %d dropped events, got %d", events-delivered, dropped)
+		}
+
+		close(m.shutdown)
+	}
+}
+
+func TestClient_SlowConsumerIsolated(t *testing.T) {
+	const events = 20
+
+	for _, policy := range []SlowConsumerPolicy{BufferEvents, DropEvents} {
+		m := newSlowConsumerClient(policy, 5)
+		m.RegisterEvent("OTHER")
+		typed := m.TypedChan("TEST")
+		m.Start()
+
+		// the event channel is never read, which must not hold back the typed channel
+		go func() {
+			for i := 1; i <= events; i++ {
+				m.receiveChan <- &discordPacket{
+					Op:             opcode.DiscordEvent,
+					EventName:      "OTHER",
+					SequenceNumber: uint(2*i - 1),
+				}
+				m.receiveChan <- &discordPacket{
+					Op:             opcode.DiscordEvent,
+					EventName:      "TEST",
+					SequenceNumber: uint(2 * i),
+					Data:           []byte(strconv.Itoa(i)),
+				}
+				m.receiveChan <- &discordPacket{Op: opcode.Heartbeat}
+			}
+		}()
+		go func() {
+			for {
+				select {
+				case <-m.emitChan:
+				case <-m.shutdown:
+					return
+				}
+			}
+		}()
+
+		for i := 1; i <= events; i++ {
+			select {
+			case evt := <-typed:
+				if wants := strconv.Itoa(i); string(evt.Data) != wants {
+					t.Fatalf("expected typed events in order. Got %s, wants %s", string(evt.Data), wants)
+				}
+			case <-m.restart:
+				t.Fatal("the client reconnected")
+			case <-time.After(time.Second):
+				t.Fatalf("typed event %d was not delivered while the event channel was full", i)
+			}
+		}
+
+		close(m.shutdown)
+	}
+}
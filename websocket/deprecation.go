@@ -0,0 +1,27 @@
+package websocket
+
+import (
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+// the oldest gateway version which Discord has not yet discontinued.
+// https://discordapp.com/developers/docs/topics/gateway#gateways-gateway-versions
+const oldestSupportedVersion = 6
+
+// warnIfVersionDeprecated warns before connecting with a gateway version Discord is known to have discontinued
+func (m *Client) warnIfVersionDeprecated() {
+	if m.conf.Version != 0 && m.conf.Version < oldestSupportedVersion {
+		m.deprecationWarning("gateway version " + strconv.Itoa(m.conf.Version) + " is discontinued, use version " +
+			strconv.Itoa(oldestSupportedVersion) + " or later")
+	}
+}
+
+// deprecationWarning logs the warning and notifies the Config.OnDeprecation callback, if set
+func (m *Client) deprecationWarning(msg string) {
+	logrus.Warn(msg)
+	if m.conf.OnDeprecation != nil {
+		go m.conf.OnDeprecation(m.conf.Version, msg)
+	}
+}
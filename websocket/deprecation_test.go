@@ -0,0 +1,37 @@
+package websocket
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClient_DeprecatedVersion(t *testing.T) {
+	conn := newTestWS()
+	go func() {
+		<-conn.opening
+	}()
+
+	warned := make(chan int, 1)
+	m, _ := NewTestClient(&Config{
+		Endpoint:   "sfkjsdlfsf",
+		HTTPClient: &http.Client{},
+		Version:    5,
+		OnDeprecation: func(version int, msg string) {
+			warned <- version
+		},
+	}, conn)
+	defer close(conn.reading)
+	if err := m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case version := <-warned:
+		if version != 5 {
+			t.Errorf("expected warning for version 5, got %d", version)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected a deprecation warning")
+	}
+}
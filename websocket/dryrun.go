@@ -0,0 +1,49 @@
+package websocket
+
+import "sync"
+
+// RecordedCommand is a command that was recorded instead of sent, see Config.DryRun
+type RecordedCommand struct {
+	Command string
+	Op      uint
+
+	// Data is the command data, encoded as it would have been sent
+	Data []byte
+}
+
+// commandRecorder holds the commands emitted in dry run mode
+type commandRecorder struct {
+	sync.Mutex
+	commands []RecordedCommand
+}
+
+// record encodes the command data and keeps it, instead of handing it to the emitter. The command has
+// already passed the same validation and rate limit as a command that is sent.
+func (m *Client) record(command string, op uint, data interface{}, sent chan error) error {
+	encoded, _, err := m.codec().encode(data)
+	if err != nil {
+		return err
+	}
+
+	m.recorder.Lock()
+	m.recorder.commands = append(m.recorder.commands, RecordedCommand{
+		Command: command,
+		Op:      op,
+		Data:    encoded,
+	})
+	m.recorder.Unlock()
+
+	m.countCommand(command, CommandEmitted)
+	if sent != nil {
+		sent <- nil
+	}
+	return nil
+}
+
+// RecordedCommands returns the commands emitted in dry run mode, oldest first
+func (m *Client) RecordedCommands() []RecordedCommand {
+	m.recorder.Lock()
+	defer m.recorder.Unlock()
+
+	return append([]RecordedCommand(nil), m.recorder.commands...)
+}
@@ -0,0 +1,64 @@
+package websocket
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/andersfylling/disgord/websocket/cmd"
+	"github.com/andersfylling/disgord/websocket/opcode"
+)
+
+func TestClient_DryRun(t *testing.T) {
+	m, err := NewClient(&Config{
+		HTTPClient: &http.Client{},
+		DryRun:     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown()
+
+	if err = m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Emit(cmd.UpdateStatus, struct {
+		Status string `json:"status"`
+	}{"idle"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.EmitSync(cmd.RequestGuildMembers, struct {
+		GuildID string `json:"guild_id"`
+	}{"1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Emit("UNKNOWN", nil); err == nil {
+		t.Error("expected unsupported commands to be rejected")
+	}
+
+	recorded := m.RecordedCommands()
+	if len(recorded) != 2 {
+		t.Fatalf("expected 2 recorded commands, got %d", len(recorded))
+	}
+	if recorded[0].Command != cmd.UpdateStatus || recorded[0].Op != opcode.StatusUpdate || string(recorded[0].Data) != `{"status":"idle"}` {
+		t.Errorf("incorrect recorded command %s %d %s", recorded[0].Command, recorded[0].Op, string(recorded[0].Data))
+	}
+	if recorded[1].Command != cmd.RequestGuildMembers || string(recorded[1].Data) != `{"guild_id":"1"}` {
+		t.Errorf("incorrect recorded command %s %s", recorded[1].Command, string(recorded[1].Data))
+	}
+
+	// the rate limit still applies
+	for i := 0; i < 200; i++ {
+		if err = m.Emit(cmd.UpdateStatus, nil); err == ErrRateLimited {
+			break
+		}
+	}
+	if err != ErrRateLimited {
+		t.Error("expected the commands to be rate limited")
+	}
+	if status := m.Status(); status.Connection != StatusDisconnected {
+		t.Errorf("expected a dry run to never connect, got %s", status.Connection)
+	}
+	if err = m.Disconnect(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,83 @@
+package websocket
+
+import (
+	"errors"
+
+	"github.com/andersfylling/disgord/httd"
+)
+
+// codec encodes the packets written to the gateway, and decodes the ones read from it, for the encoding
+// given by Config#Encoding. Discord sends and expects the same encoding in both directions.
+type codec interface {
+	// encode returns the message to write, and whether it is a binary message
+	encode(v interface{}) (data []byte, binary bool, err error)
+	decode(data []byte, packet *discordPacket) error
+}
+
+// messageWriter is implemented by connections that can write messages encoded by a codec. Other
+// connections only support JSON, through Conn#WriteJSON.
+type messageWriter interface {
+	WriteMessage(data []byte, binary bool) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) encode(v interface{}) (data []byte, binary bool, err error) {
+	data, err = httd.Marshal(v)
+	return data, false, err
+}
+
+func (jsonCodec) decode(data []byte, packet *discordPacket) error {
+	return packet.UnmarshalJSON(data)
+}
+
+// codecs holds the supported gateway encodings
+var codecs = map[string]codec{
+	encodingJSON: jsonCodec{},
+}
+
+// codecFor returns the codec of the encoding. An empty encoding is JSON.
+func codecFor(encoding string) (codec, error) {
+	if encoding == "" {
+		encoding = encodingJSON
+	}
+	c, ok := codecs[encoding]
+	if !ok {
+		return nil, errors.New("unsupported gateway encoding: " + encoding)
+	}
+	return c, nil
+}
+
+// codec returns the codec of Config#Encoding, which is verified when connecting
+func (m *Client) codec() codec {
+	if c, err := codecFor(m.conf.Encoding); err == nil {
+		return c
+	}
+	return jsonCodec{}
+}
+
+// checkPayloadSize encodes the command as it would be written, and fails when it exceeds MaxPayloadSize
+func (m *Client) checkPayloadSize(command string, op uint, data interface{}) error {
+	encoded, _, err := m.codec().encode(&clientPacket{Op: op, Data: data})
+	if err != nil {
+		return err
+	}
+	if len(encoded) > MaxPayloadSize {
+		return &ErrorPayloadTooLarge{Command: command, Size: len(encoded)}
+	}
+	return nil
+}
+
+// write encodes the packet with the codec and writes it to the connection
+func (m *Client) write(msg *clientPacket) error {
+	writer, ok := m.conn.(messageWriter)
+	if !ok {
+		return m.conn.WriteJSON(msg)
+	}
+
+	data, binary, err := m.codec().encode(msg)
+	if err != nil {
+		return err
+	}
+	return writer.WriteMessage(data, binary)
+}
@@ -0,0 +1,125 @@
+package websocket
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/andersfylling/disgord/websocket/cmd"
+	"github.com/andersfylling/disgord/websocket/opcode"
+)
+
+// testMessageWS records the encoded messages
+type testMessageWS struct {
+	testWS
+	mu       sync.Mutex
+	messages [][]byte
+}
+
+func (g *testMessageWS) WriteMessage(data []byte, binary bool) error {
+	g.mu.Lock()
+	g.messages = append(g.messages, data)
+	g.mu.Unlock()
+	return nil
+}
+
+func TestCodecFor(t *testing.T) {
+	for _, encoding := range []string{"", encodingJSON} {
+		if _, err := codecFor(encoding); err != nil {
+			t.Errorf("expected %q to be supported, got %s", encoding, err)
+		}
+	}
+	if _, err := codecFor("etf"); err == nil {
+		t.Error("expected etf to be unsupported")
+	}
+}
+
+func TestJSONCodec(t *testing.T) {
+	c := jsonCodec{}
+	data, binary, err := c.encode(&clientPacket{Op: opcode.Heartbeat, Data: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if binary || string(data) != `{"op":1,"d":5}` {
+		t.Errorf("incorrect encoding, got %s", data)
+	}
+
+	packet := &discordPacket{}
+	if err = c.decode([]byte(`{"t":"READY","s":1,"op":0,"d":{}}`), packet); err != nil {
+		t.Fatal(err)
+	}
+	if packet.EventName != "READY" || packet.SequenceNumber != 1 || string(packet.Data) != "{}" {
+		t.Errorf("incorrect decoding, got %+v", packet)
+	}
+}
+
+func TestClient_WriteEncoded(t *testing.T) {
+	conn := &testMessageWS{}
+	m := &Client{conf: &Config{}, conn: conn}
+	if err := m.write(&clientPacket{Op: opcode.Heartbeat, Data: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if len(conn.messages) != 1 || string(conn.messages[0]) != `{"op":1,"d":5}` {
+		t.Errorf("expected the packet to be written as an encoded message, got %q", conn.messages)
+	}
+}
+
+func TestClient_UnsupportedEncoding(t *testing.T) {
+	conn := &testWS{opening: make(chan interface{}, 1), reading: make(chan []byte)}
+	m, _ := NewTestClient(&Config{
+		Endpoint:   "sfkjsdlfsf",
+		HTTPClient: &http.Client{},
+		Encoding:   "etf",
+	}, conn)
+	defer close(conn.reading)
+	if err := m.Connect(); err == nil {
+		t.Error("expected Connect to fail with an unsupported encoding")
+	}
+	if len(conn.opening) != 0 {
+		t.Error("expected no connection to be opened")
+	}
+}
+
+func TestClient_EmitPayloadTooLarge(t *testing.T) {
+	m, err := NewClient(&Config{
+		HTTPClient: &http.Client{},
+		DryRun:     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown()
+
+	type requestMembers struct {
+		GuildID string   `json:"guild_id"`
+		UserIDs []string `json:"user_ids"`
+	}
+	userIDs := make([]string, 250)
+	for i := range userIDs {
+		userIDs[i] = strings.Repeat("1", 18)
+	}
+
+	err = m.Emit(cmd.RequestGuildMembers, &requestMembers{GuildID: "1", UserIDs: userIDs})
+	tooLarge, ok := err.(*ErrorPayloadTooLarge)
+	if !ok {
+		t.Fatalf("expected a *ErrorPayloadTooLarge, got %v", err)
+	}
+	if tooLarge.Command != cmd.RequestGuildMembers || tooLarge.Size <= MaxPayloadSize {
+		t.Errorf("incorrect error %+v", tooLarge)
+	}
+	if len(m.RecordedCommands()) != 0 {
+		t.Error("expected the oversized command to not be emitted")
+	}
+	if wait := m.ratelimit.RetryAfter(cmd.RequestGuildMembers); wait > 0 {
+		t.Error("expected the oversized command to not take any rate limit capacity")
+	}
+
+	// a batch of the user IDs fits
+	if err = m.Emit(cmd.RequestGuildMembers, &requestMembers{GuildID: "1", UserIDs: userIDs[:100]}); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.RecordedCommands()) != 1 {
+		t.Error("expected the command to be emitted")
+	}
+}
@@ -1,9 +1,11 @@
 package websocket
 
 import (
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"sync"
 
 	"github.com/andersfylling/disgord/endpoint"
 	"github.com/andersfylling/disgord/httd"
@@ -17,6 +19,26 @@ type gatewayResponse struct {
 	URL string `json:"url"`
 }
 
+// gatewayRoutes holds the last endpoint fetched for every gateway version, which is used when Discord
+// cannot be reached. This lets clients connect during short Discord REST outages, such as new shards.
+var gatewayRoutes = struct {
+	sync.Mutex
+	urls map[int]string
+}{urls: map[int]string{}}
+
+func cacheGatewayRoute(version int, url string) {
+	gatewayRoutes.Lock()
+	gatewayRoutes.urls[version] = url
+	gatewayRoutes.Unlock()
+}
+
+func cachedGatewayRoute(version int) (url string) {
+	gatewayRoutes.Lock()
+	url = gatewayRoutes.urls[version]
+	gatewayRoutes.Unlock()
+	return
+}
+
 // getGatewayRoute get the connection endpoint for the session
 func getGatewayRoute(client *http.Client, version int) (url string, err error) {
 	var resp *http.Response
@@ -31,13 +53,68 @@ func getGatewayRoute(client *http.Client, version int) (url string, err error) {
 	if err != nil {
 		return
 	}
+	if resp.StatusCode != http.StatusOK {
+		err = errors.New("unable to get gateway endpoint, status " + strconv.Itoa(resp.StatusCode) + ": " + string(body))
+		return
+	}
 
 	gatewayResponse := gatewayResponse{}
 	err = httd.Unmarshal(body, &gatewayResponse)
 	if err != nil {
 		return
 	}
+	if gatewayResponse.URL == "" {
+		err = errors.New("discord did not give a gateway endpoint")
+		return
+	}
 
 	url = gatewayResponse.URL + "?v=" + strconv.Itoa(version) + "&encoding=" + encodingJSON
 	return
 }
+
+// GatewayBotInfo holds the Get Gateway Bot response, which tells how many shards Discord recommends and how
+// many sessions can still be started.
+// https://discordapp.com/developers/docs/topics/gateway#get-gateway-bot
+type GatewayBotInfo struct {
+	URL               string            `json:"url"`
+	Shards            uint              `json:"shards"`
+	SessionStartLimit SessionStartLimit `json:"session_start_limit"`
+}
+
+// SessionStartLimit is the number of sessions a bot can start (identify) until the limit is reset
+type SessionStartLimit struct {
+	Total      uint `json:"total"`
+	Remaining  uint `json:"remaining"`
+	ResetAfter uint `json:"reset_after"` // milliseconds
+}
+
+// GatewayBot fetches the gateway information for the bot, without connecting to the gateway.
+func GatewayBot(client *http.Client, version int, token string) (info *GatewayBotInfo, err error) {
+	var req *http.Request
+	req, err = http.NewRequest(http.MethodGet, endpoint.GatewayBot(version), nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Authorization", "Bot "+token)
+
+	var resp *http.Response
+	resp, err = client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	var body []byte
+	body, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = errors.New("unable to get gateway bot information, status " + strconv.Itoa(resp.StatusCode) + ": " + string(body))
+		return
+	}
+
+	info = &GatewayBotInfo{}
+	err = httd.Unmarshal(body, info)
+	return
+}
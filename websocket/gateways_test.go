@@ -0,0 +1,140 @@
+package websocket
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestGatewayBot(t *testing.T) {
+	body := `{"url":"wss://gateway.discord.gg","shards":9,"session_start_limit":{"total":1000,"remaining":999,"reset_after":14400000}}`
+	client := &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("Authorization") != "Bot my_token" {
+				return &http.Response{StatusCode: http.StatusUnauthorized, Body: ioutil.NopCloser(&bytes.Buffer{})}, nil
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+			}, nil
+		}),
+	}
+
+	info, err := GatewayBot(client, 6, "my_token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.URL != "wss://gateway.discord.gg" || info.Shards != 9 {
+		t.Errorf("incorrect gateway bot info: %+v", info)
+	}
+	if limit := info.SessionStartLimit; limit.Total != 1000 || limit.Remaining != 999 || limit.ResetAfter != 14400000 {
+		t.Errorf("incorrect session start limit: %+v", limit)
+	}
+
+	if _, err = GatewayBot(client, 6, "wrong"); err == nil {
+		t.Error("expected error on unauthorized response")
+	}
+}
+
+func TestClient_GatewayRouteRetries(t *testing.T) {
+	var attempts int
+	failures := 2
+	client := &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			attempts++
+			if attempts <= failures {
+				return &http.Response{StatusCode: http.StatusBadGateway, Body: ioutil.NopCloser(&bytes.Buffer{})}, nil
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewBufferString(`{"url":"wss://gateway.discord.gg"}`)),
+			}, nil
+		}),
+	}
+
+	// a version of its own, so other tests do not fill the endpoint cache
+	const version = 1000
+	m := &Client{
+		conf: &Config{
+			HTTPClient:          client,
+			Version:             version,
+			GatewayRouteRetries: 1,
+		},
+		shutdown: make(chan interface{}),
+	}
+
+	if _, err := m.gatewayRoute(); err == nil {
+		t.Error("expected an error when every attempt fails and no endpoint is cached")
+	}
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+
+	url, err := m.gatewayRoute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "wss://gateway.discord.gg?v=1000&encoding=json" {
+		t.Errorf("incorrect endpoint, got %s", url)
+	}
+
+	// every attempt fails, so the cached endpoint is used
+	attempts = 0
+	failures = 10
+	url, err = m.gatewayRoute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "wss://gateway.discord.gg?v=1000&encoding=json" {
+		t.Errorf("expected the cached endpoint, got %s", url)
+	}
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+}
+
+func TestClient_GatewayRouteBackoffUnlocked(t *testing.T) {
+	fetching := make(chan struct{}, 10)
+	client := &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			fetching <- struct{}{}
+			return &http.Response{StatusCode: http.StatusBadGateway, Body: ioutil.NopCloser(&bytes.Buffer{})}, nil
+		}),
+	}
+	m, shutdown := NewTestClient(&Config{
+		HTTPClient:          client,
+		Version:             1001, // no cached endpoint
+		GatewayRouteRetries: 1,
+	}, &testWS{})
+
+	connected := make(chan error)
+	go func() {
+		connected <- m.Connect()
+	}()
+	<-fetching
+
+	// the client lock is not held while backing off
+	read := make(chan struct{})
+	go func() {
+		m.Config()
+		close(read)
+	}()
+	select {
+	case <-read:
+	case <-time.After(500 * time.Millisecond):
+		t.Error("the client lock is held while backing off between gateway route attempts")
+	}
+
+	close(shutdown)
+	if err := <-connected; err == nil {
+		t.Error("expected connect to fail without a gateway endpoint")
+	}
+}
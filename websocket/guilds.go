@@ -0,0 +1,70 @@
+package websocket
+
+import (
+	"sync"
+
+	"github.com/andersfylling/disgord/httd"
+)
+
+const (
+	eventGuildCreate = "GUILD_CREATE"
+	eventGuildDelete = "GUILD_DELETE"
+)
+
+// guildTracker keeps the IDs of the guilds the session is in, see Client#GuildCount. Guilds given as
+// unavailable by READY, or during an outage, are still counted.
+type guildTracker struct {
+	sync.RWMutex
+	ids map[string]struct{}
+}
+
+func (g *guildTracker) reset(guilds []*ReadyGuild) {
+	ids := make(map[string]struct{}, len(guilds))
+	for _, guild := range guilds {
+		if guild != nil {
+			ids[guild.ID] = struct{}{}
+		}
+	}
+
+	g.Lock()
+	g.ids = ids
+	g.Unlock()
+}
+
+// update adds or removes the guild of a GUILD_CREATE or GUILD_DELETE event
+func (g *guildTracker) update(name string, data []byte) {
+	var guild struct {
+		ID          string `json:"id"`
+		Unavailable bool   `json:"unavailable"`
+	}
+	if err := httd.Unmarshal(data, &guild); err != nil || guild.ID == "" {
+		return
+	}
+
+	g.Lock()
+	defer g.Unlock()
+	if g.ids == nil {
+		g.ids = make(map[string]struct{})
+	}
+	switch name {
+	case eventGuildCreate:
+		g.ids[guild.ID] = struct{}{}
+	case eventGuildDelete:
+		// an unavailable guild is an outage, the bot is still in it
+		if !guild.Unavailable {
+			delete(g.ids, guild.ID)
+		}
+	}
+}
+
+func (g *guildTracker) count() int {
+	g.RLock()
+	defer g.RUnlock()
+	return len(g.ids)
+}
+
+// GuildCount returns the number of guilds the session is in, including unavailable guilds. It is kept up
+// to date by the READY, GUILD_CREATE and GUILD_DELETE events, which do not have to be registered.
+func (m *Client) GuildCount() int {
+	return m.guilds.count()
+}
@@ -0,0 +1,35 @@
+package websocket
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestClient_GuildCount(t *testing.T) {
+	m, _ := NewTestClient(&Config{
+		HTTPClient: &http.Client{},
+	}, &testWS{})
+	m.RegisterEvent("MESSAGE_CREATE")
+
+	// the guild events are counted without being registered
+	go func() {
+		m.receiveChan <- &discordPacket{EventName: "READY", SequenceNumber: 1, Data: []byte(`{"session_id":"a","guilds":[{"id":"1","unavailable":true},{"id":"2","unavailable":true}]}`)}
+		m.receiveChan <- &discordPacket{EventName: eventGuildCreate, SequenceNumber: 2, Data: []byte(`{"id":"1"}`)}
+		m.receiveChan <- &discordPacket{EventName: eventGuildCreate, SequenceNumber: 3, Data: []byte(`{"id":"3"}`)}
+		m.receiveChan <- &discordPacket{EventName: eventGuildDelete, SequenceNumber: 4, Data: []byte(`{"id":"2","unavailable":true}`)}
+		m.receiveChan <- &discordPacket{EventName: eventGuildDelete, SequenceNumber: 5, Data: []byte(`{"id":"3"}`)}
+		m.receiveChan <- &discordPacket{EventName: "MESSAGE_CREATE", SequenceNumber: 6, Data: []byte(`{}`)}
+	}()
+
+	if evt := <-m.EventChan(); evt.Name != "READY" {
+		t.Fatalf("expected READY, got %s", evt.Name)
+	}
+	if evt := <-m.EventChan(); evt.Name != "MESSAGE_CREATE" {
+		t.Fatalf("expected the unregistered guild events to be dropped, got %s", evt.Name)
+	}
+
+	// guild 1 and the unavailable guild 2
+	if count := m.GuildCount(); count != 2 {
+		t.Errorf("expected 2 guilds, got %d", count)
+	}
+}
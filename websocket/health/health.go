@@ -0,0 +1,90 @@
+// Package health provides an HTTP health check of the gateway connections, eg. for a Kubernetes liveness
+// or readiness probe. It is kept out of the websocket package, such that bots without a HTTP server do not
+// depend on one.
+package health
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/andersfylling/disgord/httd"
+	"github.com/andersfylling/disgord/websocket"
+)
+
+// DefaultMaxHeartbeatAge is used when no max heartbeat age is given. Discord asks for a heartbeat about
+// every 41 seconds, so this allows a heartbeat ACK to be late or missing once.
+const DefaultMaxHeartbeatAge = 2 * time.Minute
+
+// ShardStatus is the health of a single shard
+type ShardStatus struct {
+	ShardID          uint      `json:"shard_id"`
+	Status           string    `json:"status"`
+	LastHeartbeatAck time.Time `json:"last_heartbeat_ack"`
+	Healthy          bool      `json:"healthy"`
+}
+
+// Response is the body written by the health check handlers
+type Response struct {
+	Healthy bool           `json:"healthy"`
+	Shards  []*ShardStatus `json:"shards"`
+}
+
+// Handler returns a health check for the client, see ShardManagerHandler
+func Handler(client *websocket.Client, maxHeartbeatAge time.Duration) http.HandlerFunc {
+	return handler(func() []*websocket.Client {
+		return []*websocket.Client{client}
+	}, maxHeartbeatAge)
+}
+
+// ShardManagerHandler returns a health check of every shard. It responds with 200 when every shard is ready
+// and Discord acknowledged a heartbeat within maxHeartbeatAge, and 503 otherwise. The body is a JSON
+// Response with the status of every shard. A maxHeartbeatAge of 0 uses DefaultMaxHeartbeatAge.
+func ShardManagerHandler(manager *websocket.ShardManager, maxHeartbeatAge time.Duration) http.HandlerFunc {
+	// the shards are looked up on every request, as they change when resharding
+	return handler(manager.Shards, maxHeartbeatAge)
+}
+
+func handler(shards func() []*websocket.Client, maxHeartbeatAge time.Duration) http.HandlerFunc {
+	if maxHeartbeatAge == 0 {
+		maxHeartbeatAge = DefaultMaxHeartbeatAge
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		resp := &Response{Healthy: true}
+		for _, shard := range shards() {
+			status := shard.Status()
+			s := shardStatus(shard.Config().ShardID, status.Connection, status.LastHeartbeatAck, now, maxHeartbeatAge)
+			resp.Shards = append(resp.Shards, s)
+			resp.Healthy = resp.Healthy && s.Healthy
+		}
+		if len(resp.Shards) == 0 {
+			resp.Healthy = false
+		}
+
+		body, err := httd.Marshal(resp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if resp.Healthy {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		w.Write(body)
+	}
+}
+
+// shardStatus decides whether a shard is healthy. A shard must be ready, and have had a heartbeat ACK
+// recently.
+func shardStatus(id uint, connection websocket.ConnectionStatus, lastAck, now time.Time, maxAge time.Duration) *ShardStatus {
+	return &ShardStatus{
+		ShardID:          id,
+		Status:           connection.String(),
+		LastHeartbeatAck: lastAck,
+		Healthy:          connection == websocket.StatusReady && !lastAck.IsZero() && now.Sub(lastAck) <= maxAge,
+	}
+}
@@ -0,0 +1,62 @@
+package health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/andersfylling/disgord/httd"
+	"github.com/andersfylling/disgord/websocket"
+)
+
+func TestShardStatus(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		connection websocket.ConnectionStatus
+		lastAck    time.Time
+		healthy    bool
+	}{
+		{websocket.StatusReady, now.Add(-time.Minute), true},
+		{websocket.StatusReady, now.Add(-3 * time.Minute), false},
+		{websocket.StatusReady, time.Time{}, false},
+		{websocket.StatusConnected, now, false},
+		{websocket.StatusDisconnected, now, false},
+	}
+
+	for i, tc := range testCases {
+		status := shardStatus(1, tc.connection, tc.lastAck, now, DefaultMaxHeartbeatAge)
+		if status.Healthy != tc.healthy {
+			t.Errorf("%d: expected healthy to be %t", i, tc.healthy)
+		}
+		if status.ShardID != 1 || status.Status != tc.connection.String() {
+			t.Errorf("%d: incorrect shard status %+v", i, status)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	client, err := websocket.NewClient(&websocket.Config{ShardID: 2, ShardCount: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Shutdown()
+
+	recorder := httptest.NewRecorder()
+	Handler(client, 0)(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected a disconnected client to be unhealthy, got status %d", recorder.Code)
+	}
+	if recorder.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("expected a JSON response, got %s", recorder.Header().Get("Content-Type"))
+	}
+
+	resp := &Response{}
+	if err = httd.Unmarshal(recorder.Body.Bytes(), resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Healthy || len(resp.Shards) != 1 || resp.Shards[0].ShardID != 2 || resp.Shards[0].Status != "disconnected" {
+		t.Errorf("incorrect response, got %+v", resp)
+	}
+}
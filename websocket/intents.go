@@ -0,0 +1,106 @@
+package websocket
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Intent is a gateway intent. Once intents are given, Discord only sends the events of the enabled intents.
+// https://discordapp.com/developers/docs/topics/gateway#gateway-intents
+type Intent uint
+
+const (
+	IntentGuilds Intent = 1 << iota
+	IntentGuildMembers
+	IntentGuildBans
+	IntentGuildEmojis
+	IntentGuildIntegrations
+	IntentGuildWebhooks
+	IntentGuildInvites
+	IntentGuildVoiceStates
+	IntentGuildPresences
+	IntentGuildMessages
+	IntentGuildMessageReactions
+	IntentGuildMessageTyping
+	IntentDirectMessages
+	IntentDirectMessageReactions
+	IntentDirectMessageTyping
+)
+
+// EventIntents holds the intents that enables each Discord event. Any one of the intents is enough for the
+// event to be sent, eg. messages are received in guilds or in direct messages. Events that are not listed
+// are always sent.
+var EventIntents = map[string]Intent{
+	"GUILD_CREATE":                IntentGuilds,
+	"GUILD_UPDATE":                IntentGuilds,
+	"GUILD_DELETE":                IntentGuilds,
+	"GUILD_ROLE_CREATE":           IntentGuilds,
+	"GUILD_ROLE_UPDATE":           IntentGuilds,
+	"GUILD_ROLE_DELETE":           IntentGuilds,
+	"CHANNEL_CREATE":              IntentGuilds,
+	"CHANNEL_UPDATE":              IntentGuilds,
+	"CHANNEL_DELETE":              IntentGuilds,
+	"CHANNEL_PINS_UPDATE":         IntentGuilds | IntentDirectMessages,
+	"THREAD_CREATE":               IntentGuilds,
+	"THREAD_UPDATE":               IntentGuilds,
+	"THREAD_DELETE":               IntentGuilds,
+	"THREAD_LIST_SYNC":            IntentGuilds,
+	"THREAD_MEMBER_UPDATE":        IntentGuilds,
+	"GUILD_MEMBER_ADD":            IntentGuildMembers,
+	"GUILD_MEMBER_UPDATE":         IntentGuildMembers,
+	"GUILD_MEMBER_REMOVE":         IntentGuildMembers,
+	"GUILD_BAN_ADD":               IntentGuildBans,
+	"GUILD_BAN_REMOVE":            IntentGuildBans,
+	"GUILD_EMOJIS_UPDATE":         IntentGuildEmojis,
+	"GUILD_INTEGRATIONS_UPDATE":   IntentGuildIntegrations,
+	"WEBHOOKS_UPDATE":             IntentGuildWebhooks,
+	"INVITE_CREATE":               IntentGuildInvites,
+	"INVITE_DELETE":               IntentGuildInvites,
+	"VOICE_STATE_UPDATE":          IntentGuildVoiceStates,
+	"PRESENCE_UPDATE":             IntentGuildPresences,
+	"MESSAGE_CREATE":              IntentGuildMessages | IntentDirectMessages,
+	"MESSAGE_UPDATE":              IntentGuildMessages | IntentDirectMessages,
+	"MESSAGE_DELETE":              IntentGuildMessages | IntentDirectMessages,
+	"MESSAGE_DELETE_BULK":         IntentGuildMessages,
+	"MESSAGE_REACTION_ADD":        IntentGuildMessageReactions | IntentDirectMessageReactions,
+	"MESSAGE_REACTION_REMOVE":     IntentGuildMessageReactions | IntentDirectMessageReactions,
+	"MESSAGE_REACTION_REMOVE_ALL": IntentGuildMessageReactions | IntentDirectMessageReactions,
+	"TYPING_START":                IntentGuildMessageTyping | IntentDirectMessageTyping,
+}
+
+// missingIntents lists the registered events that none of the configured intents enables. Intents are
+// not validated when none are configured, as Discord then sends every event.
+func (m *Client) missingIntents() (events []string) {
+	if m.conf.Intents == 0 {
+		return nil
+	}
+
+	m.evtMutex.RLock()
+	defer m.evtMutex.RUnlock()
+	for name := range m.trackedEvents {
+		if required, exists := EventIntents[name]; exists && required&m.conf.Intents == 0 {
+			events = append(events, name)
+		}
+	}
+	sort.Strings(events)
+	return events
+}
+
+// validateIntents warns about registered events that will never be received with the configured intents,
+// or fails when Config.StrictIntents is set.
+func (m *Client) validateIntents() error {
+	events := m.missingIntents()
+	if len(events) == 0 {
+		return nil
+	}
+
+	msg := "the configured intents do not enable the registered events: " + strings.Join(events, ", ")
+	if m.conf.StrictIntents {
+		return errors.New(msg)
+	}
+	logrus.Warn(msg)
+	return nil
+}
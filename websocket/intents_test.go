@@ -0,0 +1,55 @@
+package websocket
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestClient_MissingIntents(t *testing.T) {
+	m, _ := NewTestClient(&Config{
+		HTTPClient: &http.Client{},
+		Intents:    IntentGuilds | IntentDirectMessages,
+	}, &testWS{})
+	m.RegisterEvent("MESSAGE_CREATE")
+	m.RegisterEvent("GUILD_CREATE")
+	m.RegisterEvent("READY")
+	m.RegisterEvent("TYPING_START")
+	m.RegisterEvent("PRESENCE_UPDATE")
+
+	wants := []string{"PRESENCE_UPDATE", "TYPING_START"}
+	if got := m.missingIntents(); !reflect.DeepEqual(got, wants) {
+		t.Errorf("incorrect events without intents. Got %v, wants %v", got, wants)
+	}
+
+	m.conf.Intents = 0
+	if got := m.missingIntents(); len(got) != 0 {
+		t.Errorf("expected no validation without intents, got %v", got)
+	}
+}
+
+func TestClient_StrictIntents(t *testing.T) {
+	conn := &testWS{
+		opening:      make(chan interface{}, 2),
+		disconnected: true,
+	}
+	m, _ := NewTestClient(&Config{
+		Endpoint:      "sfkjsdlfsf",
+		HTTPClient:    &http.Client{},
+		Intents:       IntentGuilds,
+		StrictIntents: true,
+	}, conn)
+	m.RegisterEvent("MESSAGE_CREATE")
+
+	if err := m.Connect(); err == nil {
+		t.Error("expected Connect to fail when a registered event is not enabled by the intents")
+	}
+	if len(conn.opening) != 0 {
+		t.Error("expected no connection to be opened")
+	}
+
+	m.conf.Intents |= IntentGuildMessages
+	if err := m.Connect(); err != nil {
+		t.Error(err)
+	}
+}
@@ -3,24 +3,67 @@ package websocket
 import (
 	"bytes"
 	"compress/zlib"
+	"encoding/json"
 	"io"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/andersfylling/disgord/httd"
 )
 
-// discordPacketJSON is used when we need to fall back on the unmarshaler logic
+// GatewayPayload is the shape of every message sent over the gateway connection, in both directions. The
+// client uses its own optimized types internally, this one is for building and inspecting payloads, eg.
+// in tests or for uncommon gateway interactions.
+// https://discordapp.com/developers/docs/topics/gateway#payloads
+type GatewayPayload struct {
+	Op             uint            `json:"op"`
+	Data           json.RawMessage `json:"d"`
+	SequenceNumber uint            `json:"s,omitempty"` // only set for dispatched events
+	EventName      string          `json:"t,omitempty"` // only set for dispatched events
+}
+
+// NewGatewayPayload creates a payload for the operation code, with the data marshalled to JSON
+func NewGatewayPayload(op uint, data interface{}) (payload *GatewayPayload, err error) {
+	payload = &GatewayPayload{Op: op}
+	payload.Data, err = httd.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// ParseGatewayPayload parses a message received over the gateway connection. Compressed messages must be
+// decompressed first.
+func ParseGatewayPayload(data []byte) (payload *GatewayPayload, err error) {
+	payload = &GatewayPayload{}
+	if err = httd.Unmarshal(data, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// Unmarshal parses the data of the payload into v
+func (p *GatewayPayload) Unmarshal(v interface{}) error {
+	return httd.Unmarshal(p.Data, v)
+}
+
+// discordPacketJSON is used when we need to fall back on the unmarshaler logic. The data is kept as raw
+// JSON, as it can be an object, a scalar such as the bool of op 9, or null.
 type discordPacketJSON struct {
-	Op             uint   `json:"op"`
-	Data           []byte `json:"d"`
-	SequenceNumber uint   `json:"s"`
-	EventName      string `json:"t"`
+	Op             uint            `json:"op"`
+	Data           json.RawMessage `json:"d"`
+	SequenceNumber uint            `json:"s"`
+	EventName      string          `json:"t"`
 }
 
 func (p *discordPacketJSON) CopyOverTo(packet *discordPacket) {
 	packet.Op = p.Op
 	packet.Data = p.Data
+	if len(packet.Data) == 0 {
+		// same as the custom unmarshaler, which gives a null or missing d as null
+		packet.Data = []byte("null")
+	}
 	packet.SequenceNumber = p.SequenceNumber
 	packet.EventName = p.EventName
 }
@@ -33,16 +76,37 @@ type discordPacket struct {
 	EventName      string `json:"t"`
 }
 
+// packetPool reuses the packets between incoming messages, as every event would otherwise allocate one
+var packetPool = sync.Pool{
+	New: func() interface{} {
+		return &discordPacket{}
+	},
+}
+
+func getPacket() *discordPacket {
+	return packetPool.Get().(*discordPacket)
+}
+
+// putPacket resets the packet and returns it to the pool. The packet must not be referenced afterwards,
+// however the Data slice can be kept as it is never reused.
+func putPacket(p *discordPacket) {
+	*p = discordPacket{}
+	packetPool.Put(p)
+}
+
 // UnmarshalJSON see interface json.Unmarshaler
 func (p *discordPacket) UnmarshalJSON(data []byte) (err error) {
 	var i int
 
+	// the data is sliced out until the closing brace
+	data = bytes.TrimRight(data, " \t\r\n")
+
 	// t
 	t := []byte{
 		'{', '"', 't', '"', ':',
 	}
 	for i = range t {
-		if t[i] != data[i] {
+		if len(data) <= len(t) || t[i] != data[i] {
 			evt := discordPacketJSON{}
 			err = httd.Unmarshal(data, &evt)
 			evt.CopyOverTo(p)
@@ -141,6 +205,27 @@ func (p *discordPacket) UnmarshalJSON(data []byte) (err error) {
 type clientPacket struct {
 	Op   uint        `json:"op"`
 	Data interface{} `json:"d"`
+
+	// command is the name given to Client#Emit, see Config.OnCommand
+	command string
+
+	// sent receives the result of writing the packet, see Client#EmitSync
+	sent chan error
+}
+
+// closeSignal is the data of the internal Close command, which tells the emitter to close the connection
+// instead of writing a packet
+type closeSignal struct {
+	// resumable keeps the Discord session valid, see resumableCloser
+	resumable bool
+}
+
+// offlinePresence is the presence sent before disconnecting, see Config#OfflineOnDisconnect
+type offlinePresence struct {
+	Since  *uint       `json:"since"`
+	Game   interface{} `json:"game"`
+	Status string      `json:"status"`
+	AFK    bool        `json:"afk"`
 }
 
 type traceData struct {
@@ -152,11 +237,110 @@ type helloPacket struct {
 	traceData
 }
 
-type readyPacket struct {
-	SessionID string `json:"session_id"`
+// Ready is the data of the READY event, sent by Discord once a new session is established. Fields unknown
+// to this version are ignored, so newer gateway versions can add to it. The IDs are given as strings, to
+// keep the socket layer free of the snowflake type.
+// https://discordapp.com/developers/docs/topics/gateway#ready
+type Ready struct {
+	Version          int               `json:"v"`
+	SessionID        string            `json:"session_id"`
+	ResumeGatewayURL string            `json:"resume_gateway_url,omitempty"` // use when resuming, on newer gateway versions
+	User             *ReadyUser        `json:"user"`
+	Guilds           []*ReadyGuild     `json:"guilds"` // the guilds are unavailable until their GUILD_CREATE
+	Application      *ReadyApplication `json:"application,omitempty"`
+	Shard            *[2]uint          `json:"shard,omitempty"` // shard ID and shard count
+	Trace            []string          `json:"_trace"`
+}
+
+func (r *Ready) copy() *Ready {
+	c := *r
+	if r.User != nil {
+		user := *r.User
+		c.User = &user
+	}
+	if r.Guilds != nil {
+		c.Guilds = make([]*ReadyGuild, len(r.Guilds))
+		for i := range r.Guilds {
+			if r.Guilds[i] == nil {
+				continue
+			}
+			guild := *r.Guilds[i]
+			c.Guilds[i] = &guild
+		}
+	}
+	if r.Application != nil {
+		application := *r.Application
+		c.Application = &application
+	}
+	if r.Shard != nil {
+		shard := *r.Shard
+		c.Shard = &shard
+	}
+	if r.Trace != nil {
+		c.Trace = make([]string, len(r.Trace))
+		copy(c.Trace, r.Trace)
+	}
+	return &c
+}
+
+// ReadyUser is the bot user of the session
+type ReadyUser struct {
+	ID            string `json:"id"`
+	Username      string `json:"username"`
+	Discriminator string `json:"discriminator"`
+	Bot           bool   `json:"bot"`
+}
+
+// ReadyGuild is a guild of the session, which is sent in full by a later GUILD_CREATE event
+type ReadyGuild struct {
+	ID          string `json:"id"`
+	Unavailable bool   `json:"unavailable"`
+}
+
+// ReadyApplication is the application of the bot
+type ReadyApplication struct {
+	ID    string `json:"id"`
+	Flags uint   `json:"flags"`
+}
+
+type resumedPacket struct {
 	traceData
 }
 
+// IdentifyPayload is the data of the identify command, see Config.BeforeIdentify
+// https://discordapp.com/developers/docs/topics/gateway#identify
+type IdentifyPayload struct {
+	Token          string                    `json:"token"`
+	Properties     IdentifyPayloadProperties `json:"properties"`
+	Compress       bool                      `json:"compress"`
+	LargeThreshold uint                      `json:"large_threshold"`
+	Shard          *[2]uint                  `json:"shard,omitempty"`
+	Presence       interface{}               `json:"presence,omitempty"`
+	Intents        Intent                    `json:"intents,omitempty"`
+}
+
+// IdentifyPayloadProperties describes the connecting client to Discord
+type IdentifyPayloadProperties struct {
+	OS      string `json:"$os"`
+	Browser string `json:"$browser"`
+	Device  string `json:"$device"`
+}
+
+type resumePacket struct {
+	Token          string `json:"token"`
+	SessionID      string `json:"session_id"`
+	SequenceNumber *uint  `json:"seq"`
+}
+
+// lastSequenceNumber gives the sequence number as it is sent in heartbeats and resumes. Discord expects null
+// until a dispatch event has been received, as sequence numbers start at 1.
+func lastSequenceNumber(seq uint) *uint {
+	if seq == 0 {
+		return nil
+	}
+	return &seq
+}
+
 // decompressBytes decompresses a binary message
 func decompressBytes(input []byte) (output []byte, err error) {
 	b := bytes.NewReader(input)
@@ -1,11 +1,14 @@
 package websocket
 
 import (
+	"bytes"
 	"io/ioutil"
+	"net/http"
 	"strconv"
 	"testing"
 
 	"github.com/andersfylling/disgord/httd"
+	"github.com/andersfylling/disgord/websocket/opcode"
 )
 
 func getAllJSONFiles(t *testing.T) (files [][]byte) {
@@ -53,6 +56,60 @@ func TestDiscordEvent_CustomUnmarshaller(t *testing.T) {
 	})
 }
 
+func TestDiscordEvent_NullAndScalarData(t *testing.T) {
+	testCases := []struct {
+		file string
+		op   uint
+		data string
+	}{
+		{"op1-heartbeat", opcode.Heartbeat, `null`},
+		{"op9-invalid-session", opcode.InvalidSession, `false`},
+		{"op9-invalid-session-reordered", opcode.InvalidSession, `true`},
+		{"op10-hello", opcode.Hello, `{"heartbeat_interval":41250,"_trace":["gateway-prd-main-abcd"]}`},
+		{"diff-structure", opcode.HeartbeatAck, `null`},
+	}
+
+	for _, tc := range testCases {
+		data, err := ioutil.ReadFile("testdata/" + tc.file + ".json")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		evt := discordPacket{}
+		if err = httd.Unmarshal(data, &evt); err != nil {
+			t.Errorf("%s: %s", tc.file, err)
+			continue
+		}
+		if evt.Op != tc.op {
+			t.Errorf("%s: incorrect op. Got %d, wants %d", tc.file, evt.Op, tc.op)
+		}
+		if string(evt.Data) != tc.data {
+			t.Errorf("%s: incorrect data. Got %s, wants %s", tc.file, string(evt.Data), tc.data)
+		}
+	}
+
+	t.Run("scalar data", func(t *testing.T) {
+		evt := discordPacket{}
+		if err := httd.Unmarshal([]byte(`{"t":null,"s":null,"op":9,"d":true}`), &evt); err != nil {
+			t.Fatal(err)
+		}
+		var resumable bool
+		if err := httd.Unmarshal(evt.Data, &resumable); err != nil || !resumable {
+			t.Errorf("expected the op 9 data to be true, got %s. err: %v", string(evt.Data), err)
+		}
+	})
+
+	t.Run("short input", func(t *testing.T) {
+		evt := discordPacket{}
+		if err := evt.UnmarshalJSON([]byte(`{}`)); err != nil {
+			t.Error(err)
+		}
+		if string(evt.Data) != `null` {
+			t.Errorf("expected missing data to be null, got %s", string(evt.Data))
+		}
+	})
+}
+
 func BenchmarkEvent_CustomUnmarshal_smallJSON(b *testing.B) {
 	data, err := ioutil.ReadFile("testdata/small.json")
 	if err != nil {
@@ -96,3 +153,133 @@ func BenchmarkEvent_Unmarshal_largeJSON(b *testing.B) {
 		httd.Unmarshal(data, &evt)
 	}
 }
+
+// packetSink makes the packets escape to the heap, as they do when passed to the operation handler
+var packetSink *discordPacket
+
+func BenchmarkEvent_CustomUnmarshal_allocate(b *testing.B) {
+	data, err := ioutil.ReadFile("testdata/small.json")
+	if err != nil {
+		return
+	}
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		evt := &discordPacket{}
+		evt.UnmarshalJSON(data)
+		packetSink = evt
+	}
+}
+
+func BenchmarkEvent_CustomUnmarshal_pool(b *testing.B) {
+	data, err := ioutil.ReadFile("testdata/small.json")
+	if err != nil {
+		return
+	}
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		evt := getPacket()
+		evt.UnmarshalJSON(data)
+		packetSink = evt
+		putPacket(evt)
+	}
+}
+
+func TestSequenceNumberPayloads(t *testing.T) {
+	packets := []struct {
+		file string
+		pk   *clientPacket
+	}{
+		{"heartbeat_initial", &clientPacket{Op: opcode.Heartbeat, Data: lastSequenceNumber(0)}},
+		{"heartbeat", &clientPacket{Op: opcode.Heartbeat, Data: lastSequenceNumber(42)}},
+		{"resume_initial", &clientPacket{Op: opcode.Resume, Data: &resumePacket{
+			Token:          "my_token",
+			SessionID:      "session_id_i_stored",
+			SequenceNumber: lastSequenceNumber(0),
+		}}},
+		{"resume", &clientPacket{Op: opcode.Resume, Data: &resumePacket{
+			Token:          "my_token",
+			SessionID:      "session_id_i_stored",
+			SequenceNumber: lastSequenceNumber(1337),
+		}}},
+	}
+	for i := range packets {
+		wants, err := ioutil.ReadFile("testdata/" + packets[i].file + ".json")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := httd.Marshal(packets[i].pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, bytes.TrimSpace(wants)) {
+			t.Errorf("incorrect %s payload. Got %s, wants %s", packets[i].file, string(data), string(wants))
+		}
+	}
+}
+
+// reusedBufferWS reuses the same read buffer for every message
+type reusedBufferWS struct {
+	*testWS
+	buffer []byte
+}
+
+func (g *reusedBufferWS) Read() (packet []byte, err error) {
+	packet, err = g.testWS.Read()
+	if err != nil {
+		return
+	}
+	g.buffer = append(g.buffer[:0], packet...)
+	return g.buffer, nil
+}
+
+func TestClient_EventDataIsCopied(t *testing.T) {
+	conn := &reusedBufferWS{
+		testWS: &testWS{reading: make(chan []byte)},
+	}
+	m, _ := NewTestClient(&Config{
+		HTTPClient: &http.Client{},
+	}, conn)
+	m.RegisterEvent("TEST")
+	defer close(conn.reading)
+
+	go func() {
+		conn.reading <- []byte(`{"t":"TEST","s":1,"op":0,"d":{"id":"first"}}`)
+		conn.reading <- []byte(`{"t":"TEST","s":2,"op":0,"d":{"id":"other"}}`)
+	}()
+
+	first := <-m.EventChan()
+	wants := string(first.Data)
+	<-m.EventChan()
+	if string(first.Data) != wants {
+		t.Errorf("event data changed when the read buffer was reused. Got %s, wants %s", string(first.Data), wants)
+	}
+}
+
+func TestGatewayPayload(t *testing.T) {
+	payload, err := ParseGatewayPayload([]byte(`{"t":"TEST","s":3,"op":0,"d":{"id":"1"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload.EventName != "TEST" || payload.SequenceNumber != 3 || payload.Op != opcode.DiscordEvent {
+		t.Errorf("incorrect payload: %+v", payload)
+	}
+	data := struct {
+		ID string `json:"id"`
+	}{}
+	if err = payload.Unmarshal(&data); err != nil || data.ID != "1" {
+		t.Errorf("incorrect payload data %s, err: %v", string(payload.Data), err)
+	}
+
+	payload, err = NewGatewayPayload(opcode.Heartbeat, lastSequenceNumber(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := httd.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wants := `{"op":1,"d":42}`; string(encoded) != wants {
+		t.Errorf("incorrect payload JSON. Got %s, wants %s", string(encoded), wants)
+	}
+}
@@ -0,0 +1,109 @@
+package websocket
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultPauseBufferSize is used when Config.PauseBufferSize is not set
+const defaultPauseBufferSize = 1000
+
+// pauseGate holds back events from the application while the client is paused. The socket layer keeps
+// processing operation codes and heartbeats, so the session stays alive.
+type pauseGate struct {
+	sync.Mutex
+	paused  bool
+	pending []*Event
+
+	// delivery is held while events are handed to the application, to keep them in order
+	delivery sync.Mutex
+}
+
+// Pause stops events from being delivered to the event channel and the subscribers, without
+// disconnecting from the gateway. Events received while paused are buffered, up to
+// Config.PauseBufferSize, and delivered in order once Client#Resume is called. Events that do not
+// fit in the buffer are dropped.
+func (m *Client) Pause() {
+	m.pause.Lock()
+	m.pause.paused = true
+	m.pause.Unlock()
+}
+
+// Resume continues the event delivery after Client#Pause, starting with the buffered events.
+func (m *Client) Resume() {
+	m.pause.Lock()
+	m.pause.paused = false
+	m.pause.Unlock()
+
+	go func() {
+		m.pause.delivery.Lock()
+		m.deliverPending()
+		m.pause.delivery.Unlock()
+	}()
+}
+
+// Paused checks if the event delivery is paused
+func (m *Client) Paused() bool {
+	m.pause.Lock()
+	defer m.pause.Unlock()
+	return m.pause.paused
+}
+
+// deliver hands the event to the application, or buffers it while paused
+func (m *Client) deliver(evt *Event) {
+	m.pause.delivery.Lock()
+	defer m.pause.delivery.Unlock()
+
+	// events buffered during a pause must be delivered first
+	if m.deliverPending() {
+		m.dispatch(evt)
+		return
+	}
+
+	m.pause.Lock()
+	defer m.pause.Unlock()
+	limit := int(m.conf.PauseBufferSize)
+	if limit == 0 {
+		limit = defaultPauseBufferSize
+	}
+	if len(m.pause.pending) < limit {
+		m.pause.pending = append(m.pause.pending, evt)
+	} else {
+		logrus.Warnf("event delivery is paused and the buffer is full, dropping event %s", evt.Name)
+	}
+}
+
+// deliverPending dispatches the events buffered during a pause. Returns false if the client was paused
+// again before every event could be delivered. Must be called while holding the delivery lock.
+func (m *Client) deliverPending() bool {
+	for {
+		m.pause.Lock()
+		if m.pause.paused {
+			m.pause.Unlock()
+			return false
+		}
+		if len(m.pause.pending) == 0 {
+			m.pause.pending = nil
+			m.pause.Unlock()
+			return true
+		}
+		evt := m.pause.pending[0]
+		m.pause.pending = m.pause.pending[1:]
+		m.pause.Unlock()
+
+		m.dispatch(evt)
+	}
+}
+
+func (m *Client) dispatch(evt *Event) {
+	m.subscribers.dispatch(evt)
+	if evt.subscribersOnly {
+		return
+	}
+	if c, exists := m.typed.get(evt.eventName()); exists {
+		m.send(c, evt)
+		return
+	}
+	m.send(m.eventChan, evt)
+}
@@ -0,0 +1,48 @@
+package websocket
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestClient_PauseResume(t *testing.T) {
+	m, _ := NewTestClient(&Config{
+		HTTPClient:      &http.Client{},
+		PauseBufferSize: 3,
+	}, &testWS{})
+
+	m.Pause()
+	if !m.Paused() {
+		t.Fatal("expected client to be paused")
+	}
+	for i := 1; i <= 5; i++ {
+		m.deliver(&Event{Name: strconv.Itoa(i)})
+	}
+
+	done := make(chan interface{})
+	defer close(done)
+	go func() {
+		select {
+		case <-m.EventChan():
+			t.Error("event was delivered while paused")
+		case <-done:
+		}
+	}()
+	<-time.After(10 * time.Millisecond)
+	done <- true
+
+	m.Resume()
+	go m.deliver(&Event{Name: "6"})
+	for _, name := range []string{"1", "2", "3", "6"} {
+		select {
+		case evt := <-m.EventChan():
+			if evt.Name != name {
+				t.Errorf("incorrect event order. Got %s, wants %s", evt.Name, name)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event %s was not delivered after resume", name)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package websocket
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+func newRandom(r *rand.Rand) *lockedRand {
+	if r == nil {
+		var seed int64
+		if err := binary.Read(crand.Reader, binary.LittleEndian, &seed); err != nil {
+			seed = time.Now().UnixNano()
+		}
+		r = rand.New(rand.NewSource(seed))
+	}
+	return &lockedRand{r: r}
+}
+
+// lockedRand makes a *rand.Rand safe for concurrent use, as the client uses it from several go routines
+type lockedRand struct {
+	sync.Mutex
+	r *rand.Rand
+}
+
+func (l *lockedRand) Intn(n int) int {
+	l.Lock()
+	defer l.Unlock()
+	return l.r.Intn(n)
+}
@@ -0,0 +1,20 @@
+package websocket
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestNewRandom(t *testing.T) {
+	a := newRandom(rand.New(rand.NewSource(42)))
+	b := newRandom(rand.New(rand.NewSource(42)))
+	for i := 0; i < 10; i++ {
+		if a.Intn(100) != b.Intn(100) {
+			t.Fatal("expected the same seed to give the same sequence")
+		}
+	}
+
+	if r := newRandom(nil); r.r == nil {
+		t.Error("expected a default random source")
+	}
+}
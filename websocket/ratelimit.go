@@ -7,6 +7,11 @@ import (
 	"github.com/andersfylling/disgord/websocket/cmd"
 )
 
+// globalRatelimitBucket is the name of the bucket shared by every command, see Client#RateLimitRemaining
+const globalRatelimitBucket = "global"
+
+// newRatelimiter creates the gateway command rate limiter. Every command counts towards the global bucket,
+// while some commands, such as presence updates, have an additional and stricter bucket of their own.
 func newRatelimiter() ratelimiter {
 	rl := ratelimiter{
 		buckets: map[string]rlBucket{},
@@ -43,6 +48,27 @@ func (b *rlBucket) Blocked() bool {
 	return time.Now().UnixNano()-last.unix <= b.duration
 }
 
+// Remaining is the number of requests that can be made before the bucket is blocked
+func (b *rlBucket) Remaining() (remaining uint) {
+	now := time.Now().UnixNano()
+	for i := range b.entries {
+		if now-b.entries[i].unix > b.duration {
+			remaining++
+		}
+	}
+	return
+}
+
+// RetryAfter is how long until the bucket has room for another request
+func (b *rlBucket) RetryAfter() time.Duration {
+	last := b.entries[len(b.entries)-1]
+	wait := last.unix + b.duration - time.Now().UnixNano()
+	if wait < 0 {
+		return 0
+	}
+	return time.Duration(wait)
+}
+
 func (b *rlBucket) Insert(cmd string) {
 	// TODO: we could shift the last valid element to the bottom and then not shift on every insert
 	// b.entries = append(b.entries[1:], b.entries[:len(b.entries)-2])
@@ -65,19 +91,46 @@ func (rl *ratelimiter) Request(command string) (accepted bool) {
 	rl.Lock()
 	defer rl.Unlock()
 
-	// global
+	// a rejected command must not use any of the budgets, so every bucket is checked before inserting
 	if rl.global.Blocked() {
 		return false
 	}
+	bucket, exists := rl.buckets[command]
+	if exists && bucket.Blocked() {
+		return false
+	}
+
 	rl.global.Insert(command)
+	if exists {
+		bucket.Insert(command)
+	}
+	return true
+}
+
+// RetryAfter gives how long until the command is no longer rate limited, or 0 if it can be sent right now
+func (rl *ratelimiter) RetryAfter(command string) time.Duration {
+	rl.RLock()
+	defer rl.RUnlock()
 
-	// bucket specific
+	wait := rl.global.RetryAfter()
 	if bucket, exists := rl.buckets[command]; exists {
-		if bucket.Blocked() {
-			return false
+		if bucketWait := bucket.RetryAfter(); bucketWait > wait {
+			wait = bucketWait
 		}
-		bucket.Insert(command)
 	}
+	return wait
+}
 
-	return true
+// Remaining gives the number of commands that can be sent right now for each bucket
+func (rl *ratelimiter) Remaining() (remaining map[string]uint) {
+	rl.RLock()
+	defer rl.RUnlock()
+
+	remaining = map[string]uint{
+		globalRatelimitBucket: rl.global.Remaining(),
+	}
+	for command, bucket := range rl.buckets {
+		remaining[command] = bucket.Remaining()
+	}
+	return
 }
@@ -1,8 +1,11 @@
 package websocket
 
 import (
+	"context"
 	"testing"
 	"time"
+
+	"github.com/andersfylling/disgord/websocket/cmd"
 )
 
 func TestRlBucket(t *testing.T) {
@@ -56,3 +59,86 @@ func TestRlBucket(t *testing.T) {
 
 	})
 }
+
+func TestRatelimiter_Buckets(t *testing.T) {
+	rl := newRatelimiter()
+	rl.global = newRatelimitBucket(3, 60)
+	rl.buckets[cmd.UpdateStatus] = newRatelimitBucket(1, 60)
+
+	if !rl.Request(cmd.UpdateStatus) {
+		t.Fatal("expected the first presence update to be accepted")
+	}
+	for i := 0; i < 5; i++ {
+		if rl.Request(cmd.UpdateStatus) {
+			t.Fatal("expected presence updates to be rate limited by their own bucket")
+		}
+	}
+
+	remaining := rl.Remaining()
+	if remaining[globalRatelimitBucket] != 2 {
+		t.Errorf("expected rejected presence updates to not use the global budget. Got %d remaining, wants 2", remaining[globalRatelimitBucket])
+	}
+	if remaining[cmd.UpdateStatus] != 0 {
+		t.Errorf("expected no presence updates remaining, got %d", remaining[cmd.UpdateStatus])
+	}
+
+	for i := 0; i < 2; i++ {
+		if !rl.Request(cmd.RequestGuildMembers) {
+			t.Fatal("expected other commands to be accepted")
+		}
+	}
+	if rl.Request(cmd.RequestGuildMembers) {
+		t.Error("expected the global bucket to be blocked")
+	}
+}
+
+func TestClient_EmitWait(t *testing.T) {
+	m := &Client{
+		conf:     &Config{},
+		shutdown: make(chan interface{}),
+		emitChan: make(chan *clientPacket),
+		ratelimit: ratelimiter{
+			buckets: map[string]rlBucket{
+				cmd.UpdateStatus: newRatelimitBucketNano(1, int64(50*time.Millisecond)),
+			},
+			global: newRatelimitBucket(120, 60),
+		},
+		random:       newRandom(nil),
+		disconnected: true,
+	}
+	defer close(m.shutdown)
+	m.setDisconnected(false)
+	m.stateMutex.Lock()
+	m.setReady(true)
+	m.stateMutex.Unlock()
+	go func() {
+		for {
+			select {
+			case <-m.emitChan:
+			case <-m.shutdown:
+				return
+			}
+		}
+	}()
+
+	if err := m.EmitWait(context.Background(), cmd.UpdateStatus, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Emit(cmd.UpdateStatus, nil); err != ErrRateLimited {
+		t.Fatalf("expected the command to be rate limited, got %v", err)
+	}
+
+	start := time.Now()
+	if err := m.EmitWait(context.Background(), cmd.UpdateStatus, nil); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < 30*time.Millisecond {
+		t.Errorf("expected EmitWait to wait for the rate limit, waited %s", waited)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if err := m.EmitWait(ctx, cmd.UpdateStatus, nil); err != context.DeadlineExceeded {
+		t.Errorf("expected the context error, got %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package websocket
+
+import "sync"
+
+// newEventReplay creates a ring buffer holding the last `size` events. A size of 0 disables the buffer.
+func newEventReplay(size uint) *eventReplay {
+	if size == 0 {
+		return nil
+	}
+
+	return &eventReplay{
+		events: make([]*Event, size),
+	}
+}
+
+// eventReplay keeps the most recent events dispatched by the socket layer, such that consumers attaching after
+// Connect can catch up on the initial READY/GUILD_CREATE burst.
+type eventReplay struct {
+	sync.Mutex
+	events []*Event
+	next   int
+	full   bool
+}
+
+func (r *eventReplay) add(evt *Event) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.events[r.next] = evt
+	r.next++
+	if r.next == len(r.events) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// list returns a copy of the buffered events, ordered from oldest to newest.
+func (r *eventReplay) list() (events []*Event) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.full {
+		events = make([]*Event, 0, len(r.events))
+		events = append(events, r.events[r.next:]...)
+	}
+	events = append(events, r.events[:r.next]...)
+	return
+}
@@ -0,0 +1,31 @@
+package websocket
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestEventReplay(t *testing.T) {
+	if r := newEventReplay(0); r != nil {
+		t.Error("expected a replay buffer of size 0 to be disabled")
+	}
+
+	r := newEventReplay(3)
+	if len(r.list()) != 0 {
+		t.Error("expected empty replay buffer")
+	}
+
+	for i := 0; i < 5; i++ {
+		r.add(&Event{Name: strconv.Itoa(i)})
+	}
+
+	events := r.list()
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+	for i, name := range []string{"2", "3", "4"} {
+		if events[i].Name != name {
+			t.Errorf("incorrect order. At index %d, got %s, wants %s", i, events[i].Name, name)
+		}
+	}
+}
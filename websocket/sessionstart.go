@@ -0,0 +1,108 @@
+package websocket
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultSessionStartReserve is used when Config.SessionStartReserve is not set
+const defaultSessionStartReserve = 5
+
+// sessionStartPeriod is how often Discord resets the session start limit, counting from the reset time
+// given by Get Gateway Bot
+const sessionStartPeriod = 24 * time.Hour
+
+func newSessionStartGuard(limit SessionStartLimit, reserve uint) *sessionStartGuard {
+	g := &sessionStartGuard{
+		reserve: reserve,
+	}
+	g.update(limit)
+	return g
+}
+
+// sessionStartGuard keeps track of the session starts left for a bot token, and holds back identify
+// commands once only the reserve is left. Running out of session starts gets the token banned from
+// identifying until the limit resets, which is easy to do in a reconnect storm after an outage.
+type sessionStartGuard struct {
+	sync.Mutex
+	total     uint
+	remaining uint
+	reserve   uint
+	resetAt   time.Time
+}
+
+var _ IdentifyGate = (*sessionStartGuard)(nil)
+
+// update replaces the tracked budget with the limit given by Get Gateway Bot
+func (g *sessionStartGuard) update(limit SessionStartLimit) {
+	g.Lock()
+	defer g.Unlock()
+
+	g.total = limit.Total
+	g.remaining = limit.Remaining
+	g.resetAt = time.Now().Add(time.Duration(limit.ResetAfter) * time.Millisecond)
+}
+
+// Wait blocks until the limit resets when only the reserve is left, or the context is done. Every call
+// that is let through counts as a session start.
+func (g *sessionStartGuard) Wait(ctx context.Context, shardID uint) error {
+	g.Lock()
+	for {
+		now := time.Now()
+		if !now.Before(g.resetAt) {
+			periods := now.Sub(g.resetAt)/sessionStartPeriod + 1
+			g.resetAt = g.resetAt.Add(periods * sessionStartPeriod)
+			g.remaining = g.total
+		}
+
+		// a reserve of the whole budget would block forever
+		reserve := g.reserve
+		if g.total > 0 && reserve >= g.total {
+			reserve = g.total - 1
+		}
+		if g.remaining > reserve {
+			g.remaining--
+			g.Unlock()
+			return nil
+		}
+
+		wait := g.resetAt.Sub(now)
+		remaining := g.remaining
+		g.Unlock()
+
+		logrus.Error("only " + strconv.Itoa(int(remaining)) + " session starts remain for the bot token, " +
+			"shard " + strconv.Itoa(int(shardID)) + " waits " + wait.String() + " for the limit to reset before identifying")
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		g.Lock()
+	}
+}
+
+// SetSessionStartLimit updates the session start limit of the bot token, as given by Get Gateway Bot. See
+// Config.SessionStartLimit. Shards of a ShardManager using the same bot token share the limit, so updating
+// it for one shard updates it for all of them. A shard whose token had no limit when it was created gets a
+// limit of its own.
+func (m *Client) SetSessionStartLimit(limit SessionStartLimit) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.sessionStarts != nil {
+		m.sessionStarts.update(limit)
+		return
+	}
+	m.sessionStarts = newSessionStartGuard(limit, sessionStartReserve(m.conf))
+}
+
+func sessionStartReserve(conf *Config) uint {
+	if conf.SessionStartReserve == 0 {
+		return defaultSessionStartReserve
+	}
+	return conf.SessionStartReserve
+}
@@ -0,0 +1,118 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSessionStartGuard(t *testing.T) {
+	g := newSessionStartGuard(SessionStartLimit{Total: 10, Remaining: 3, ResetAfter: 50}, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if err := g.Wait(ctx, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if since := time.Since(start); since > 40*time.Millisecond {
+		t.Errorf("identify was held back while session starts remained. Took %s", since)
+	}
+
+	// only the reserve is left, so the next identify waits for the reset
+	if err := g.Wait(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if since := time.Since(start); since < 50*time.Millisecond {
+		t.Errorf("identify was not held back until the reset. Took %s", since)
+	}
+	g.Lock()
+	remaining := g.remaining
+	g.Unlock()
+	if remaining != 9 {
+		t.Errorf("expected the budget to be reset. Got %d remaining, wants 9", remaining)
+	}
+
+	t.Run("aborted", func(t *testing.T) {
+		g := newSessionStartGuard(SessionStartLimit{Total: 10, Remaining: 0, ResetAfter: 3600000}, 1)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if err := g.Wait(ctx, 0); err == nil {
+			t.Error("expected the wait to be aborted")
+		}
+	})
+
+	t.Run("reserve of the whole budget", func(t *testing.T) {
+		g := newSessionStartGuard(SessionStartLimit{Total: 2, Remaining: 2}, 5)
+		if err := g.Wait(context.Background(), 0); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestClient_SessionStartLimit(t *testing.T) {
+	m, err := NewClient(&Config{
+		HTTPClient:        &http.Client{},
+		SessionStartLimit: &SessionStartLimit{Total: 1000, Remaining: 5, ResetAfter: 3600000},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- m.waitForIdentifyGate()
+	}()
+	select {
+	case <-done:
+		t.Fatal("identify was not held back with only the reserve left")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// the waiting identify keeps waiting for the old reset, but later identifies use the new limit
+	m.SetSessionStartLimit(SessionStartLimit{Total: 1000, Remaining: 1000, ResetAfter: 0})
+	m.sessionStarts.Lock()
+	remaining := m.sessionStarts.remaining
+	m.sessionStarts.Unlock()
+	if remaining != 1000 {
+		t.Errorf("expected the limit to be updated. Got %d remaining, wants 1000", remaining)
+	}
+
+	close(m.shutdown)
+	if err = <-done; err == nil {
+		t.Error("expected the wait to be aborted on shutdown")
+	}
+}
+
+func TestShardManager_SessionStartLimit(t *testing.T) {
+	manager, err := NewShardManager(&ShardManagerConfig{
+		Config: &Config{
+			Token:             "main",
+			HTTPClient:        &http.Client{},
+			SessionStartLimit: &SessionStartLimit{Total: 1000, Remaining: 1000},
+		},
+		ShardCount:              5,
+		ShardTokens:             map[uint]string{2: "other", 3: "other", 4: "unlimited"},
+		TokenSessionStartLimits: map[string]SessionStartLimit{"other": {Total: 1000, Remaining: 10}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shards := manager.Shards()
+	if shards[0].sessionStarts == nil || shards[0].sessionStarts != shards[1].sessionStarts {
+		t.Error("expected the shards of the template token to share the session start limit")
+	}
+	if shards[2].sessionStarts == nil || shards[2].sessionStarts != shards[3].sessionStarts {
+		t.Error("expected the shards of an override token to share the session start limit of the token")
+	}
+	if shards[2].sessionStarts == shards[0].sessionStarts || shards[2].sessionStarts.remaining != 10 {
+		t.Error("expected the shards of an override token to not use the session start limit of the template")
+	}
+	if shards[4].sessionStarts != nil {
+		t.Error("expected the shard of a token without a session start limit to not be guarded")
+	}
+}
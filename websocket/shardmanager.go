@@ -0,0 +1,547 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andersfylling/disgord/websocket/cmd"
+)
+
+// Discord only accepts one identify command every 5 seconds per bot token.
+// https://discordapp.com/developers/docs/topics/gateway#identifying
+const identifyInterval = 5 * time.Second
+
+// defaultDisconnectTimeout is used when ShardManagerConfig.DisconnectTimeout is not set
+const defaultDisconnectTimeout = 10 * time.Second
+
+// IdentifyGate is asked for permission before every identify command. By default shards of the same
+// ShardManager share a gate per bot token, which spaces out their identify commands. Implement it, eg.
+// with Redis or a file lock, to coordinate identify commands between processes using the same bot token,
+// such as during deploys where the old and new process overlap.
+type IdentifyGate interface {
+	// Wait blocks until the shard is allowed to identify, or the context is done, in which case the
+	// context error is returned. Any error stops the identify command from being sent.
+	Wait(ctx context.Context, shardID uint) error
+}
+
+func newIdentifyLimiter() *identifyLimiter {
+	return &identifyLimiter{
+		interval: identifyInterval,
+	}
+}
+
+// identifyLimiter spaces out the identify commands sent with the same bot token. Every token has its own
+// identify budget, so shards using different tokens must never share a limiter.
+type identifyLimiter struct {
+	sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+var _ IdentifyGate = (*identifyLimiter)(nil)
+
+// Wait blocks until the caller is allowed to identify, or the context is done
+func (l *identifyLimiter) Wait(ctx context.Context, shardID uint) error {
+	l.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.Unlock()
+
+	select {
+	case <-time.After(time.Until(slot)):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ShardManagerConfig holds the settings for every shard spawned by the ShardManager
+type ShardManagerConfig struct {
+	// Config is used as a template for each shard. ShardID and ShardCount are set by the manager.
+	Config *Config
+
+	// ShardCount is the total number of shards
+	ShardCount uint
+
+	// ShardRange limits the manager to the shards in the range, for bots that split their shards over
+	// several processes. The shards still identify with the total shard count. Defaults to every shard.
+	// See ValidateShardRanges to check that the ranges of every process cover the shards exactly once.
+	ShardRange *ShardRange
+
+	// ShardTokens overrides the bot token for specific shards, using the shard ID as key. This allows
+	// bots that split their guilds over several bot applications to run every shard in one process.
+	// Each token gets its own identify budget.
+	ShardTokens map[uint]string
+
+	// TokenSessionStartLimits gives the session start limit of the override tokens, using the token as key,
+	// as given by Get Gateway Bot for each token. The shards using the same token share the limit, see
+	// Config.SessionStartLimit. Shards with a token that has no limit do not guard their session starts.
+	TokenSessionStartLimits map[string]SessionStartLimit
+
+	// ShardIdentify overrides the identify properties for specific shards, using the shard ID as key.
+	// Otherwise the properties of the template config are used, where ShardIDPlaceholder is replaced
+	// by the shard ID.
+	ShardIdentify map[uint]IdentifyProperties
+
+	// DisconnectTimeout is how long ShardManager#Disconnect waits for the shards to disconnect.
+	// Defaults to 10 seconds.
+	DisconnectTimeout time.Duration
+
+	// PresenceWindow coalesces the presence updates given to ShardManager#UpdateStatusAll, such that each
+	// shard sends at most one update per window: the latest one. Frequent fleet wide updates then never trip
+	// the presence rate limit. Defaults to 0, which sends every update right away.
+	PresenceWindow time.Duration
+}
+
+// ShardIDPlaceholder is replaced by the shard ID in the Browser and Device of the config template, such
+// that the gateway logs of each shard can be told apart. Eg. "my-bot/shard-{shard_id}".
+const ShardIDPlaceholder = "{shard_id}"
+
+// IdentifyProperties are the identify settings that can be given per shard. Empty strings and a nil
+// Compress keep the values of the config template.
+type IdentifyProperties struct {
+	OS       string
+	Browser  string
+	Device   string
+	Compress *bool
+}
+
+// ShardRange is a contiguous range of shard IDs, from First up to and including Last
+type ShardRange struct {
+	First uint
+	Last  uint
+}
+
+func (r ShardRange) String() string {
+	return strconv.Itoa(int(r.First)) + "-" + strconv.Itoa(int(r.Last))
+}
+
+// ValidateShardRanges verifies that the shard ranges, eg. of every process running the bot, together cover
+// every shard of the total shard count exactly once. The ranges may be given in any order.
+func ValidateShardRanges(total uint, ranges []ShardRange) error {
+	sorted := make([]ShardRange, len(ranges))
+	copy(sorted, ranges)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].First < sorted[j].First
+	})
+
+	var next uint
+	for _, r := range sorted {
+		if r.First > r.Last {
+			return errors.New("shard range " + r.String() + " is reversed")
+		}
+		if r.First < next {
+			return errors.New("shard range " + r.String() + " overlaps with another range")
+		}
+		if r.First > next {
+			return errors.New("shards " + ShardRange{First: next, Last: r.First - 1}.String() + " are not in any range")
+		}
+		next = r.Last + 1
+	}
+	if next > total {
+		return errors.New("shard ranges exceed the shard count of " + strconv.Itoa(int(total)))
+	}
+	if next < total {
+		return errors.New("shards " + ShardRange{First: next, Last: total - 1}.String() + " are not in any range")
+	}
+	return nil
+}
+
+// ShardErrors holds the errors of the shards that failed, using the shard ID as key
+type ShardErrors map[uint]error
+
+func (e ShardErrors) Error() string {
+	ids := make([]int, 0, len(e))
+	for id := range e {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+
+	msgs := make([]string, len(ids))
+	for i, id := range ids {
+		msgs[i] = "shard " + strconv.Itoa(id) + ": " + e[uint(id)].Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// NewShardManager creates a websocket client for every shard. No connection is established until
+// ShardManager#Connect is called.
+func NewShardManager(conf *ShardManagerConfig) (manager *ShardManager, err error) {
+	if conf.Config == nil {
+		return nil, errors.New("missing shard config template")
+	}
+	if err = validateShardCount(conf, conf.ShardCount); err != nil {
+		return nil, err
+	}
+
+	// the shard count is changed on reshard, so keep a copy
+	managerConf := *conf
+	manager = &ShardManager{
+		conf:             &managerConf,
+		identifyLimiters: map[string]*identifyLimiter{},
+		sessionStarts:    map[string]*sessionStartGuard{},
+	}
+	if conf.PresenceWindow > 0 {
+		manager.presence = newPresenceCoalescer(conf.PresenceWindow)
+	}
+	manager.shards, err = manager.createShards(conf.ShardCount)
+	if err != nil {
+		return nil, err
+	}
+
+	return manager, nil
+}
+
+func validateShardCount(conf *ShardManagerConfig, count uint) error {
+	if count == 0 {
+		return errors.New("shard count must be at least 1")
+	}
+	if r := conf.ShardRange; r != nil && (r.First > r.Last || r.Last >= count) {
+		return errors.New("shard range " + r.String() + " is not within the shard count of " + strconv.Itoa(int(count)))
+	}
+	for id := range conf.ShardTokens {
+		if id >= count {
+			return errors.New("token override for shard " + strconv.Itoa(int(id)) + " which is out of range")
+		}
+	}
+	for id := range conf.ShardIdentify {
+		if id >= count {
+			return errors.New("identify override for shard " + strconv.Itoa(int(id)) + " which is out of range")
+		}
+	}
+	return nil
+}
+
+// shardRange returns the shard range this manager runs, given the total number of shards
+func (s *ShardManager) shardRange(count uint) ShardRange {
+	if s.conf.ShardRange != nil {
+		return *s.conf.ShardRange
+	}
+	return ShardRange{First: 0, Last: count - 1}
+}
+
+// createShards creates a websocket client for every shard in the shard range, given the total number of
+// shards. Must be called while holding the lock, or before the manager is shared.
+func (s *ShardManager) createShards(count uint) (shards []*Client, err error) {
+	r := s.shardRange(count)
+	shards = make([]*Client, 0, r.Last-r.First+1)
+	for id := r.First; id <= r.Last; id++ {
+		shardConf := *s.conf.Config
+		shardConf.ShardID = id
+		shardConf.ShardCount = count
+		if token, exists := s.conf.ShardTokens[id]; exists {
+			shardConf.Token = token
+			// the session start limit of the template belongs to the template token
+			shardConf.SessionStartLimit = nil
+		}
+		shardIdentity(&shardConf, s.conf.ShardIdentify)
+		if s.conf.Config.Rand != nil {
+			// a *rand.Rand is not safe for concurrent use, so every shard gets its own source
+			shardConf.Rand = rand.New(rand.NewSource(s.conf.Config.Rand.Int63()))
+		}
+
+		var shard *Client
+		shard, err = NewClient(&shardConf)
+		if err != nil {
+			return nil, err
+		}
+		shard.identifyLimit = s.tokenIdentifyLimiter(shardConf.Token)
+		shard.sessionStarts = s.tokenSessionStarts(shardConf.Token)
+		shards = append(shards, shard)
+	}
+	return shards, nil
+}
+
+// shardIdentity applies the identify overrides of the shard, or fills in the shard ID of the template
+func shardIdentity(conf *Config, overrides map[uint]IdentifyProperties) {
+	if properties, exists := overrides[conf.ShardID]; exists {
+		if properties.OS != "" {
+			conf.OS = properties.OS
+		}
+		if properties.Browser != "" {
+			conf.Browser = properties.Browser
+		}
+		if properties.Device != "" {
+			conf.Device = properties.Device
+		}
+		if properties.Compress != nil {
+			conf.Compress = *properties.Compress
+		}
+	}
+
+	id := strconv.FormatUint(uint64(conf.ShardID), 10)
+	conf.Browser = strings.Replace(conf.Browser, ShardIDPlaceholder, id, -1)
+	conf.Device = strings.Replace(conf.Device, ShardIDPlaceholder, id, -1)
+}
+
+// ShardManager spawns and keeps track of all the websocket clients, one for each shard.
+type ShardManager struct {
+	sync.RWMutex
+	conf   *ShardManagerConfig
+	shards []*Client
+
+	// identify budgets per bot token
+	identifyLimiters map[string]*identifyLimiter
+
+	// session start guards per bot token, for the tokens with a session start limit
+	sessionStarts map[string]*sessionStartGuard
+
+	// presence coalesces the fleet wide presence updates. nil when disabled.
+	presence *presenceCoalescer
+
+	// reshard makes sure only one reshard runs at a time
+	reshard sync.Mutex
+}
+
+func (s *ShardManager) tokenIdentifyLimiter(token string) *identifyLimiter {
+	if limiter, exists := s.identifyLimiters[token]; exists {
+		return limiter
+	}
+
+	limiter := newIdentifyLimiter()
+	s.identifyLimiters[token] = limiter
+	return limiter
+}
+
+// tokenSessionStarts returns the session start guard of the token, or nil when the token has no session
+// start limit
+func (s *ShardManager) tokenSessionStarts(token string) *sessionStartGuard {
+	if guard, exists := s.sessionStarts[token]; exists {
+		return guard
+	}
+
+	var limit SessionStartLimit
+	if l := s.conf.Config.SessionStartLimit; l != nil && token == s.conf.Config.Token {
+		limit = *l
+	} else if l, exists := s.conf.TokenSessionStartLimits[token]; exists {
+		limit = l
+	} else {
+		return nil
+	}
+	guard := newSessionStartGuard(limit, sessionStartReserve(s.conf.Config))
+	s.sessionStarts[token] = guard
+	return guard
+}
+
+// Shard returns the websocket client for the given shard ID, or nil if the shard does not exist or is
+// outside the shard range
+func (s *ShardManager) Shard(id uint) *Client {
+	s.RLock()
+	defer s.RUnlock()
+
+	if len(s.shards) == 0 {
+		return nil
+	}
+	first := s.shards[0].conf.ShardID
+	if id < first || id-first >= uint(len(s.shards)) {
+		return nil
+	}
+	return s.shards[id-first]
+}
+
+// Shards returns every websocket client managed, ordered by shard ID
+func (s *ShardManager) Shards() []*Client {
+	s.RLock()
+	defer s.RUnlock()
+
+	shards := make([]*Client, len(s.shards))
+	copy(shards, s.shards)
+	return shards
+}
+
+// Connect connects every shard to the Discord gateway. Identify commands are spaced out per bot token.
+func (s *ShardManager) Connect() (err error) {
+	for _, shard := range s.Shards() {
+		if err = shard.Connect(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Disconnect disconnects every shard from the Discord gateway at the same time, and waits for them to
+// finish, or for the disconnect timeout. The errors of the shards that failed, or did not finish in time,
+// are returned as ShardErrors.
+func (s *ShardManager) Disconnect() (err error) {
+	return s.disconnectShards(s.Shards())
+}
+
+func (s *ShardManager) disconnectShards(shards []*Client) error {
+	return s.stopShards(shards, (*Client).Disconnect)
+}
+
+// shutdownShards shuts down shards that are no longer used, such that their goroutines exit
+func (s *ShardManager) shutdownShards(shards []*Client) error {
+	return s.stopShards(shards, (*Client).Shutdown)
+}
+
+func (s *ShardManager) stopShards(shards []*Client, stop func(shard *Client) error) (err error) {
+	type result struct {
+		id  uint
+		err error
+	}
+
+	results := make(chan result, len(shards))
+	for _, shard := range shards {
+		go func(shard *Client) {
+			results <- result{id: shard.conf.ShardID, err: stop(shard)}
+		}(shard)
+	}
+
+	timeout := s.conf.DisconnectTimeout
+	if timeout == 0 {
+		timeout = defaultDisconnectTimeout
+	}
+	deadline := time.After(timeout)
+
+	errs := ShardErrors{}
+	pending := make(map[uint]bool, len(shards))
+	for _, shard := range shards {
+		pending[shard.conf.ShardID] = true
+	}
+	for len(pending) > 0 {
+		select {
+		case r := <-results:
+			delete(pending, r.id)
+			if r.err != nil {
+				errs[r.id] = r.err
+			}
+		case <-deadline:
+			for id := range pending {
+				errs[id] = errors.New("timed out while disconnecting")
+			}
+			pending = nil
+		}
+	}
+
+	if len(errs) > 0 {
+		err = errs
+	}
+	return
+}
+
+// UpdateStatus sends a presence update through the given shard. Discord keeps a presence per shard, so
+// the status is only shown in the guilds of that shard.
+func (s *ShardManager) UpdateStatus(shardID uint, status interface{}) error {
+	shard := s.Shard(shardID)
+	if shard == nil {
+		return errors.New("shard " + strconv.Itoa(int(shardID)) + " does not exist")
+	}
+	return shard.Emit(cmd.UpdateStatus, status)
+}
+
+// UpdateStatusAll sends the presence update through every shard, such that the status is shown in every
+// guild. Each shard is subject to its own command rate limit. The errors of the shards that failed, eg.
+// because they were rate limited, are returned as ShardErrors; the other shards keep the new status.
+//
+// With ShardManagerConfig.PresenceWindow the updates are coalesced per shard, and an update held back
+// until the end of the window only has its error logged.
+func (s *ShardManager) UpdateStatusAll(status interface{}) (err error) {
+	errs := ShardErrors{}
+	for _, shard := range s.Shards() {
+		var shardErr error
+		if s.presence != nil {
+			shardErr = s.presence.update(shard, status)
+		} else {
+			shardErr = shard.Emit(cmd.UpdateStatus, status)
+		}
+		if shardErr != nil {
+			errs[shard.conf.ShardID] = shardErr
+		}
+	}
+
+	if len(errs) > 0 {
+		err = errs
+	}
+	return
+}
+
+// Reshard replaces every shard with a new set of shards using the new shard count. The new shards are
+// connected, and once they are all ready the old shards are shut down, so events keep flowing during the
+// switch. Expect some events to be dispatched by both sets of shards while they overlap. The identify
+// budgets are shared with the old shards, so the identify rate limit is respected throughout. Concurrent
+// calls are run one at a time.
+//
+// If the new shards fail to connect or become ready before the context is done, they are shut down again
+// and the old shards are kept.
+func (s *ShardManager) Reshard(ctx context.Context, count uint) (err error) {
+	s.reshard.Lock()
+	defer s.reshard.Unlock()
+
+	if err = validateShardCount(s.conf, count); err != nil {
+		return err
+	}
+
+	s.Lock()
+	shards, err := s.createShards(count)
+	s.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, shard := range shards {
+		if err = shard.Connect(); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		for _, shard := range shards {
+			if err = shard.WaitForReady(ctx); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		_ = s.shutdownShards(shards)
+		return err
+	}
+
+	s.Lock()
+	old := s.shards
+	s.shards = shards
+	s.conf.ShardCount = count
+	s.Unlock()
+
+	if s.presence != nil {
+		s.presence.forget(old)
+	}
+	return s.shutdownShards(old)
+}
+
+// ShardStatuses returns the connection status of every shard, ordered by shard ID
+func (s *ShardManager) ShardStatuses() []ConnectionStatus {
+	shards := s.Shards()
+	statuses := make([]ConnectionStatus, len(shards))
+	for i := range shards {
+		statuses[i] = shards[i].ConnectionStatus()
+	}
+	return statuses
+}
+
+// GuildCount returns the number of guilds of every shard together, see Client#GuildCount
+func (s *ShardManager) GuildCount() (count int) {
+	for _, shard := range s.Shards() {
+		count += shard.GuildCount()
+	}
+	return count
+}
+
+// WaitAllReady blocks until every shard has received READY (or RESUMED), or the context is done.
+func (s *ShardManager) WaitAllReady(ctx context.Context) error {
+	for _, shard := range s.Shards() {
+		if err := shard.WaitForReady(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,434 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andersfylling/disgord/websocket/opcode"
+)
+
+func TestIdentifyLimiter(t *testing.T) {
+	l := newIdentifyLimiter()
+	l.interval = 20 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(ctx, 0); err != nil {
+			t.Fatal("expected identify to be allowed")
+		}
+	}
+	if since := time.Since(start); since < 2*l.interval {
+		t.Errorf("identifies were not spaced out. Took %s, wants at least %s", since, 2*l.interval)
+	}
+
+	cancel()
+	l.interval = time.Hour
+	_ = l.Wait(ctx, 0)
+	if err := l.Wait(ctx, 0); err == nil {
+		t.Error("expected wait to be aborted on shutdown")
+	}
+}
+
+func TestNewShardManager(t *testing.T) {
+	template := &Config{
+		Token:      "main",
+		HTTPClient: &http.Client{},
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := NewShardManager(&ShardManagerConfig{Config: template}); err == nil {
+			t.Error("expected error on shard count 0")
+		}
+		if _, err := NewShardManager(&ShardManagerConfig{
+			Config:      template,
+			ShardCount:  2,
+			ShardTokens: map[uint]string{2: "other"},
+		}); err == nil {
+			t.Error("expected error on token override for non-existent shard")
+		}
+	})
+
+	t.Run("token overrides", func(t *testing.T) {
+		manager, err := NewShardManager(&ShardManagerConfig{
+			Config:      template,
+			ShardCount:  3,
+			ShardTokens: map[uint]string{2: "other"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		shards := manager.Shards()
+		for id, shard := range shards {
+			if shard.conf.ShardID != uint(id) || shard.conf.ShardCount != 3 {
+				t.Errorf("shard %d has incorrect shard config: %d/%d", id, shard.conf.ShardID, shard.conf.ShardCount)
+			}
+		}
+		if shards[2].conf.Token != "other" || shards[0].conf.Token != "main" {
+			t.Error("token override was not applied")
+		}
+		if shards[0].identifyLimit != shards[1].identifyLimit {
+			t.Error("expected shards with the same token to share identify budget")
+		}
+		if shards[0].identifyLimit == shards[2].identifyLimit {
+			t.Error("expected shards with different tokens to have separate identify budgets")
+		}
+		if template.ShardID != 0 || template.Token != "main" {
+			t.Error("template config was modified")
+		}
+	})
+}
+
+func TestShardManager_WaitAllReady(t *testing.T) {
+	manager, err := NewShardManager(&ShardManagerConfig{
+		Config: &Config{
+			Token:      "main",
+			HTTPClient: &http.Client{},
+		},
+		ShardCount: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, shard := range manager.Shards() {
+		shard.setDisconnected(false)
+	}
+	for _, status := range manager.ShardStatuses() {
+		if status != StatusConnected {
+			t.Errorf("expected shard to be connected, got %s", status)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err = manager.WaitAllReady(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded while shards are not ready, got %v", err)
+	}
+
+	go func() {
+		for _, shard := range manager.Shards() {
+			shard.receiveChan <- &discordPacket{EventName: "READY", SequenceNumber: 1, Data: []byte(`{}`)}
+		}
+	}()
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err = manager.WaitAllReady(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range manager.ShardStatuses() {
+		if status != StatusReady {
+			t.Errorf("expected shard to be ready, got %s", status)
+		}
+	}
+}
+
+func TestShardManager_Disconnect(t *testing.T) {
+	manager, err := NewShardManager(&ShardManagerConfig{
+		Config: &Config{
+			Token:      "main",
+			HTTPClient: &http.Client{},
+		},
+		ShardCount: 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// none of the shards have connected
+	err = manager.Disconnect()
+	errs, ok := err.(ShardErrors)
+	if !ok {
+		t.Fatalf("expected ShardErrors, got %v", err)
+	}
+	if len(errs) != 3 {
+		t.Errorf("expected an error for each shard, got %d", len(errs))
+	}
+	if msg := errs.Error(); !strings.HasPrefix(msg, "shard 0: ") {
+		t.Errorf("expected the errors to be ordered by shard ID, got %s", msg)
+	}
+}
+
+func TestShardManager_Reshard(t *testing.T) {
+	manager, err := NewShardManager(&ShardManagerConfig{
+		Config: &Config{
+			Token:      "main",
+			HTTPClient: &http.Client{},
+		},
+		ShardCount:  3,
+		ShardTokens: map[uint]string{2: "other"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := manager.Shards()
+
+	if err = manager.Reshard(context.Background(), 0); err == nil {
+		t.Error("expected error on shard count 0")
+	}
+	if err = manager.Reshard(context.Background(), 2); err == nil {
+		t.Error("expected error when a token override would be out of range")
+	}
+
+	after := manager.Shards()
+	if len(after) != len(shards) {
+		t.Fatalf("expected the shards to be kept, got %d shards", len(after))
+	}
+	for i := range shards {
+		if shards[i] != after[i] {
+			t.Error("expected the shards to be kept")
+		}
+	}
+
+	t.Run("rollback", func(t *testing.T) {
+		manager, err := NewShardManager(&ShardManagerConfig{
+			Config: &Config{
+				Token:      "main",
+				HTTPClient: &http.Client{},
+				DryRun:     true,
+			},
+			ShardCount: 1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer manager.Shards()[0].Shutdown()
+		before := runtime.NumGoroutine()
+
+		// the dry run shards never become ready
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if err = manager.Reshard(ctx, 4); err == nil {
+			t.Fatal("expected the reshard to fail")
+		}
+		if len(manager.Shards()) != 1 {
+			t.Error("expected the shards to be kept")
+		}
+
+		var goroutines int
+		for i := 0; i < 50; i++ {
+			if goroutines = runtime.NumGoroutine(); goroutines <= before {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if goroutines > before {
+			t.Errorf("expected the new shards to be shut down, went from %d to %d goroutines", before, goroutines)
+		}
+	})
+}
+
+func TestShardManager_ShardIdentify(t *testing.T) {
+	compress := false
+	manager, err := NewShardManager(&ShardManagerConfig{
+		Config: &Config{
+			Token:      "main",
+			HTTPClient: &http.Client{},
+			Browser:    "disgord",
+			Device:     "bot/shard-" + ShardIDPlaceholder,
+			Compress:   true,
+		},
+		ShardCount: 3,
+		ShardIdentify: map[uint]IdentifyProperties{
+			1: {Browser: "debug", Compress: &compress},
+			2: {Device: "other"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shards := manager.Shards()
+	if conf := shards[0].conf; conf.Browser != "disgord" || conf.Device != "bot/shard-0" || !conf.Compress {
+		t.Errorf("expected the template properties with the shard ID, got %+v", conf)
+	}
+	if conf := shards[1].conf; conf.Browser != "debug" || conf.Device != "bot/shard-1" || conf.Compress {
+		t.Errorf("expected the identify override, got %+v", conf)
+	}
+	if conf := shards[2].conf; conf.Browser != "disgord" || conf.Device != "other" || !conf.Compress {
+		t.Errorf("expected the template compression to be kept, got %+v", conf)
+	}
+
+	if _, err = NewShardManager(&ShardManagerConfig{
+		Config:        &Config{HTTPClient: &http.Client{}},
+		ShardCount:    1,
+		ShardIdentify: map[uint]IdentifyProperties{1: {}},
+	}); err == nil {
+		t.Error("expected error on identify override for non-existent shard")
+	}
+}
+
+func TestShardManager_UpdateStatusAll(t *testing.T) {
+	manager, err := NewShardManager(&ShardManagerConfig{
+		Config: &Config{
+			Token:      "main",
+			HTTPClient: &http.Client{},
+		},
+		ShardCount: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the first shard is ready
+	ready := manager.Shard(0)
+	ready.setDisconnected(false)
+	ready.stateMutex.Lock()
+	ready.setReady(true)
+	ready.stateMutex.Unlock()
+	sent := make(chan *clientPacket, 1)
+	go func() {
+		sent <- <-ready.emitChan
+	}()
+
+	err = manager.UpdateStatusAll(struct{}{})
+	errs, ok := err.(ShardErrors)
+	if !ok {
+		t.Fatalf("expected ShardErrors, got %v", err)
+	}
+	if _, failed := errs[1]; !failed || len(errs) != 1 {
+		t.Errorf("expected only the second shard to fail, got %s", errs)
+	}
+	select {
+	case packet := <-sent:
+		if packet.Op != opcode.StatusUpdate {
+			t.Errorf("expected a status update, got op %d", packet.Op)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected the ready shard to send the status update")
+	}
+
+	if err = manager.UpdateStatus(2, struct{}{}); err == nil {
+		t.Error("expected error on non-existent shard")
+	}
+}
+
+func TestShardManager_ShardRange(t *testing.T) {
+	conf := &ShardManagerConfig{
+		Config:     &Config{HTTPClient: &http.Client{}},
+		ShardCount: 10,
+		ShardRange: &ShardRange{First: 4, Last: 6},
+	}
+	manager, err := NewShardManager(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shards := manager.Shards()
+	if len(shards) != 3 {
+		t.Fatalf("expected 3 shards, got %d", len(shards))
+	}
+	for i, shard := range shards {
+		if shard.conf.ShardID != uint(4+i) || shard.conf.ShardCount != 10 {
+			t.Errorf("shard has incorrect shard config: %d/%d", shard.conf.ShardID, shard.conf.ShardCount)
+		}
+	}
+	if manager.Shard(5) != shards[1] {
+		t.Error("expected Shard to look up the shard by its ID")
+	}
+	if manager.Shard(3) != nil || manager.Shard(7) != nil {
+		t.Error("expected no shards outside the shard range")
+	}
+
+	conf.ShardRange = &ShardRange{First: 8, Last: 10}
+	if _, err = NewShardManager(conf); err == nil {
+		t.Error("expected error on a shard range outside the shard count")
+	}
+}
+
+func TestValidateShardRanges(t *testing.T) {
+	testCases := []struct {
+		name   string
+		ranges []ShardRange
+		valid  bool
+	}{
+		{"complete", []ShardRange{{0, 3}, {4, 7}, {8, 9}}, true},
+		{"unordered", []ShardRange{{8, 9}, {0, 3}, {4, 7}}, true},
+		{"overlap", []ShardRange{{0, 4}, {4, 9}}, false},
+		{"gap", []ShardRange{{0, 3}, {5, 9}}, false},
+		{"missing start", []ShardRange{{1, 9}}, false},
+		{"missing end", []ShardRange{{0, 8}}, false},
+		{"exceeds", []ShardRange{{0, 10}}, false},
+		{"reversed", []ShardRange{{0, 4}, {9, 5}}, false},
+		{"empty", nil, false},
+	}
+	for _, tc := range testCases {
+		err := ValidateShardRanges(10, tc.ranges)
+		if tc.valid && err != nil {
+			t.Errorf("%s: unexpected error: %s", tc.name, err)
+		} else if !tc.valid && err == nil {
+			t.Errorf("%s: expected error", tc.name)
+		}
+	}
+}
+
+func TestShardManager_PresenceWindow(t *testing.T) {
+	manager, err := NewShardManager(&ShardManagerConfig{
+		Config: &Config{
+			Token:      "main",
+			HTTPClient: &http.Client{},
+		},
+		ShardCount:     2,
+		PresenceWindow: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, shard := range manager.Shards() {
+		shard.emitChan = make(chan *clientPacket, 10)
+		shard.setDisconnected(false)
+		shard.stateMutex.Lock()
+		shard.setReady(true)
+		shard.stateMutex.Unlock()
+	}
+
+	for _, status := range []string{"first", "second", "latest"} {
+		if err = manager.UpdateStatusAll(status); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(150 * time.Millisecond)
+
+	for id, shard := range manager.Shards() {
+		var statuses []interface{}
+		for len(shard.emitChan) > 0 {
+			statuses = append(statuses, (<-shard.emitChan).Data)
+		}
+		if len(statuses) != 2 || statuses[0] != "first" || statuses[1] != "latest" {
+			t.Errorf("shard %d: expected the first and the latest status, got %v", id, statuses)
+		}
+	}
+
+	manager.presence.Lock()
+	open := len(manager.presence.open)
+	manager.presence.Unlock()
+	if open != 0 {
+		t.Errorf("expected the windows to close, got %d open", open)
+	}
+
+	// retired shards are dropped along with their held back status
+	shards := manager.Shards()
+	for _, status := range []string{"first", "latest"} {
+		if err = manager.UpdateStatusAll(status); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manager.presence.forget(shards)
+	manager.presence.Lock()
+	open, pending := len(manager.presence.open), len(manager.presence.pending)
+	manager.presence.Unlock()
+	if open != 0 || pending != 0 {
+		t.Errorf("expected the retired shards to be forgotten, got %d open and %d pending", open, pending)
+	}
+	time.Sleep(100 * time.Millisecond)
+	for id, shard := range shards {
+		if len(shard.emitChan) != 1 {
+			t.Errorf("shard %d: expected the held back status to not be sent, got %d statuses", id, len(shard.emitChan))
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ConnectionStatus describes how far a websocket client has come in establishing a Discord session
+type ConnectionStatus uint8
+
+const (
+	// StatusDisconnected there is no connection to the Discord gateway
+	StatusDisconnected ConnectionStatus = iota
+
+	// StatusConnected the socket connection is established, but Discord has not yet sent READY or RESUMED
+	StatusConnected
+
+	// StatusReady the session is established and events are flowing
+	StatusReady
+)
+
+func (s ConnectionStatus) String() string {
+	switch s {
+	case StatusDisconnected:
+		return "disconnected"
+	case StatusConnected:
+		return "connected"
+	case StatusReady:
+		return "ready"
+	default:
+		return "unknown"
+	}
+}
+
+// ConnectionStatus returns the current connection status
+func (m *Client) ConnectionStatus() ConnectionStatus {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+
+	if m.disconnected {
+		return StatusDisconnected
+	} else if m.ready {
+		return StatusReady
+	}
+	return StatusConnected
+}
+
+// ConnectedSince returns when the current connection was established, or the zero time while
+// disconnected. Reconnects start over.
+func (m *Client) ConnectedSince() time.Time {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+
+	return m.connectedSince
+}
+
+// LastDisconnect returns when the client was last disconnected, or the zero time if it has never been
+// disconnected.
+func (m *Client) LastDisconnect() time.Time {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+
+	return m.lastDisconnect
+}
+
+// ConnectLatency returns how long it took to dial Discord and upgrade to a websocket connection, for the
+// current connection or the last one while disconnected. It is 0 if the client has never connected. Unlike
+// the heartbeat latency, it does not involve Discord processing a command, so a slow connect with fast
+// heartbeats points at the network or the gateway route rather than at Discord.
+func (m *Client) ConnectLatency() time.Duration {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+
+	return m.connectLatency
+}
+
+// HelloLatency returns how long Discord took to send hello after the current connection was established,
+// or 0 if it has not sent hello yet on this connection.
+func (m *Client) HelloLatency() time.Duration {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+
+	return m.helloLatency
+}
+
+// LastHeartbeatAck returns when Discord last acknowledged a heartbeat, or the zero time if it never has
+func (m *Client) LastHeartbeatAck() time.Time {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.lastHeartbeatAck
+}
+
+// LastCloseCode returns the close code Discord last closed the connection with, or 0 if Discord has never
+// closed it with a close code
+func (m *Client) LastCloseCode() CloseCode {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+
+	return m.lastCloseCode
+}
+
+// setReady must be called while holding the stateMutex
+func (m *Client) setReady(ready bool) {
+	if m.readyChan == nil {
+		m.readyChan = make(chan interface{})
+	}
+	if ready == m.ready {
+		return
+	}
+
+	m.ready = ready
+	if ready {
+		m.haveBeenReady = true
+		close(m.readyChan)
+	} else {
+		m.readyChan = make(chan interface{})
+	}
+}
+
+// WaitForReady blocks until Discord has sent a READY or RESUMED event for the current connection, or
+// the context is done.
+func (m *Client) WaitForReady(ctx context.Context) error {
+	m.stateMutex.Lock()
+	if m.readyChan == nil {
+		m.readyChan = make(chan interface{})
+	}
+	ready := m.readyChan
+	m.stateMutex.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// commandCounters keeps track of what happened to the commands given to Client#Emit
+type commandCounters struct {
+	sync.Mutex
+	emitted     uint64
+	rateLimited uint64
+	dropped     uint64
+}
+
+// CommandOutcome is what happened to a command given to Client#Emit, see Config.OnCommand
+type CommandOutcome uint8
+
+const (
+	// CommandEmitted is a command written to the socket connection, see Status.EmittedCommands
+	CommandEmitted CommandOutcome = iota
+
+	// CommandRateLimited is a command rejected by the command rate limiter, see Status.RateLimitedCommands
+	CommandRateLimited
+
+	// CommandDropped is a command that was not sent, see Status.DroppedCommands
+	CommandDropped
+)
+
+func (o CommandOutcome) String() string {
+	switch o {
+	case CommandEmitted:
+		return "emitted"
+	case CommandRateLimited:
+		return "rate limited"
+	case CommandDropped:
+		return "dropped"
+	default:
+		return "unknown"
+	}
+}
+
+// countCommand counts the outcome of the command, and reports it to Config.OnCommand
+func (m *Client) countCommand(command string, outcome CommandOutcome) {
+	m.commands.Lock()
+	switch outcome {
+	case CommandEmitted:
+		m.commands.emitted++
+	case CommandRateLimited:
+		m.commands.rateLimited++
+	case CommandDropped:
+		m.commands.dropped++
+	}
+	m.commands.Unlock()
+
+	if m.conf.OnCommand != nil {
+		m.conf.OnCommand(command, outcome)
+	}
+}
+
+// Status is a snapshot of the client state, for monitoring
+type Status struct {
+	Connection       ConnectionStatus
+	HeartbeatLatency time.Duration
+
+	// ConnectedSince is when the current connection was established, see Client#ConnectedSince
+	ConnectedSince time.Time
+
+	// LastDisconnect is when the client was last disconnected, see Client#LastDisconnect
+	LastDisconnect time.Time
+
+	// LastHeartbeatAck is when Discord last acknowledged a heartbeat, see Client#LastHeartbeatAck
+	LastHeartbeatAck time.Time
+
+	// ConnectLatency is how long the dial and websocket upgrade took, see Client#ConnectLatency
+	ConnectLatency time.Duration
+
+	// HelloLatency is how long Discord took to send hello, see Client#HelloLatency
+	HelloLatency time.Duration
+
+	// LastCloseCode is the close code Discord last closed the connection with, see Client#LastCloseCode
+	LastCloseCode CloseCode
+
+	// EmittedCommands is the number of commands written to the socket connection, heartbeats included
+	EmittedCommands uint64
+
+	// RateLimitedCommands is the number of commands rejected by the command rate limiter
+	RateLimitedCommands uint64
+
+	// DroppedCommands is the number of commands that were not sent due to a shutdown or a write error
+	DroppedCommands uint64
+
+	// DroppedEvents is the number of events dropped because the application did not receive them in time,
+	// see Config.SlowConsumerPolicy
+	DroppedEvents uint64
+
+	// RateLimitRemaining is the number of commands that can be sent right now, see Client#RateLimitRemaining
+	RateLimitRemaining map[string]uint
+}
+
+// Status returns a snapshot of the client state
+func (m *Client) Status() Status {
+	status := Status{
+		Connection: m.ConnectionStatus(),
+	}
+	status.HeartbeatLatency, _ = m.HeartbeatLatency()
+	status.RateLimitRemaining = m.RateLimitRemaining()
+	status.ConnectedSince = m.ConnectedSince()
+	status.LastDisconnect = m.LastDisconnect()
+	status.LastCloseCode = m.LastCloseCode()
+	status.LastHeartbeatAck = m.LastHeartbeatAck()
+	status.ConnectLatency = m.ConnectLatency()
+	status.HelloLatency = m.HelloLatency()
+
+	m.commands.Lock()
+	status.EmittedCommands = m.commands.emitted
+	status.RateLimitedCommands = m.commands.rateLimited
+	status.DroppedCommands = m.commands.dropped
+	m.commands.Unlock()
+
+	m.outboxes.Lock()
+	status.DroppedEvents = m.outboxes.dropped
+	m.outboxes.Unlock()
+
+	return status
+}
+
+// RateLimitRemaining gives the number of commands that can be sent right now, before being rate limited. The
+// "global" entry is shared by all commands, while commands with a stricter limit of their own, such as
+// cmd.UpdateStatus, have a separate entry. A command is only accepted when both itself and the global
+// bucket have requests remaining.
+func (m *Client) RateLimitRemaining() map[string]uint {
+	return m.ratelimit.Remaining()
+}
@@ -0,0 +1,202 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultSubscriberBuffer is used for subscriber channels when Config.ChannelBuffer is not set
+const defaultSubscriberBuffer = 100
+
+// subscribers fans out every dispatched event to the channels registered through Client#Subscribe and
+// Client#SubscribeTo. A subscriber that can not keep up will have events dropped instead of stalling the
+// other subscribers and the socket layer.
+type subscribers struct {
+	sync.RWMutex
+	channels map[uint]*subscriber
+	nextID   uint
+}
+
+type subscriber struct {
+	events chan *Event
+
+	// names are the events of a SubscribeTo subscription, nil when every event is received
+	names  map[string]struct{}
+	filter func(evt *Event) bool
+
+	// wait is how long a full channel may hold back the socket layer before the event is dropped
+	wait time.Duration
+
+	// stalled is set once an event was dropped after waiting, such that the next events are dropped right
+	// away until the channel has room again. Only accessed by dispatch.
+	stalled bool
+
+	// done is closed when unsubscribing, so a waiting dispatch gives up right away
+	done chan struct{}
+}
+
+func (s *subscriber) accepts(evt *Event) bool {
+	if s.names == nil {
+		return !evt.subscribersOnly
+	}
+	if _, exists := s.names[evt.eventName()]; !exists {
+		return false
+	}
+	return s.filter == nil || s.filter(evt)
+}
+
+func (s *subscribers) add(sub *subscriber) (id uint) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.channels == nil {
+		s.channels = map[uint]*subscriber{}
+	}
+	id = s.nextID
+	s.nextID++
+	sub.done = make(chan struct{})
+	s.channels[id] = sub
+	return
+}
+
+func (s *subscribers) remove(id uint) {
+	s.RLock()
+	sub, exists := s.channels[id]
+	s.RUnlock()
+	if !exists {
+		return
+	}
+
+	// a dispatch waiting on the channel holds the read lock
+	close(sub.done)
+
+	s.Lock()
+	defer s.Unlock()
+	delete(s.channels, id)
+	close(sub.events)
+}
+
+// dispatch gives the event to every subscriber that accepts it. Full subscribers that may wait share one
+// deadline, so the socket layer is held back for at most the wait duration per event no matter how many
+// subscribers are full. Must be called while holding the delivery lock.
+func (s *subscribers) dispatch(evt *Event) {
+	s.RLock()
+	defer s.RUnlock()
+
+	var deadline time.Time
+	for id, sub := range s.channels {
+		if !sub.accepts(evt) {
+			continue
+		}
+		select {
+		case sub.events <- evt:
+			sub.stalled = false
+			continue
+		default:
+		}
+		if sub.wait > 0 && !sub.stalled {
+			if deadline.IsZero() {
+				deadline = time.Now().Add(sub.wait)
+			}
+			if sub.send(evt, deadline) {
+				continue
+			}
+			sub.stalled = true
+		}
+		logrus.Warnf("event subscriber %d is full, dropping event %s", id, evt.Name)
+	}
+}
+
+// send waits for room in the channel until the deadline
+func (s *subscriber) send(evt *Event, deadline time.Time) bool {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case s.events <- evt:
+		return true
+	case <-timer.C:
+	case <-s.done:
+	}
+	return false
+}
+
+// Subscribe registers an independent event channel which receives every event that is sent to the
+// event channel. Each subscriber has a buffer of Config.ChannelBuffer events (or 100 if unset), and
+// events are dropped for subscribers with a full buffer, so a slow subscriber never holds back others.
+// Call the returned function to unsubscribe, which also closes the channel. Note that the channel given
+// by Client#EventChan must still be consumed.
+func (m *Client) Subscribe() (<-chan *Event, func()) {
+	buffer := m.conf.ChannelBuffer
+	if buffer == 0 {
+		buffer = defaultSubscriberBuffer
+	}
+
+	c := make(chan *Event, buffer)
+	id := m.subscribers.add(&subscriber{events: c})
+	var once sync.Once
+	return c, func() {
+		once.Do(func() {
+			m.subscribers.remove(id)
+		})
+	}
+}
+
+// SubscribeTo registers an event channel which only receives the events with the given names that the
+// filter accepts. A nil filter accepts every event with the names. The filter is called by the socket
+// layer and must not block. The events do not have to be registered, see Client#RegisterEvent; the socket
+// layer keeps them for as long as the subscription lasts, and events that are not registered are only
+// given to the subscriptions asking for them.
+//
+// Unlike Subscribe, the channel has a buffer of 100 events regardless of Config.ChannelBuffer, and a full
+// channel holds back the socket layer for up to Config.EventSendTimeout before the event is dropped. This
+// suits short bursts of related events, eg. Guild Members Chunks, that must not be lost. The timeout is
+// shared by every full subscription, and once an event was dropped the following events are dropped right
+// away until the channel has room again. Call the returned function to unsubscribe, which also closes the
+// channel.
+func (m *Client) SubscribeTo(names []string, filter func(evt *Event) bool) (<-chan *Event, func()) {
+	sub := &subscriber{
+		events: make(chan *Event, defaultSubscriberBuffer),
+		names:  make(map[string]struct{}, len(names)),
+		filter: filter,
+		wait:   m.eventSendTimeout(),
+	}
+	for _, name := range names {
+		sub.names[name] = struct{}{}
+	}
+
+	id := m.subscribers.add(sub)
+	m.subscribeEvents(names)
+	var once sync.Once
+	return sub.events, func() {
+		once.Do(func() {
+			m.subscribers.remove(id)
+			m.unsubscribeEvents(names)
+		})
+	}
+}
+
+// WaitForEvent blocks until an event with the given name is dispatched which the filter accepts, or the
+// context is done. A nil filter accepts any event with the name. The event is only kept by the socket layer
+// while waiting, see Client#SubscribeTo, so a name that is not registered through Client#RegisterEvent is
+// not sent to the event channel. Every call uses its own subscription, so concurrent calls are independent
+// and all of them may receive the same event.
+func (m *Client) WaitForEvent(ctx context.Context, name string, filter func(data []byte) bool) (*Event, error) {
+	events, unsubscribe := m.SubscribeTo([]string{name}, func(evt *Event) bool {
+		return filter == nil || filter(evt.Data)
+	})
+	defer unsubscribe()
+
+	select {
+	case evt, open := <-events:
+		if !open {
+			return nil, errors.New("subscription was closed")
+		}
+		return evt, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,219 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClient_Subscribe(t *testing.T) {
+	m, _ := NewTestClient(&Config{
+		HTTPClient:    &http.Client{},
+		ChannelBuffer: 2,
+	}, &testWS{})
+	m.RegisterEvent("TEST")
+
+	fast, unsubscribeFast := m.Subscribe()
+	slow, unsubscribeSlow := m.Subscribe()
+
+	const events = 5
+	received := make(chan int)
+	go func() {
+		var count int
+		for range fast {
+			count++
+		}
+		received <- count
+	}()
+	go func() {
+		for i := 1; i <= events; i++ {
+			m.receiveChan <- &discordPacket{EventName: "TEST", SequenceNumber: uint(i)}
+		}
+	}()
+	for i := 0; i < events; i++ {
+		select {
+		case <-m.EventChan():
+		case <-time.After(time.Second):
+			t.Fatal("event was not dispatched")
+		}
+	}
+
+	unsubscribeFast()
+	unsubscribeFast()
+	if count := <-received; count != events {
+		t.Errorf("expected subscriber to receive %d events, got %d", events, count)
+	}
+	if len(slow) != 2 {
+		t.Errorf("expected slow subscriber to have a full buffer of 2 events, got %d", len(slow))
+	}
+
+	unsubscribeSlow()
+	for range slow {
+	}
+}
+
+func TestClient_SubscribeTo(t *testing.T) {
+	m, _ := NewTestClient(&Config{
+		HTTPClient:       &http.Client{},
+		EventSendTimeout: 100 * time.Millisecond,
+	}, &testWS{})
+	m.RegisterEvent("OTHER")
+	all, unsubscribeAll := m.Subscribe()
+	defer unsubscribeAll()
+
+	events, unsubscribe := m.SubscribeTo([]string{"TEST"}, func(evt *Event) bool {
+		return string(evt.Data) != `"skip"`
+	})
+
+	// a subscription that is never read must only hold back the socket layer once
+	stuck, unsubscribeStuck := m.SubscribeTo([]string{"TEST"}, nil)
+
+	// more events than the subscriber buffer, which must wait for room instead of being dropped
+	const count = defaultSubscriberBuffer + 10
+	received := make(chan int)
+	go func() {
+		// let the buffer fill up
+		time.Sleep(20 * time.Millisecond)
+		var kept int
+		for i := 0; i < count; i++ {
+			select {
+			case evt := <-events:
+				if string(evt.Data) == `"keep"` {
+					kept++
+				}
+			case <-time.After(time.Second):
+			}
+		}
+		received <- kept
+	}()
+	go func() {
+		var seq uint
+		for i := 0; i < count; i++ {
+			seq++
+			m.receiveChan <- &discordPacket{EventName: "TEST", SequenceNumber: seq, Data: []byte(`"keep"`)}
+			seq++
+			m.receiveChan <- &discordPacket{EventName: "TEST", SequenceNumber: seq, Data: []byte(`"skip"`)}
+			seq++
+			m.receiveChan <- &discordPacket{EventName: "OTHER", SequenceNumber: seq}
+		}
+	}()
+	start := time.Now()
+	for i := 0; i < count; i++ {
+		select {
+		case evt := <-m.EventChan():
+			if evt.Name != "OTHER" {
+				t.Fatalf("expected the subscribed event to not be sent to the event channel, got %s", evt.Name)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("event was not dispatched")
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 5*m.eventSendTimeout() {
+		t.Errorf("expected the unread subscription to hold back the socket layer once, took %s", elapsed)
+	}
+	if len(stuck) != defaultSubscriberBuffer {
+		t.Errorf("expected the unread subscription to be full, got %d events", len(stuck))
+	}
+	if kept := <-received; kept != count {
+		t.Errorf("expected the subscription to receive %d events, got %d", count, kept)
+	}
+	for len(all) > 0 {
+		if evt := <-all; evt.Name != "OTHER" {
+			t.Errorf("expected the unregistered event to only be given to its subscription, got %s", evt.Name)
+		}
+	}
+
+	if !m.eventSubscribed("TEST") {
+		t.Error("expected the event to be kept while subscribed")
+	}
+	unsubscribeStuck()
+	unsubscribe()
+	unsubscribe()
+	if m.eventSubscribed("TEST") {
+		t.Error("expected the event to be released once unsubscribed")
+	}
+	if _, open := <-events; open {
+		t.Error("expected the channel to be closed")
+	}
+}
+
+func TestClient_WaitForEvent(t *testing.T) {
+	m, _ := NewTestClient(&Config{
+		HTTPClient: &http.Client{},
+	}, &testWS{})
+
+	waitFor := func(data string) <-chan *Event {
+		c := make(chan *Event, 1)
+		go func() {
+			evt, err := m.WaitForEvent(context.Background(), "TEST", func(d []byte) bool {
+				return string(d) == data
+			})
+			if err != nil {
+				t.Error(err)
+			}
+			c <- evt
+		}()
+		return c
+	}
+	first := waitFor(`"first"`)
+	second := waitFor(`"second"`)
+	for {
+		m.subscribers.RLock()
+		subscribed := len(m.subscribers.channels)
+		m.subscribers.RUnlock()
+		if subscribed == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	go func() {
+		m.receiveChan <- &discordPacket{EventName: "OTHER", SequenceNumber: 1, Data: []byte(`"first"`)}
+		m.receiveChan <- &discordPacket{EventName: "TEST", SequenceNumber: 2, Data: []byte(`"second"`)}
+		m.receiveChan <- &discordPacket{EventName: "TEST", SequenceNumber: 3, Data: []byte(`"first"`)}
+	}()
+	if evt := <-first; evt == nil || string(evt.Data) != `"first"` {
+		t.Error("expected the first waiter to receive its event")
+	}
+	if evt := <-second; evt == nil || string(evt.Data) != `"second"` {
+		t.Error("expected the second waiter to receive its event")
+	}
+
+	m.subscribers.RLock()
+	subscribed := len(m.subscribers.channels)
+	m.subscribers.RUnlock()
+	if subscribed != 0 {
+		t.Errorf("expected the waiters to unsubscribe, got %d subscribers", subscribed)
+	}
+	if m.eventSubscribed("TEST") || len(m.TrackedEvents()) > 0 {
+		t.Error("expected the event to only be kept while waiting")
+	}
+
+	// an event registered by the application stays registered
+	m.RegisterEvent("OTHER")
+	waited := make(chan error)
+	go func() {
+		_, err := m.WaitForEvent(context.Background(), "OTHER", nil)
+		waited <- err
+	}()
+	for !m.eventSubscribed("OTHER") {
+		time.Sleep(time.Millisecond)
+	}
+	go func() {
+		m.receiveChan <- &discordPacket{EventName: "OTHER", SequenceNumber: 4}
+	}()
+	<-m.EventChan()
+	if err := <-waited; err != nil {
+		t.Fatal(err)
+	}
+	if !m.eventOfInterest("OTHER") {
+		t.Error("expected the registered event to stay registered")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := m.WaitForEvent(ctx, "TEST", nil); err != context.DeadlineExceeded {
+		t.Errorf("expected the wait to time out, got %v", err)
+	}
+}
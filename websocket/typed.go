@@ -0,0 +1,49 @@
+package websocket
+
+import "sync"
+
+// typedChannels holds the dedicated event channels created through Client#TypedChan
+type typedChannels struct {
+	sync.RWMutex
+	channels map[string]chan *Event
+}
+
+func (t *typedChannels) get(name string) (c chan *Event, exists bool) {
+	t.RLock()
+	defer t.RUnlock()
+
+	c, exists = t.channels[name]
+	return
+}
+
+func (t *typedChannels) getOrCreate(name string, buffer uint) chan *Event {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.channels == nil {
+		t.channels = map[string]chan *Event{}
+	}
+	if c, exists := t.channels[name]; exists {
+		return c
+	}
+	c := make(chan *Event, buffer)
+	t.channels[name] = c
+	return c
+}
+
+// TypedChan gives a dedicated channel for the given event type, which is created on the first call and
+// registers the event. Events of this type are no longer sent to the channel given by Client#EventChan,
+// so each event type can be consumed and sized independently. The channel has a buffer of
+// Config.ChannelBuffer events (or 100 if unset). Once it is full, events are delivered just like on the
+// default event channel: the socket layer waits at most Config.EventSendTimeout, after which the events
+// are buffered or dropped as decided by Config.SlowConsumerPolicy.
+func (m *Client) TypedChan(eventName string) <-chan *Event {
+	buffer := m.conf.ChannelBuffer
+	if buffer == 0 {
+		buffer = defaultSubscriberBuffer
+	}
+
+	c := m.typed.getOrCreate(eventName, buffer)
+	m.RegisterEvent(eventName)
+	return c
+}
@@ -0,0 +1,41 @@
+package websocket
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClient_TypedChan(t *testing.T) {
+	m, _ := NewTestClient(&Config{
+		HTTPClient: &http.Client{},
+	}, &testWS{})
+	m.RegisterEvent("OTHER")
+
+	messages := m.TypedChan("MESSAGE")
+	if again := m.TypedChan("MESSAGE"); again != messages {
+		t.Error("expected the same channel for the same event type")
+	}
+
+	go func() {
+		m.receiveChan <- &discordPacket{EventName: "MESSAGE", SequenceNumber: 1}
+		m.receiveChan <- &discordPacket{EventName: "OTHER", SequenceNumber: 2}
+	}()
+
+	select {
+	case evt := <-m.EventChan():
+		if evt.Name != "OTHER" {
+			t.Errorf("expected only events without a typed channel on the event channel, got %s", evt.Name)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event was not dispatched to the event channel")
+	}
+	select {
+	case evt := <-messages:
+		if evt.Name != "MESSAGE" {
+			t.Errorf("expected a MESSAGE event, got %s", evt.Name)
+		}
+	default:
+		t.Fatal("event was not dispatched to the typed channel")
+	}
+}
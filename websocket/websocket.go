@@ -1,6 +1,11 @@
 package websocket
 
-import "net/http"
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
 
 type Conn interface {
 	Close() error
@@ -11,14 +16,71 @@ type Conn interface {
 	Disconnected() bool
 }
 
+// resumableCloser is implemented by connections that can close without invalidating the Discord session.
+// Discord invalidates the session when the connection is closed with code 1000 or 1001.
+type resumableCloser interface {
+	CloseResumable() error
+}
+
+// pinger is implemented by connections that support websocket ping frames, see Config#PingInterval
+type pinger interface {
+	Ping() error
+	LastPong() time.Time
+}
+
+// boundReader is implemented by connections that can give a reader bound to the current connection, such
+// that a receiver never reads from a connection opened after its own
+type boundReader interface {
+	reader() func() (packet []byte, err error)
+}
+
+// ErrRateLimited is returned by Emit when the command rate limit is reached, see Client#EmitWait
+var ErrRateLimited = errors.New("rate limited")
+
 type ErrorUnexpectedClose struct {
 	info string
+	code CloseCode
 }
 
 func (e *ErrorUnexpectedClose) Error() string {
 	return e.info
 }
 
+// Code returns the close code given by Discord, or 0 if the connection was lost without one
+func (e *ErrorUnexpectedClose) Code() CloseCode {
+	return e.code
+}
+
+// ErrorNotReady is returned by Emit when a command is given before the connection is in a state where
+// Discord accepts it. Status is StatusDisconnected if the client has never connected, and StatusConnected
+// if it is connected but Discord has not yet said hello, or the session has never been ready.
+type ErrorNotReady struct {
+	Command string
+	Status  ConnectionStatus
+}
+
+func (e *ErrorNotReady) Error() string {
+	if e.Status == StatusDisconnected {
+		return "cannot emit " + e.Command + ": you must connect to the socket API/Gateway before you can send gateway commands"
+	}
+	return "cannot emit " + e.Command + ": connected, but the session is not ready yet"
+}
+
+// MaxPayloadSize is the largest encoded command, in bytes, that Discord accepts. Discord closes the
+// connection with a decode error when a command is larger.
+const MaxPayloadSize = 4096
+
+// ErrorPayloadTooLarge is returned by Emit when the encoded command exceeds MaxPayloadSize. The command is
+// not sent, such that it does not close the connection.
+type ErrorPayloadTooLarge struct {
+	Command string
+	Size    int
+}
+
+func (e *ErrorPayloadTooLarge) Error() string {
+	return "cannot emit " + e.Command + ": the payload is " + strconv.Itoa(e.Size) + " bytes, which exceeds the gateway limit of " + strconv.Itoa(MaxPayloadSize) + " bytes"
+}
+
 // WebsocketErr is used internally when the websocket package returns an error. It does not represent a Discord error(!)
 type WebsocketErr struct {
 	ID      uint
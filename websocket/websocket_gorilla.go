@@ -3,8 +3,11 @@ package websocket
 // TODO: if we add any other websocket packages, add build constraints to this file.
 
 import (
+	"errors"
 	"io"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/andersfylling/disgord/httd"
 	"github.com/gorilla/websocket"
@@ -22,6 +25,10 @@ func newConn(HTTPClient *http.Client) (Conn, error) {
 type gorilla struct {
 	c          *websocket.Conn
 	HTTPClient *http.Client
+
+	// mu guards c between closing and pinging, which happen outside the reader and writer
+	mu       sync.Mutex
+	lastPong time.Time
 }
 
 func (g *gorilla) Open(endpoint string, requestHeader http.Header) (err error) {
@@ -38,14 +45,46 @@ func (g *gorilla) Open(endpoint string, requestHeader http.Header) (err error) {
 	}
 
 	// establish ws connection
-	g.c, _, err = dialer.Dial(endpoint, requestHeader)
+	var c *websocket.Conn
+	c, _, err = dialer.Dial(endpoint, requestHeader)
+	if err != nil {
+		return
+	}
+
+	// pongs are handled by the reader
+	c.SetPongHandler(func(string) error {
+		g.mu.Lock()
+		g.lastPong = time.Now()
+		g.mu.Unlock()
+		return nil
+	})
+
+	g.mu.Lock()
+	g.c = c
+	g.mu.Unlock()
 	return
 }
 
+// conn returns the current connection, or an error once it is closed. Writes use the connection they
+// started on, such that closing it concurrently makes them fail instead of using a nil connection.
+func (g *gorilla) conn() (*websocket.Conn, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.c == nil {
+		return nil, errors.New("connection is closed")
+	}
+	return g.c, nil
+}
+
 func (g *gorilla) WriteJSON(v interface{}) (err error) {
+	c, err := g.conn()
+	if err != nil {
+		return err
+	}
+
 	// TODO: move unmarshalling out of here?
 	var w io.WriteCloser
-	w, err = g.c.NextWriter(websocket.TextMessage)
+	w, err = c.NextWriter(websocket.TextMessage)
 	if err != nil {
 		return err
 	}
@@ -53,20 +92,92 @@ func (g *gorilla) WriteJSON(v interface{}) (err error) {
 	return
 }
 
+// WriteMessage writes a message encoded by a codec
+func (g *gorilla) WriteMessage(data []byte, binary bool) error {
+	c, err := g.conn()
+	if err != nil {
+		return err
+	}
+
+	messageType := websocket.TextMessage
+	if binary {
+		messageType = websocket.BinaryMessage
+	}
+	return c.WriteMessage(messageType, data)
+}
+
 func (g *gorilla) Close() (err error) {
-	err = g.c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	return g.close(websocket.CloseNormalClosure)
+}
+
+// CloseResumable closes the connection with a close code that keeps the Discord session valid
+func (g *gorilla) CloseResumable() (err error) {
+	return g.close(int(CloseUnknownError))
+}
+
+func (g *gorilla) close(code int) (err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.c == nil {
+		return errors.New("connection is closed")
+	}
+	err = g.c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, ""))
+
+	// closing the underlying connection unblocks the reader, such that it exits before the next connection
+	// is opened
+	g.c.Close()
 	g.c = nil
 	return
 }
 
+// Ping writes a websocket ping frame, which the other end answers with a pong frame
+func (g *gorilla) Ping() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.c == nil {
+		return errors.New("connection is closed")
+	}
+	return g.c.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second))
+}
+
+// LastPong returns when the last pong frame was received
+func (g *gorilla) LastPong() time.Time {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.lastPong
+}
+
 func (g *gorilla) Read() (packet []byte, err error) {
+	c, err := g.conn()
+	if err != nil {
+		return nil, err
+	}
+	return read(c)
+}
+
+// reader returns a reader bound to the current connection, which never reads from a newer one
+func (g *gorilla) reader() func() (packet []byte, err error) {
+	c, err := g.conn()
+	return func() ([]byte, error) {
+		if err != nil {
+			return nil, err
+		}
+		return read(c)
+	}
+}
+
+func read(c *websocket.Conn) (packet []byte, err error) {
 	var messageType int
-	messageType, packet, err = g.c.ReadMessage()
+	messageType, packet, err = c.ReadMessage()
 	if err != nil {
 		if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-			err = &ErrorUnexpectedClose{
+			closeErr := &ErrorUnexpectedClose{
 				info: err.Error(),
 			}
+			if e, ok := err.(*websocket.CloseError); ok {
+				closeErr.code = CloseCode(e.Code)
+			}
+			err = closeErr
 		}
 
 		return
@@ -79,7 +190,13 @@ func (g *gorilla) Read() (packet []byte, err error) {
 }
 
 func (g *gorilla) Disconnected() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	return g.c == nil
 }
 
 var _ Conn = (*gorilla)(nil)
+var _ resumableCloser = (*gorilla)(nil)
+var _ pinger = (*gorilla)(nil)
+var _ messageWriter = (*gorilla)(nil)
+var _ boundReader = (*gorilla)(nil)
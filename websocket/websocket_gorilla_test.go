@@ -0,0 +1,56 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestGorilla_BoundToConnection(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		c.WriteMessage(websocket.TextMessage, []byte(r.URL.Query().Get("name")))
+		for {
+			if _, _, err = c.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+	endpoint := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	g := &gorilla{HTTPClient: &http.Client{}}
+	if err := g.Open(endpoint+"?name=first", nil); err != nil {
+		t.Fatal(err)
+	}
+	first := g.reader()
+	if packet, err := first(); err != nil || string(packet) != "first" {
+		t.Fatalf("expected to read from the first connection, got %s. err: %v", string(packet), err)
+	}
+
+	if err := g.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.WriteMessage([]byte("{}"), false); err == nil {
+		t.Error("expected writing to a closed connection to fail")
+	}
+
+	if err := g.Open(endpoint+"?name=second", nil); err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	if _, err := first(); err == nil {
+		t.Error("expected the reader of the first connection to not read from the second one")
+	}
+	if packet, err := g.reader()(); err != nil || string(packet) != "second" {
+		t.Errorf("expected to read from the second connection, got %s. err: %v", string(packet), err)
+	}
+}
@@ -0,0 +1,156 @@
+package disgord
+
+// eventWorkerQueueSize is the number of events each event worker holds before the event handler waits for
+// it, which in turn applies the websocket.SlowConsumerPolicy of the socket layer
+const eventWorkerQueueSize = 100
+
+// eventWorkers runs the event callbacks on a fixed number of goroutines, see Config.EventWorkers. Events
+// with the same key always go to the same worker, such that their callbacks run in order.
+type eventWorkers struct {
+	queues   []chan func()
+	shutdown <-chan interface{}
+}
+
+func newEventWorkers(count uint, shutdown <-chan interface{}) *eventWorkers {
+	w := &eventWorkers{
+		queues:   make([]chan func(), count),
+		shutdown: shutdown,
+	}
+	for i := range w.queues {
+		w.queues[i] = make(chan func(), eventWorkerQueueSize)
+		go w.work(w.queues[i])
+	}
+	return w
+}
+
+func (w *eventWorkers) work(queue <-chan func()) {
+	for {
+		select {
+		case job := <-queue:
+			job()
+		case <-w.shutdown:
+			return
+		}
+	}
+}
+
+// dispatch queues the callbacks on the worker of the key, and blocks while the worker is busy with a full
+// queue
+func (w *eventWorkers) dispatch(key uint64, callbacks func()) {
+	queue := w.queues[key%uint64(len(w.queues))]
+	select {
+	case queue <- callbacks:
+	case <-w.shutdown:
+	}
+}
+
+// eventWorkerKey gives the events of a channel the same key, or the events of a guild when they do not
+// belong to a channel. Events without either, such as READY, share the key 0. The key is taken from the
+// decoded event, such that the event data is only unmarshalled once.
+func eventWorkerKey(box eventBox) uint64 {
+	var guildID, channelID Snowflake
+	switch evt := box.(type) {
+	case *ChannelCreate:
+		if evt.Channel != nil {
+			channelID = evt.Channel.ID
+		}
+	case *ChannelUpdate:
+		if evt.Channel != nil {
+			channelID = evt.Channel.ID
+		}
+	case *ChannelDelete:
+		if evt.Channel != nil {
+			channelID = evt.Channel.ID
+		}
+	case *ChannelPinsUpdate:
+		channelID = evt.ChannelID
+	case *ThreadCreate:
+		if evt.Thread != nil {
+			channelID = evt.Thread.ID
+		}
+	case *ThreadUpdate:
+		if evt.Thread != nil {
+			channelID = evt.Thread.ID
+		}
+	case *ThreadDelete:
+		channelID = evt.ID
+	case *ThreadListSync:
+		guildID = evt.GuildID
+	case *ThreadMemberUpdate:
+		guildID = evt.GuildID
+		if evt.Member != nil {
+			channelID = evt.Member.ID
+		}
+	case *TypingStart:
+		channelID = evt.ChannelID
+	case *MessageCreate:
+		if evt.Message != nil {
+			channelID = evt.Message.ChannelID
+		}
+	case *MessageUpdate:
+		if evt.Message != nil {
+			channelID = evt.Message.ChannelID
+		}
+	case *MessageDelete:
+		channelID = evt.ChannelID
+	case *MessageDeleteBulk:
+		channelID = evt.ChannelID
+	case *MessageReactionAdd:
+		channelID = evt.ChannelID
+	case *MessageReactionRemove:
+		channelID = evt.ChannelID
+	case *MessageReactionRemoveAll:
+		channelID = evt.ChannelID
+	case *GuildCreate:
+		if evt.Guild != nil {
+			guildID = evt.Guild.ID
+		}
+	case *GuildUpdate:
+		if evt.Guild != nil {
+			guildID = evt.Guild.ID
+		}
+	case *GuildDelete:
+		if evt.UnavailableGuild != nil {
+			guildID = evt.UnavailableGuild.ID
+		}
+	case *GuildEmojisUpdate:
+		guildID = evt.GuildID
+	case *GuildBanAdd:
+		guildID = evt.GuildID
+	case *GuildBanRemove:
+		guildID = evt.GuildID
+	case *GuildIntegrationsUpdate:
+		guildID = evt.GuildID
+	case *GuildMemberAdd:
+		if evt.Member != nil {
+			guildID = evt.Member.GuildID
+		}
+	case *GuildMemberRemove:
+		guildID = evt.GuildID
+	case *GuildMemberUpdate:
+		guildID = evt.GuildID
+	case *GuildMembersChunk:
+		guildID = evt.GuildID
+	case *GuildRoleCreate:
+		guildID = evt.GuildID
+	case *GuildRoleUpdate:
+		guildID = evt.GuildID
+	case *GuildRoleDelete:
+		guildID = evt.GuildID
+	case *PresenceUpdate:
+		guildID = evt.GuildID
+	case *VoiceStateUpdate:
+		if evt.VoiceState != nil {
+			guildID = evt.VoiceState.GuildID
+		}
+	case *VoiceServerUpdate:
+		guildID = evt.GuildID
+	case *WebhooksUpdate:
+		channelID = evt.ChannelID
+	}
+
+	if !channelID.Empty() {
+		return uint64(channelID)
+	}
+	return uint64(guildID)
+}
@@ -0,0 +1,64 @@
+package disgord
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEventWorkerKey(t *testing.T) {
+	message := eventWorkerKey(&MessageCreate{Message: &Message{ChannelID: 2}})
+	if reaction := eventWorkerKey(&MessageReactionAdd{ChannelID: 2}); reaction != message {
+		t.Error("expected events of the same channel to have the same key")
+	}
+	if other := eventWorkerKey(&MessageCreate{Message: &Message{ChannelID: 3}}); other == message {
+		t.Error("expected events of another channel to have another key")
+	}
+	if role := eventWorkerKey(&GuildRoleCreate{GuildID: 1}); role != eventWorkerKey(&GuildBanAdd{GuildID: 1}) {
+		t.Error("expected guild events of the same guild to have the same key")
+	}
+	if key := eventWorkerKey(&Ready{}); key != 0 {
+		t.Errorf("expected events without a guild and channel to have the key 0, got %d", key)
+	}
+	if key := eventWorkerKey(&GuildMemberAdd{}); key != 0 {
+		t.Errorf("expected an event without data to have the key 0, got %d", key)
+	}
+}
+
+func TestEventWorkers(t *testing.T) {
+	shutdown := make(chan interface{})
+	defer close(shutdown)
+	workers := newEventWorkers(4, shutdown)
+
+	// the events of a channel are handled in order
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		i := i
+		wg.Add(1)
+		workers.dispatch(2, func() {
+			defer wg.Done()
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+		})
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected every event to be handled")
+	}
+
+	for i := range order {
+		if order[i] != i {
+			t.Fatalf("expected the events to be handled in order, got %v", order)
+		}
+	}
+}